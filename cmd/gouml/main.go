@@ -8,14 +8,22 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 
-	"github.com/jfeliu007/goplantuml/render/mermaid"
+	// The renderers register themselves to be found by name
+	_ "github.com/jfeliu007/goplantuml/render/c4"
+	_ "github.com/jfeliu007/goplantuml/render/graphml"
+	_ "github.com/jfeliu007/goplantuml/render/json"
+	_ "github.com/jfeliu007/goplantuml/render/mermaid"
+	_ "github.com/jfeliu007/goplantuml/render/nomnoml"
+	_ "github.com/jfeliu007/goplantuml/render/yaml"
 
 	"github.com/jfeliu007/goplantuml/render/plantuml"
 
 	goplantuml "github.com/jfeliu007/goplantuml/parser"
 	"github.com/jfeliu007/goplantuml/render"
+	"github.com/spf13/afero"
 )
 
 // RenderingOptionSlice will implements the sort interface
@@ -39,31 +47,122 @@ func (as RenderingOptionSlice) Swap(i, j int) {
 
 func main() {
 	recursive := flag.Bool("recursive", false, "walk all directories recursively")
-	ignore := flag.String("ignore", "", "comma separated list of folders to ignore")
+	maxDepth := flag.Int("max-depth", -1, "maximum number of directory levels walked below each directory when -recursive is used. 0 parses only the given directories. Not limited when negative")
+	ignore := flag.String("ignore", "", "comma separated list of folders or glob patterns to ignore. Patterns without separators, such as *_mock, are matched against the folder names and ** matches any number of folders, as in **/testdata")
 	showAggregations := flag.Bool("show-aggregations", false, "renders public aggregations even when -hide-connections is used (do not render by default)")
 	hideFields := flag.Bool("hide-fields", false, "hides fields")
 	hideMethods := flag.Bool("hide-methods", false, "hides methods")
+	hideEmptyClasses := flag.Bool("hide-empty-classes", false, "hides the types rendered without fields, methods or constants that are not connected to any other type")
+	hideEmptyMembers := flag.Bool("hide-empty-members", false, "hides the sections of the classes that have no fields or no methods. Only used by the plantuml render")
+	hideInterfaceMethods := flag.Bool("hide-interface-methods", false, "hides the methods of the interfaces")
+	hideConstructors := flag.Bool("hide-constructors", false, "hides the functions named New or starting with New followed by an upper case letter. Only used with -show-package-functions")
 	hideConnections := flag.Bool("hide-connections", false, "hides all connections in the diagram")
+	hideSelfReferences := flag.Bool("hide-self-references", false, "hides the compositions and aggregations of a type with itself, such as the ones of a tree node holding its children")
+	showConstraintEdges := flag.Bool("show-constraint-edges", false, "renders a dashed edge from the generic types to the parsed interfaces their type parameters are constrained by")
 	showCompositions := flag.Bool("show-compositions", false, "Shows compositions even when -hide-connections is used")
 	showImplementations := flag.Bool("show-implementations", false, "Shows implementations even when -hide-connections is used")
 	showAliases := flag.Bool("show-aliases", false, "Shows aliases even when -hide-connections is used")
 	showConnectionLabels := flag.Bool("show-connection-labels", false, "Shows labels in the connections to identify the connections types (e.g. extends, implements, aggregates, alias of")
+	showBuiltinAggregations := flag.Bool("show-builtin-aggregations", false, "Renders the aggregations of builtin types such as string or int. Ignored if -show-aggregations is not used.")
+	showFieldNameLabels := flag.Bool("show-field-name-labels", false, "Labels the aggregations and compositions with the names of the fields they were created by")
+	theme := flag.String("theme", "", "name of the PlantUML theme the diagram is rendered with, such as cerulean. Only used by the plantuml render")
 	title := flag.String("title", "", "Title of the generated diagram")
 	notes := flag.String("notes", "", "Comma separated list of notes to be added to the diagram")
 	output := flag.String("output", "", "output file path. If omitted, then this will default to standard output")
+	outputDir := flag.String("output-dir", "", "output directory path. When set, a diagram is written for every package in a file named after it instead of a single diagram. Only used by the plantuml render")
 	showOptionsAsNote := flag.Bool("show-options-as-note", false, "Show a note in the diagram with the none evident options ran with this CLI")
 	aggregatePrivateMembers := flag.Bool("aggregate-private-members", false, "Show aggregations for private members. Ignored if -show-aggregations is not used.")
 	hidePrivateMembers := flag.Bool("hide-private-members", false, "Hide private fields and methods")
-	renderType := flag.String("render-type", "mermaid", "Type of render (plantuml|mermaid), default mermaid")
+	renderType := flag.String("render-type", "mermaid", "Type of render ("+strings.Join(render.Names(), "|")+"), default mermaid")
+	showConstants := flag.Bool("show-constants", false, "Renders the constants declared for a named type as an enumeration")
+	showConstValues := flag.Bool("show-const-values", false, "Renders the value of the constants after their name when it is a literal or it is derived from iota. Ignored if -show-constants is not used.")
+	showMultiplicity := flag.Bool("show-multiplicity", false, "Renders the multiplicity of aggregations of slices, arrays and maps. Ignored if -show-aggregations is not used.")
+	nestedNamespaces := flag.Bool("nested-namespaces", false, "Renders packages in namespaces nested into the ones of their parent packages. Only used by the plantuml render")
+	flattenPackages := flag.Bool("flatten-packages", false, "Renders the classes of every package at the top level instead of in a namespace, with their fully qualified name only when it is needed to tell them apart. Only used by the plantuml and mermaid renders")
+	markdownFence := flag.Bool("markdown-fence", false, "Wraps the diagram in a markdown fenced code block tagged with its language, such as ```mermaid. Only used by the plantuml and mermaid renders")
+	packageLegends := flag.Bool("package-legends", false, "Renders a note with the number of structs and interfaces of every package in its namespace. Only used by the plantuml render")
+	mermaidNamespaces := flag.Bool("mermaid-namespaces", false, "Wraps the classes of every package in a namespace block. Only used by the mermaid render")
+	includePattern := flag.String("include", "", "regular expression, only the types whose fully qualified name matches it are rendered")
+	excludePattern := flag.String("exclude", "", "regular expression, the types whose fully qualified name matches it are not rendered")
+	qualifiedMemberTypes := flag.Bool("qualified-member-types", false, "Renders the types of the fields and parameters with their fully qualified name, to tell apart the same-named types of different packages. Only used by the plantuml and mermaid renders")
+	showPackageVars := flag.Bool("show-package-vars", false, "Renders the package level variables holding a parsed struct, such as default instances, in a class of their own for every package. Only used by the plantuml render")
+	showPackageFunctions := flag.Bool("show-package-functions", false, "Renders the functions declared without a receiver in a class of their own for every package")
+	plainText := flag.Bool("plain-text", false, "Renders the keywords of the types (map, chan, struct, interface and func) without the <font> markup, for PlantUML setups that show it literally. Only used by the plantuml render")
+	smartRelationships := flag.Bool("smart-relationships", false, "Renders the aggregations of the types held by value, including in slices, arrays and maps, as compositions, and only the ones held through a pointer as aggregations. Ignored if -show-aggregations is not used.")
+	showReceiverKind := flag.Bool("show-receiver-kind", false, "Renders a * before the name of the methods declared with a pointer receiver")
+	showFieldComments := flag.Bool("show-field-comments", false, "Renders the trailing comment of every field, or its doc comment, after it. Only used by the plantuml and mermaid renders")
+	showDocComments := flag.Bool("show-doc-comments", false, "Renders the doc comment of every type as a note attached to it")
+	importGraph := flag.Bool("import-graph", false, "Renders the parsed packages and the imports between them instead of the types. Only used by the plantuml and mermaid renders")
+	expandAnonymousStructs := flag.Bool("expand-anonymous-structs", false, "Renders the anonymous struct types of fields as classes of their own, named after the struct and the field, instead of inline")
+	embeddedAsFields := flag.Bool("embedded-as-fields", false, "Renders embedded struct fields as regular fields instead of compositions")
+	groupBy := flag.String("group-by", "none", "Renders side by side the classes declared in the same file (none|file). Only used by the plantuml render")
+	memberOrder := flag.String("member-order", "visibility", "Order in which fields and methods are rendered (visibility|source|alphabetical)")
+	listDirectories := flag.Bool("list-directories", false, "Lists the directories that would be parsed with the given options, one per line, instead of rendering the diagram")
+	includeVendor := flag.Bool("include-vendor", false, "walks vendor directories too when -recursive is used")
+	includeHidden := flag.Bool("include-hidden", false, "walks directories starting with a dot too when -recursive is used")
+	skipGenerated := flag.Bool("skip-generated", false, "Skips the generated files, which start with a // Code generated ... DO NOT EDIT. comment, such as the output of protoc or mockgen")
+	includeTests := flag.Bool("include-tests", false, "Parses _test.go files too. External test packages are rendered in their own namespace")
+	stdInterfaces := flag.String("std-interfaces", "", "comma separated list of standard interfaces connected to the types that implement them, such as error,fmt.Stringer,io.Reader. Supported: "+strings.Join(goplantuml.StdInterfaceNames(), ", "))
+	tags := flag.String("tags", "", "comma separated list of build tags to consider when choosing the files to parse")
+	goos := flag.String("goos", "", "GOOS to consider when choosing the files to parse. All files are parsed if -goos, -goarch and -tags are omitted")
+	goarch := flag.String("goarch", "", "GOARCH to consider when choosing the files to parse. All files are parsed if -goos, -goarch and -tags are omitted")
+	continueOnError := flag.Bool("continue-on-error", false, "Keeps parsing when a directory or a file cannot be parsed, reporting it as a warning instead of failing")
+	concurrency := flag.Int("concurrency", 0, "maximum number of directories parsed at the same time. Defaults to the number of CPUs when 0")
+	noColor := flag.Bool("no-color", false, "Draws the connections in the default color instead of random ones. Only used by the plantuml render")
+	maxSignatureWidth := flag.Int("max-signature-width", 0, "number of characters above which the parameters of the method signatures are wrapped onto continuation lines. Not wrapped when 0. Only used by the plantuml render")
+	maxMembers := flag.Int("max-members", 0, "number of fields, and separately of methods, rendered in every class. The ones past it are replaced with a ... (+K more) line. All of them are rendered when 0")
+	indent := flag.String("indent", "", "indentation of the rendered lines, either a number of spaces or tab. Four spaces when omitted")
+	colorSeed := flag.Int("color-seed", 0, "Seed used to derive the colors of the connections so they do not change between runs. Random colors are used when 0")
 	flag.Parse()
+	indentation, err := getIndent(*indent)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
 	renderingOptions := map[goplantuml.RenderingOption]interface{}{
-		goplantuml.RenderConnectionLabels:  *showConnectionLabels,
-		goplantuml.RenderFields:            !*hideFields,
-		goplantuml.RenderMethods:           !*hideMethods,
-		goplantuml.RenderAggregations:      *showAggregations,
-		goplantuml.RenderTitle:             *title,
-		goplantuml.AggregatePrivateMembers: *aggregatePrivateMembers,
-		goplantuml.RenderPrivateMembers:    !*hidePrivateMembers,
+		goplantuml.RenderConnectionLabels:      *showConnectionLabels,
+		goplantuml.RenderFields:                !*hideFields,
+		goplantuml.RenderMethods:               !*hideMethods,
+		goplantuml.RenderAggregations:          *showAggregations,
+		goplantuml.RenderTitle:                 *title,
+		goplantuml.AggregatePrivateMembers:     *aggregatePrivateMembers,
+		goplantuml.RenderPrivateMembers:        !*hidePrivateMembers,
+		goplantuml.ColorSeed:                   *colorSeed,
+		goplantuml.RenderConstants:             *showConstants,
+		goplantuml.RenderMemberOrder:           goplantuml.MemberOrder(*memberOrder),
+		goplantuml.RenderEmbeddedAsComposition: !*embeddedAsFields,
+		goplantuml.RenderMultiplicity:          *showMultiplicity,
+		goplantuml.RenderNestedNamespaces:      *nestedNamespaces,
+		goplantuml.IncludePattern:              *includePattern,
+		goplantuml.ExcludePattern:              *excludePattern,
+		goplantuml.RenderDocComments:           *showDocComments,
+		goplantuml.ImportGraph:                 *importGraph,
+		goplantuml.HideEmptyClasses:            *hideEmptyClasses,
+		goplantuml.MermaidNamespaces:           *mermaidNamespaces,
+		goplantuml.ShowReceiverKind:            *showReceiverKind,
+		goplantuml.NoColor:                     *noColor,
+		goplantuml.RenderPackageFunctions:      *showPackageFunctions,
+		goplantuml.HideEmptyMembers:            *hideEmptyMembers,
+		goplantuml.HideInterfaceMethods:        *hideInterfaceMethods,
+		goplantuml.HideConstructors:            *hideConstructors,
+		goplantuml.SmartRelationships:          *smartRelationships,
+		goplantuml.MaxSignatureWidth:           *maxSignatureWidth,
+		goplantuml.PlainText:                   *plainText,
+		goplantuml.Theme:                       *theme,
+		goplantuml.RenderFieldComments:         *showFieldComments,
+		goplantuml.Indent:                      indentation,
+		goplantuml.RenderFieldNameLabels:       *showFieldNameLabels,
+		goplantuml.RenderBuiltinAggregations:   *showBuiltinAggregations,
+		goplantuml.FlattenPackages:             *flattenPackages,
+		goplantuml.GroupBy:                     goplantuml.Grouping(*groupBy),
+		goplantuml.MarkdownFence:               *markdownFence,
+		goplantuml.PackageLegends:              *packageLegends,
+		goplantuml.ShowConstValues:             *showConstValues,
+		goplantuml.HideSelfReferences:          *hideSelfReferences,
+		goplantuml.RenderConstraintEdges:       *showConstraintEdges,
+		goplantuml.RenderPackageVars:           *showPackageVars,
+		goplantuml.MaxMembers:                  *maxMembers,
+		goplantuml.QualifiedMemberTypes:        *qualifiedMemberTypes,
 	}
 	if *hideConnections {
 		renderingOptions[goplantuml.RenderAliases] = *showAliases
@@ -91,10 +190,10 @@ func main() {
 		}
 	}
 	renderingOptions[goplantuml.RenderNotes] = strings.Join(noteList, "\n")
-	dirs, err := getDirectories()
+	dirs, files, err := getDirectoriesAndFiles()
 
 	if err != nil {
-		fmt.Println("usage:\ngouml <DIR>\nDIR Must be a valid directory")
+		fmt.Println("usage:\ngouml <DIR|FILE>\nDIR Must be a valid directory\nFILE Must be a valid .go file")
 		fmt.Fprintln(os.Stderr, err.Error())
 		os.Exit(1)
 	}
@@ -106,55 +205,131 @@ func main() {
 		os.Exit(1)
 	}
 
-	result, err := goplantuml.NewClassDiagram(dirs, ignoredDirectories, *recursive)
-	result.SetRenderingOptions(renderingOptions)
+	options := &goplantuml.ClassDiagramOptions{
+		FileSystem:             afero.NewOsFs(),
+		Directories:            dirs,
+		Files:                  files,
+		IgnoredDirectories:     ignoredDirectories,
+		Recursive:              *recursive,
+		MaxDepth:               *maxDepth,
+		IncludeTests:           *includeTests,
+		SkipGenerated:          *skipGenerated,
+		ContinueOnError:        *continueOnError,
+		IncludeVendor:          *includeVendor,
+		IncludeHidden:          *includeHidden,
+		RenderingOptions:       map[goplantuml.RenderingOption]interface{}{},
+		BuildTags:              getList(*tags),
+		GOOS:                   *goos,
+		GOARCH:                 *goarch,
+		Concurrency:            *concurrency,
+		ExpandAnonymousStructs: *expandAnonymousStructs,
+		StdInterfaces:          getList(*stdInterfaces),
+	}
+	if *listDirectories {
+		directories, err := goplantuml.PlannedDirectories(options)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		for _, directory := range directories {
+			fmt.Println(directory)
+		}
+		return
+	}
+	result, err := goplantuml.NewClassDiagramWithOptions(options)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
 		os.Exit(1)
 	}
-	var ren render.Renderer
-	switch *renderType {
-	case "plantuml":
-		ren = plantuml.NewRender()
-	case "mermaid":
-		ren = mermaid.NewRender()
+	err = result.SetRenderingOptions(renderingOptions)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	for _, parseError := range result.Errors() {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", parseError.Error())
+	}
+	for _, warning := range result.RenderingOptions.Validate() {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning.Error())
+	}
+	if *outputDir != "" {
+		if *renderType != "plantuml" {
+			fmt.Fprintln(os.Stderr, "-output-dir can only be used with -render-type plantuml")
+			os.Exit(1)
+		}
+		if err := writePerPackage(*outputDir, plantuml.NewRender().RenderPerPackage(result)); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+	ren, err := render.Get(*renderType)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
 	}
 
-	rendered := ren.Render(result)
 	var writer io.Writer
 	if *output != "" {
-		writer, err = os.Create(*output)
+		file, err := os.Create(*output)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
 		}
+		defer file.Close()
+		writer = file
 	} else {
 		writer = os.Stdout
 	}
-	fmt.Fprint(writer, rendered)
+	if err := ren.RenderTo(writer, result); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
 }
 
-func getDirectories() ([]string, error) {
+// writePerPackage writes every document into a .puml file named after its package in the given directory, which is
+// created if needed
+func writePerPackage(dir string, documents map[string]string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for pack, document := range documents {
+		// Packages at the root of the module base start with a dot, which would hide the file
+		fileName := strings.TrimPrefix(pack, ".") + ".puml"
+		if err := os.WriteFile(filepath.Join(dir, fileName), []byte(document), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getDirectoriesAndFiles() ([]string, []string, error) {
 
 	args := flag.Args()
 	if len(args) < 1 {
-		return nil, errors.New("DIR missing")
+		return nil, nil, errors.New("DIR missing")
 	}
 	dirs := []string{}
+	files := []string{}
 	for _, dir := range args {
 		fi, err := os.Stat(dir)
 		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("could not find directory %s", dir)
+			return nil, nil, fmt.Errorf("could not find directory %s", dir)
 		}
-		if !fi.Mode().IsDir() {
-			return nil, fmt.Errorf("%s is not a directory", dir)
+		if !fi.Mode().IsDir() && filepath.Ext(dir) != ".go" {
+			return nil, nil, fmt.Errorf("%s is not a directory nor a .go file", dir)
 		}
 		dirAbs, err := filepath.Abs(dir)
 		if err != nil {
-			return nil, fmt.Errorf("could not find directory %s", dir)
+			return nil, nil, fmt.Errorf("could not find directory %s", dir)
+		}
+		if fi.Mode().IsDir() {
+			dirs = append(dirs, dirAbs)
+		} else {
+			files = append(files, dirAbs)
 		}
-		dirs = append(dirs, dirAbs)
 	}
-	return dirs, nil
+	return dirs, files, nil
 }
 
 func getIgnoredDirectories(list string) ([]string, error) {
@@ -165,7 +340,13 @@ func getIgnoredDirectories(list string) ([]string, error) {
 	}
 	split := strings.Split(list, ",")
 	for _, dir := range split {
-		dirAbs, err := filepath.Abs(strings.TrimSpace(dir))
+		dir = strings.TrimSpace(dir)
+		if strings.ContainsAny(dir, "*?[") {
+			// Glob patterns are matched as they are given
+			result = append(result, dir)
+			continue
+		}
+		dirAbs, err := filepath.Abs(dir)
 		if err != nil {
 			return nil, fmt.Errorf("could not find directory %s", dir)
 		}
@@ -174,6 +355,33 @@ func getIgnoredDirectories(list string) ([]string, error) {
 	return result, nil
 }
 
+// getList returns the trimmed elements of the given comma separated list, leaving out the empty ones
+func getList(list string) []string {
+	result := []string{}
+	for _, element := range strings.Split(list, ",") {
+		if trimmed := strings.TrimSpace(element); trimmed != "" {
+			result = append(result, trimmed)
+		}
+	}
+	return result
+}
+
+// getIndent returns the indentation described by the -indent flag, which is either a number of spaces or tab. The
+// default indentation is used when it is empty
+func getIndent(indent string) (string, error) {
+	if indent == "" {
+		return "", nil
+	}
+	if indent == "tab" {
+		return "\t", nil
+	}
+	spaces, err := strconv.Atoi(indent)
+	if err != nil || spaces < 1 {
+		return "", fmt.Errorf("Invalid indentation %s, expected a number of spaces or tab", indent)
+	}
+	return strings.Repeat(" ", spaces), nil
+}
+
 func getLegend(ro map[goplantuml.RenderingOption]interface{}) (string, error) {
 	result := "<u><b>Legend</b></u>\n"
 	orderedOptions := RenderingOptionSlice{}
@@ -198,6 +406,8 @@ func getLegend(ro map[goplantuml.RenderingOption]interface{}) (string, error) {
 			result = fmt.Sprintf("%sRender Methods: %t\n", result, val.(bool))
 		case goplantuml.AggregatePrivateMembers:
 			result = fmt.Sprintf("%sPritave Aggregations: %t\n", result, val.(bool))
+		case goplantuml.RenderMultiplicity:
+			result = fmt.Sprintf("%sRender Multiplicity: %t\n", result, val.(bool))
 		}
 	}
 	return strings.TrimSpace(result), nil
@@ -3,6 +3,6 @@ module github.com/jfeliu007/goplantuml
 go 1.16
 
 require (
-	github.com/AvraamMavridis/randomcolor v0.0.0-20180822172341-208aff70bf2c // indirect
+	github.com/AvraamMavridis/randomcolor v0.0.0-20180822172341-208aff70bf2c
 	github.com/spf13/afero v1.6.0
 )
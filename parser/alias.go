@@ -1,12 +1,22 @@
 package parser
 
-import "fmt"
+import (
+	"fmt"
+	"go/ast"
+	"sort"
+	"strings"
+)
 
-//Alias defines a type that is an alias for some other type
+//Alias defines a type that is an alias for some other type. DefinedType is true when the type is not a real
+//alias (type A = B) but a new type defined from another one (type A B)
 type Alias struct {
 	Name        string
 	PackageName string
 	AliasOf     string
+	DefinedType bool
+	// TypeArguments holds the type arguments of the generic type the alias is an instantiation of, such as int for
+	// type IntList = List[int], in which case Name is the fully qualified name of the generic type itself
+	TypeArguments []string
 }
 
 func getNewAlias(name, packageName, aliasOf string) *Alias {
@@ -20,6 +30,78 @@ func getNewAlias(name, packageName, aliasOf string) *Alias {
 	}
 }
 
+// getInstantiation returns the fully qualified name of the generic type the given expression instantiates, such as
+// List in List[int], along with its type arguments. It returns false when the expression is not an instantiation
+func getInstantiation(exp ast.Expr, aliases map[string]string, packageName string, typeParameters []string) (string, []string, bool) {
+	var genericType ast.Expr
+	var indices []ast.Expr
+	switch v := exp.(type) {
+	case *ast.IndexExpr:
+		genericType, indices = v.X, []ast.Expr{v.Index}
+	case *ast.IndexListExpr:
+		genericType, indices = v.X, v.Indices
+	default:
+		return "", nil, false
+	}
+	name, _ := getFieldType(genericType, aliases, packageName)
+	arguments := make([]string, 0, len(indices))
+	for _, index := range indices {
+		argument, _ := getFieldType(index, aliases, packageName)
+		argument = replaceTypeParameters(argument, typeParameters)
+		arguments = append(arguments, strings.ReplaceAll(argument, packageConstant+".", ""))
+	}
+	return replacePackageConstant(name, packageName), arguments, true
+}
+
+// AliasCycles returns the aliases and defined types that refer to each other in a cycle, as in type A = B and type
+// B = A, which go does not compile but can still be parsed. Every cycle holds the fully qualified names of its types in
+// the order they refer to each other, starting with the lowest one, and the cycles are sorted by their first type
+func (p *ClassParser) AliasCycles() [][]string {
+	next := map[string]string{}
+	for _, alias := range p.AllAliases {
+		if len(alias.TypeArguments) == 0 {
+			// The types of the same package are named with an empty package of their own, as in pkg..B
+			next[alias.AliasOf] = strings.Replace(alias.Name, "..", ".", 1)
+		}
+	}
+	names := make([]string, 0, len(next))
+	for name := range next {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	cycles := [][]string{}
+	visited := map[string]bool{}
+	for _, name := range names {
+		// The types of the current path are indexed by their position in it
+		path := map[string]int{}
+		var order []string
+		for current, ok := name, true; ok && !visited[current]; current, ok = next[current] {
+			if start, found := path[current]; found {
+				cycles = append(cycles, rotateCycle(order[start:]))
+				break
+			}
+			path[current] = len(order)
+			order = append(order, current)
+		}
+		for _, n := range order {
+			visited[n] = true
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i][0] < cycles[j][0] })
+	return cycles
+}
+
+// rotateCycle returns the given cycle starting with its lowest name
+func rotateCycle(cycle []string) []string {
+	lowest := 0
+	for i, name := range cycle {
+		if name < cycle[lowest] {
+			lowest = i
+		}
+	}
+	return append(append([]string{}, cycle[lowest:]...), cycle[:lowest]...)
+}
+
 //AliasSlice implement the sort.Interface interface to allow for proper sorting of an alias slice
 type AliasSlice []Alias
 
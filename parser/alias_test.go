@@ -7,7 +7,7 @@ import (
 
 func TestGetNewAlias(t *testing.T) {
 	result := &Alias{
-		Name:        "__builtin__.int",
+		Name:        "builtin.int",
 		PackageName: "testpackage",
 		AliasOf:     "test",
 	}
@@ -46,6 +46,93 @@ func TestAliasSlice(t *testing.T) {
 	}
 	aliasSlice.Swap(0, 1)
 	if aliasSlice[0].AliasOf != "A" {
-		t.Errorf("TestAliasSlice: Expected aliasSlice[0].AliasOf to be 'A' got %s", aliasSlice[0])
+		t.Errorf("TestAliasSlice: Expected aliasSlice[0].AliasOf to be 'A' got %s", aliasSlice[0].AliasOf)
+	}
+}
+
+func TestGenericAliases(t *testing.T) {
+	parser := getEmptyParser("main")
+	parser.AllImports = map[string]string{"bar": "github.com.foo.bar"}
+	err := parser.ParseSource("lists/lists.go", []byte(`package lists
+
+import "github.com/foo/bar"
+
+type List[T any] struct {
+	Items []T
+}
+
+type Pair[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+type IntList = List[int]
+
+type Pairs = Pair[string, bar.Thing]
+
+type Ints List[int]
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	pack := parser.CurrentPackageName
+	tt := []struct {
+		name     string
+		expected *Alias
+	}{
+		{
+			name:     "IntList",
+			expected: &Alias{Name: pack + ".List", PackageName: pack, AliasOf: pack + ".IntList", TypeArguments: []string{"int"}},
+		},
+		{
+			name:     "Pairs",
+			expected: &Alias{Name: pack + ".Pair", PackageName: pack, AliasOf: pack + ".Pairs", TypeArguments: []string{"string", "github.com.foo.bar.Thing"}},
+		},
+		{
+			name:     "Ints",
+			expected: &Alias{Name: pack + ".List", PackageName: pack, AliasOf: pack + ".Ints", DefinedType: true, TypeArguments: []string{"int"}},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			alias := parser.AllAliases[pack+"."+tc.name]
+			if !reflect.DeepEqual(alias, tc.expected) {
+				t.Errorf("Expected the alias to be %+v, got %+v", tc.expected, alias)
+			}
+		})
+	}
+	if len(parser.AllRenamedStructs) != 0 {
+		t.Errorf("Expected no renamed structs for the instantiations, got %v", parser.AllRenamedStructs)
+	}
+}
+
+func TestAliasCycles(t *testing.T) {
+	parser, err := NewClassDiagramWithOptions(&ClassDiagramOptions{})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = parser.ParseSource("loop.go", []byte(`package loop
+
+type B = A
+
+type A = B
+
+type C = D
+
+type D = E
+
+type E C
+
+type F = C
+
+type G = int
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	// F leads to a cycle without being part of it
+	expected := [][]string{{".loop.A", ".loop.B"}, {".loop.C", ".loop.D", ".loop.E"}}
+	if cycles := parser.AliasCycles(); !reflect.DeepEqual(cycles, expected) {
+		t.Errorf("Expected the cycles %v, got %v", expected, cycles)
 	}
 }
@@ -17,8 +17,11 @@ package parser
 import (
 	"fmt"
 	"go/ast"
+	"go/build"
+	"go/constant"
 	"go/parser"
 	"go/token"
+	"io"
 	"os"
 	"path"
 	"path/filepath"
@@ -26,6 +29,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/spf13/afero"
 )
@@ -37,22 +41,124 @@ const BuiltinPackageName = "builtin"
 // adding new lines
 type LineStringBuilder struct {
 	strings.Builder
+	// Indent is the string repeated at the beginning of the lines for every level of depth. Four spaces are used when
+	// it is empty
+	Indent string
+}
+
+// NewLineStringBuilder returns a LineStringBuilder that indents its lines with the given string, or with four spaces
+// when it is empty
+func NewLineStringBuilder(indent string) *LineStringBuilder {
+	return &LineStringBuilder{Indent: indent}
 }
 
 // WriteLineWithDepth will write the given text with added tabs at the beginning into the string builder.
 func (lsb *LineStringBuilder) WriteLineWithDepth(depth int, str string) {
-	lsb.WriteString(strings.Repeat(tab, depth))
+	lsb.WriteString(strings.Repeat(indentOrTab(lsb.Indent), depth))
 	lsb.WriteString(str)
 	lsb.WriteString("\n")
 }
 
+// LineWriter writes lines with added tabs at the beginning into an io.Writer, so the lines do not need to be kept
+// in memory. The first error returned by the io.Writer is kept and any following write is ignored.
+type LineWriter struct {
+	writer io.Writer
+	err    error
+	depth  int
+	indent string
+}
+
+// NewLineWriter returns a LineWriter that writes into the given io.Writer
+func NewLineWriter(w io.Writer) *LineWriter {
+	return &LineWriter{
+		writer: w,
+	}
+}
+
+// NewLineWriterWithIndent returns a LineWriter that writes into the given io.Writer and indents the lines with the
+// given string, or with four spaces when it is empty
+func NewLineWriterWithIndent(w io.Writer, indent string) *LineWriter {
+	return &LineWriter{
+		writer: w,
+		indent: indent,
+	}
+}
+
+// WriteLineWithDepth will write the given text with added tabs at the beginning into the io.Writer.
+func (lw *LineWriter) WriteLineWithDepth(depth int, str string) {
+	if lw.err != nil {
+		return
+	}
+	_, lw.err = io.WriteString(lw.writer, strings.Repeat(indentOrTab(lw.indent), lw.depth+depth)+str+"\n")
+}
+
+// indentOrTab returns the given indentation, or the default one when it is empty
+func indentOrTab(indent string) string {
+	if indent == "" {
+		return tab
+	}
+	return indent
+}
+
+// Indent adds the given depth to the depth of every following line. A negative depth removes it again.
+func (lw *LineWriter) Indent(depth int) {
+	lw.depth += depth
+}
+
+// Err returns the first error found while writing, if any
+func (lw *LineWriter) Err() error {
+	return lw.err
+}
+
 // ClassDiagramOptions will provide a way for callers of the NewClassDiagramFs() function to pass all the necessary arguments.
 type ClassDiagramOptions struct {
 	FileSystem         afero.Fs
 	Directories        []string
+	// IgnoredDirectories are skipped by the recursive walk. Each one is either the exact path of a directory or a glob
+	// pattern, such as "*_mock" matched against the name of every directory or "**/testdata" matched against its path
 	IgnoredDirectories []string
 	RenderingOptions   map[RenderingOption]interface{}
 	Recursive          bool
+	// MaxDepth limits how many levels of subdirectories are walked below each of the Directories when Recursive is
+	// set. 0 parses only the given directories, as when Recursive is not set. Negative values do not limit the depth.
+	MaxDepth int
+	// Options are applied after RenderingOptions
+	Options []Option
+	// IncludeVendor walks into vendor directories and IncludeHidden into the ones starting with a dot when Recursive
+	// is set. They are skipped otherwise
+	IncludeVendor bool
+	IncludeHidden bool
+	// IncludeTests parses the _test.go files too. Test files of the package are merged into its namespace while
+	// external test packages (package foo_test) get a namespace of their own
+	IncludeTests bool
+	// Files are parsed on their own, without the other files of their directory. Their package base is computed from
+	// the directory they are in.
+	Files []string
+	// BuildTags, GOOS and GOARCH are used to skip the files that are not part of the build they describe. When none of
+	// them is set, all the files are parsed. GOOS and GOARCH default to the ones of the running program.
+	BuildTags []string
+	GOOS      string
+	GOARCH    string
+	// Concurrency caps the number of directories parsed at the same time. It defaults to GOMAXPROCS when it is 0 or
+	// less
+	Concurrency int
+	// StdInterfaces lists the interfaces of the standard library, such as error or fmt.Stringer, that the parsed structs
+	// are connected to when they implement them, as if they were parsed interfaces. See StdInterfaceNames for the ones
+	// that can be used
+	StdInterfaces []string
+	// ExpandAnonymousStructs turns the anonymous struct types of the fields into classes of their own, named after the
+	// struct and the field (Parent_Meta for the field Meta of Parent), instead of rendering them inline
+	ExpandAnonymousStructs bool
+	// SkipGenerated leaves out the generated files, which have a comment matching ^// Code generated .* DO NOT EDIT\.$
+	// before their package clause, such as the output of protoc or mockgen
+	SkipGenerated bool
+	// ModuleBase is the name of the directory the namespaces are computed from when the parsed directories are not in a
+	// module. It defaults to the name of the working directory when the FileSystem is the OS one. The whole path of the
+	// directories is used otherwise
+	ModuleBase string
+	// ContinueOnError keeps parsing when a directory or a file cannot be read or parsed, instead of failing. The diagram
+	// holds everything else and the errors are returned by the Errors method of the ClassParser as ParseErrors
+	ContinueOnError bool
 }
 
 // RenderingOptions will allow the class parser to optionally enebale or disable the things to render.
@@ -69,8 +175,138 @@ type RenderingOptions struct {
 	ConnectionLabels        bool
 	AggregatePrivateMembers bool
 	PrivateMembers          bool
+	ColorSeed               int
+	Constants               bool
+	MemberOrder             MemberOrder
+	EmbeddedAsComposition   bool
+	Multiplicity            bool
+	NestedNamespaces        bool
+	IncludePattern          *regexp.Regexp
+	ExcludePattern          *regexp.Regexp
+	DocComments             bool
+	ImportGraph             bool
+	HideEmptyClasses        bool
+	MermaidNamespaces       bool
+	ShowReceiverKind        bool
+	NoColor                 bool
+	PackageFunctions        bool
+	HideEmptyMembers        bool
+	HideInterfaceMethods    bool
+	HideConstructors        bool
+	SmartRelationships      bool
+	MaxSignatureWidth       int
+	PlainText               bool
+	Theme                   string
+	FieldComments           bool
+	Indent                  string
+	FieldNameLabels         bool
+	BuiltinAggregations     bool
+	FlattenPackages         bool
+	GroupBy                 Grouping
+	// Stereotypes replaces the stereotype rendered for the structures of the type used as key ("class", "interface",
+	// "alias", "type" or "func"). The renderers use their own stereotype for the types that are not in the map
+	Stereotypes map[string]string
+	// Connectors replaces the arrow the plantuml render draws the connections of the kind used as key with
+	// ("composition", "extends", "realization", "aggregation", "alias", "derives" or "constraint"), such as <|-- for
+	// "realization".
+	// The default arrows are used for the kinds that are not in the map
+	Connectors map[string]string
+	// MarkdownFence wraps the diagrams rendered by the plantuml and mermaid renders in a markdown fenced code block
+	// tagged with their language, so they can be embedded in a markdown document as they are
+	MarkdownFence bool
+	// PackageAliases replaces the package prefixes used as keys, such as the path of the module, with shorter names in
+	// the namespaces and the types the plantuml and mermaid renders display. A prefix replaced with an empty string is
+	// removed, and the longest prefix a package starts with is the one replaced
+	PackageAliases map[string]string
+	// PackageLegends renders a note in the namespace of every package with the number of structs and interfaces it
+	// holds. Only the plantuml render draws them
+	PackageLegends bool
+	// ConstValues renders the value of the constants of the enumerations after their name, when it is known (see
+	// Field.Value)
+	ConstValues bool
+	// HideSelfReferences leaves out the compositions and aggregations of a type with itself, such as the ones of a tree
+	// node holding its children
+	HideSelfReferences bool
+	// ConstraintEdges renders a dashed edge from the generic types, and from the functions of their package, to the
+	// parsed interfaces their type parameters are constrained by
+	ConstraintEdges bool
+	// PackageVars renders the variables declared at the package level that hold a parsed struct, such as default
+	// instances, in a class of their own for every package, aggregating the structs they hold. Only the plantuml render
+	// draws them
+	PackageVars bool
+	// MaxMembers limits how many fields, and separately how many methods, are rendered in every class. The members
+	// past the limit are replaced with a single ... (+K more) line. Every member is rendered when it is 0
+	MaxMembers int
+	// QualifiedMemberTypes renders the types of the fields and parameters in the body of the classes with their fully
+	// qualified name (see Field.FullType), so the same-named types of different packages can be told apart. Only the
+	// plantuml and mermaid renders use it
+	QualifiedMemberTypes bool
 }
 
+// MemberType returns the type the given field or parameter is rendered with in the body of a class, which is its fully
+// qualified type when QualifiedMemberTypes is set and it is known
+func (ro *RenderingOptions) MemberType(field *Field) string {
+	if ro.QualifiedMemberTypes && field.FullType != "" {
+		return field.FullType
+	}
+	return field.Type
+}
+
+// MemberString returns the given field or parameter the way Field.String does, but with the type returned by
+// MemberType
+func (ro *RenderingOptions) MemberString(field *Field) string {
+	if field.Embedded || field.Name == "" {
+		return ro.MemberType(field)
+	}
+	return fmt.Sprintf("%s %s", field.Name, ro.MemberType(field))
+}
+
+// IsSelfReference returns true if the connection between the types with the given fully qualified names is a
+// composition or an aggregation of a type with itself that is hidden by HideSelfReferences
+func (ro *RenderingOptions) IsSelfReference(source string, target string) bool {
+	return ro.HideSelfReferences && source == target
+}
+
+// IsIncluded returns true if the type with the given fully qualified name matches the IncludePattern, when set, and
+// does not match the ExcludePattern, when set
+func (ro *RenderingOptions) IsIncluded(name string) bool {
+	if ro.IncludePattern != nil && !ro.IncludePattern.MatchString(name) {
+		return false
+	}
+	return ro.ExcludePattern == nil || !ro.ExcludePattern.MatchString(name)
+}
+
+// MemberOrder defines the order in which the fields and methods of a class are rendered
+type MemberOrder string
+
+const (
+	// MemberOrderVisibility renders private members first and then public ones, each of them in the order they were parsed
+	MemberOrderVisibility MemberOrder = "visibility"
+
+	// MemberOrderSource renders members in the order they are declared in the source code
+	MemberOrderSource MemberOrder = "source"
+
+	// MemberOrderAlphabetical renders members sorted by name
+	MemberOrderAlphabetical MemberOrder = "alphabetical"
+)
+
+// GroupsByVisibility returns true if private and public members are rendered separately. An empty MemberOrder
+// is treated as MemberOrderVisibility
+func (o MemberOrder) GroupsByVisibility() bool {
+	return o == "" || o == MemberOrderVisibility
+}
+
+// Grouping defines which classes are rendered side by side
+type Grouping string
+
+const (
+	// GroupByNone lets the classes be laid out freely. An empty Grouping is treated as GroupByNone
+	GroupByNone Grouping = "none"
+
+	// GroupByFile renders the classes declared in the same source file side by side
+	GroupByFile Grouping = "file"
+)
+
 const (
 	// RenderAggregations is to be used in the SetRenderingOptions argument as the key to the map, when value is true, it will set the parser to render aggregations
 	RenderAggregations RenderingOption = iota
@@ -104,11 +340,185 @@ const (
 
 	// RenderPrivateMembers is used if private members (fields, methods) should be rendered
 	RenderPrivateMembers
+
+	// ColorSeed is used to seed the colors of the connections. When the value is not 0, every connection gets a color
+	// derived from the seed and the names of the connected classes, so it does not change between renders. The aliases
+	// are always colored that way, with a seed of 0 when it is not set
+	ColorSeed
+
+	// RenderConstants is to be used in the SetRenderingOptions argument as the key to the map, when value is true, it will render the constants
+	// declared for a named type as an enumeration
+	RenderConstants
+
+	// RenderMemberOrder is to be used in the SetRenderingOptions argument as the key to the map, the value is the MemberOrder in which
+	// fields and methods are rendered
+	RenderMemberOrder
+
+	// RenderEmbeddedAsComposition is to be used in the SetRenderingOptions argument as the key to the map, when value is true, embedded fields
+	// of structs are rendered as compositions. Otherwise they are rendered as regular fields
+	RenderEmbeddedAsComposition
+
+	// RenderMultiplicity is to be used in the SetRenderingOptions argument as the key to the map, when value is true, aggregations of
+	// slices, arrays and maps are rendered with the multiplicity of the aggregated type
+	RenderMultiplicity
+
+	// RenderNestedNamespaces is to be used in the SetRenderingOptions argument as the key to the map, when value is true, packages are
+	// rendered as namespaces nested into the namespaces of their parent packages instead of a flat list
+	RenderNestedNamespaces
+
+	// IncludePattern is to be used in the SetRenderingOptions argument as the key to the map, the value is a regular expression. Only the
+	// types whose fully qualified name matches it are rendered, along with the connections between them
+	IncludePattern
+
+	// ExcludePattern is to be used in the SetRenderingOptions argument as the key to the map, the value is a regular expression. The
+	// types whose fully qualified name matches it are not rendered, nor any connection to them
+	ExcludePattern
+
+	// RenderDocComments is to be used in the SetRenderingOptions argument as the key to the map, when value is true, the doc comment of
+	// every type is rendered as a note attached to it
+	RenderDocComments
+
+	// ImportGraph is to be used in the SetRenderingOptions argument as the key to the map, when value is true, the diagram shows the
+	// packages and the imports between them instead of the types
+	ImportGraph
+
+	// HideEmptyClasses is to be used in the SetRenderingOptions argument as the key to the map, when value is true, the types that
+	// would be rendered without any field, method or constant and without any connection are not rendered
+	HideEmptyClasses
+
+	// MermaidNamespaces is to be used in the SetRenderingOptions argument as the key to the map, when value is true, the mermaid
+	// render wraps the classes of every package in a namespace block
+	MermaidNamespaces
+
+	// ShowReceiverKind is to be used in the SetRenderingOptions argument as the key to the map, when value is true, the methods
+	// declared with a pointer receiver are rendered with a * before their name
+	ShowReceiverKind
+
+	// NoColor is to be used in the SetRenderingOptions argument as the key to the map, when value is true, the connections are drawn
+	// with the default color instead of random ones
+	NoColor
+
+	// Stereotypes is to be used in the SetRenderingOptions argument as the key to the map, the value is a map[string]string from
+	// the type of the structures ("class", "interface", "alias", "type" or "func") to the stereotype rendered for them
+	Stereotypes
+
+	// RenderPackageFunctions is to be used in the SetRenderingOptions argument as the key to the map, when value is true, the
+	// functions declared without a receiver are rendered in a class of their own for every package
+	RenderPackageFunctions
+
+	// HideEmptyMembers is to be used in the SetRenderingOptions argument as the key to the map, when value is true, the plantuml
+	// render hides the sections of the classes that have no fields or no methods
+	HideEmptyMembers
+
+	// HideInterfaceMethods is to be used in the SetRenderingOptions argument as the key to the map, when value is true, the
+	// methods of the interfaces are not rendered
+	HideInterfaceMethods
+
+	// HideConstructors is to be used in the SetRenderingOptions argument as the key to the map, when value is true, the
+	// constructors are not rendered along with the other functions of the package (see Function.IsConstructor)
+	HideConstructors
+
+	// SmartRelationships is to be used in the SetRenderingOptions argument as the key to the map, when value is true,
+	// the aggregations of the types held by value are rendered as compositions, and only the ones held through a
+	// pointer are rendered as aggregations
+	SmartRelationships
+
+	// MaxSignatureWidth is to be used in the SetRenderingOptions argument as the key to the map, the value is the int
+	// number of characters above which the parameters of the method signatures are wrapped onto continuation lines.
+	// Signatures are never wrapped when it is 0. Only the plantuml renderer wraps signatures
+	MaxSignatureWidth
+
+	// PlainText is to be used in the SetRenderingOptions argument as the key to the map, when value is true, the
+	// keywords of the types (map, chan, struct, interface and func) are rendered without the <font> markup, for the
+	// PlantUML setups that do not support it, such as the ASCII output
+	PlainText
+
+	// Theme is to be used in the SetRenderingOptions argument as the key to the map, the value is the string name of
+	// the PlantUML theme the diagram is rendered with, such as cerulean. No theme is used when it is empty. Only the
+	// plantuml renderer uses themes
+	Theme
+
+	// RenderFieldComments is to be used in the SetRenderingOptions argument as the key to the map, when value is true,
+	// the comments of the fields are rendered after them (see Field.Comment)
+	RenderFieldComments
+
+	// Indent is to be used in the SetRenderingOptions argument as the key to the map, the value is the string repeated at
+	// the beginning of the rendered lines for every level of depth. It can only hold spaces and tabs, and four spaces
+	// are used when it is empty
+	Indent
+
+	// RenderFieldNameLabels is to be used in the SetRenderingOptions argument as the key to the map, when value is
+	// true, the aggregations and compositions are labeled with the names of the fields they were created by
+	RenderFieldNameLabels
+
+	// RenderBuiltinAggregations is to be used in the SetRenderingOptions argument as the key to the map, when value is
+	// true, the aggregations of builtin types such as string or int are rendered as well
+	RenderBuiltinAggregations
+
+	// FlattenPackages is to be used in the SetRenderingOptions argument as the key to the map, when value is true, the
+	// classes of every package are rendered at the top level instead of in a namespace, labeled with their fully qualified
+	// name only when another package has a class with the same name
+	FlattenPackages
+
+	// GroupBy is to be used in the SetRenderingOptions argument as the key to the map, the value is the Grouping the
+	// classes rendered side by side are chosen with
+	GroupBy
+
+	// Connectors is to be used in the SetRenderingOptions argument as the key to the map, the value is a
+	// map[string]string from the kind of the connections ("composition", "extends", "realization", "aggregation",
+	// "alias", "derives" or "constraint") to the arrow the plantuml render draws them with
+	Connectors
+
+	// MarkdownFence is to be used in the SetRenderingOptions argument as the key to the map, when value is true, the
+	// diagrams rendered by the plantuml and mermaid renders are wrapped in a markdown fenced code block
+	MarkdownFence
+
+	// PackageAliases is to be used in the SetRenderingOptions argument as the key to the map, the value is a
+	// map[string]string from the package prefixes to the names the plantuml and mermaid renders display instead
+	PackageAliases
+
+	// PackageLegends is to be used in the SetRenderingOptions argument as the key to the map, when value is true, the
+	// plantuml render draws a note with the number of structs and interfaces of every package in its namespace
+	PackageLegends
+
+	// ShowConstValues is to be used in the SetRenderingOptions argument as the key to the map, when value is true, the
+	// constants of the enumerations are rendered along with their value, as in MaxRetries = 5, when it is a literal or
+	// it is derived from iota
+	ShowConstValues
+
+	// HideSelfReferences is to be used in the SetRenderingOptions argument as the key to the map, when value is true,
+	// the compositions and aggregations whose source and target are the same type are not rendered
+	HideSelfReferences
+
+	// RenderConstraintEdges is to be used in the SetRenderingOptions argument as the key to the map, when value is
+	// true, a dashed edge is drawn from every generic type to the interfaces its type parameters are constrained by,
+	// as in Box[T Shape], when those interfaces were parsed. Generic package functions are connected the same way
+	// when RenderPackageFunctions is set
+	RenderConstraintEdges
+
+	// RenderPackageVars is to be used in the SetRenderingOptions argument as the key to the map, when value is true,
+	// the plantuml render draws the package level variables holding a parsed struct, as in var DefaultClient =
+	// &Client{}, in a class with the var stereotype for every package, with an aggregation to the structs they hold
+	// when RenderAggregations is set
+	RenderPackageVars
+
+	// MaxMembers is to be used in the SetRenderingOptions argument as the key to the map, the value is the int number
+	// of fields, and separately of methods, rendered in every class. The ones past it are replaced with a ... (+K more)
+	// line, which keeps the classes of huge structs legible. Every member is rendered when it is 0
+	MaxMembers
+
+	// QualifiedMemberTypes is to be used in the SetRenderingOptions argument as the key to the map, when value is true,
+	// the types of the fields and parameters are rendered with their fully qualified name, as in []shop.Order, instead of
+	// the short one the types of the current package are rendered with. Only the plantuml and mermaid renders use it
+	QualifiedMemberTypes
 )
 
 // RenderingOption is an alias for an it so it is easier to use it as options in a map (see SetRenderingOptions(map[RenderingOption]bool) error)
 type RenderingOption int
 
+// generatedCode matches the comment that marks the generated files
+var generatedCode = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
 // ClassParser contains the Structure of the parsed files. The Structure is a map of package_names that contains
 // a map of structure_names -> Structs
 type ClassParser struct {
@@ -120,58 +530,165 @@ type ClassParser struct {
 	AllImports         map[string]string
 	AllAliases         map[string]*Alias
 	AllRenamedStructs  map[string]map[string]string
+	// PackageImports holds the dotted paths of the packages imported by each parsed package
+	PackageImports map[string]map[string]struct{}
+	// PackageFunctions holds the functions declared without a receiver in each parsed package, in the order they were
+	// parsed. init functions are left out since they cannot be called
+	PackageFunctions map[string][]*Function
+	// PackageVars holds the variables declared at the level of each parsed package whose type is known, in the order
+	// they were parsed
+	PackageVars map[string][]*Variable
+	buildContext   *build.Context
+	includeTests   bool
+	// expandAnonymousStructs is set from ClassDiagramOptions.ExpandAnonymousStructs
+	expandAnonymousStructs bool
+	// stdInterfaces holds the standard interfaces given in ClassDiagramOptions.StdInterfaces, keyed by their full name
+	stdInterfaces map[string]*Struct
+	// packagePaths maps the dotted import path of every parsed directory to the namespace of its package
+	packagePaths map[string]string
+	// modules caches the module each directory belongs to. Namespaces are computed from the path to ModuleBase
+	// instead of the module path when it is nil
+	modules map[string]*goModule
+	// modulesMutex guards modules, which is shared by the parsers of the directories parsed concurrently
+	modulesMutex *sync.Mutex
+	// currentFile is the path of the file being parsed, recorded in the DefinedIn of what is declared in it
+	currentFile string
+	// skipGenerated is set from ClassDiagramOptions.SkipGenerated
+	skipGenerated bool
+	// fs is the file system the directories, the files and the go.mod files are read from. The OS file system is used
+	// when it is nil
+	fs afero.Fs
+	// continueOnError is set from ClassDiagramOptions.ContinueOnError
+	continueOnError bool
+	// errors holds the errors recorded with continueOnError, in the order they were found
+	errors []error
+	// ignoredTypes holds the fully qualified names of the types marked with the //plantuml:ignore directive
+	ignoredTypes map[string]struct{}
+}
+
+// ParseError is recorded by a ClassParser created with ContinueOnError for every directory or file that could not be
+// read or parsed
+type ParseError struct {
+	Path string
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("Cannot parse %s: %s", e.Path, e.Err)
+}
+
+// Unwrap returns the error the directory or the file failed with
+func (e *ParseError) Unwrap() error {
+	return e.Err
 }
 
 // NewClassDiagramWithOptions returns a new classParser with which can Render the class diagram of
 // files in the given directory passed in the ClassDiargamOptions. This will also alow for different types of FileSystems
 // Passed since it is part of the ClassDiagramOptions as well.
 func NewClassDiagramWithOptions(options *ClassDiagramOptions) (*ClassParser, error) {
-	cwd, err := os.Getwd()
+	moduleBase, err := getModuleBase(options)
 	if err != nil {
 		return nil, err
 	}
 
 	classParser := &ClassParser{
 		RenderingOptions: &RenderingOptions{
-			ModuleBase:       path.Base(cwd),
-			Aggregations:     false,
-			Fields:           true,
-			Methods:          true,
-			Compositions:     true,
-			Implementations:  true,
-			Aliases:          true,
-			ConnectionLabels: false,
-			Title:            "",
-			Notes:            "",
+			ModuleBase:            moduleBase,
+			Aggregations:          false,
+			Fields:                true,
+			Methods:               true,
+			Compositions:          true,
+			Implementations:       true,
+			Aliases:               true,
+			ConnectionLabels:      false,
+			Title:                 "",
+			Notes:                 "",
+			MemberOrder:           MemberOrderVisibility,
+			EmbeddedAsComposition: true,
 		},
-		Structure:         make(map[string]map[string]*Struct),
-		AllInterfaces:     make(map[string]struct{}),
-		AllStructs:        make(map[string]struct{}),
-		AllImports:        make(map[string]string),
-		AllAliases:        make(map[string]*Alias),
-		AllRenamedStructs: make(map[string]map[string]string),
+		Structure:              make(map[string]map[string]*Struct),
+		AllInterfaces:          make(map[string]struct{}),
+		AllStructs:             make(map[string]struct{}),
+		AllImports:             make(map[string]string),
+		AllAliases:             make(map[string]*Alias),
+		AllRenamedStructs:      make(map[string]map[string]string),
+		includeTests:           options.IncludeTests,
+		expandAnonymousStructs: options.ExpandAnonymousStructs,
+		modules:                make(map[string]*goModule),
+		modulesMutex:           &sync.Mutex{},
+		fs:                     options.FileSystem,
+		skipGenerated:          options.SkipGenerated,
+		continueOnError:        options.ContinueOnError,
+	}
+	classParser.stdInterfaces, err = getStdInterfaces(options.StdInterfaces)
+	if err != nil {
+		return nil, err
+	}
+	if len(options.BuildTags) > 0 || options.GOOS != "" || options.GOARCH != "" {
+		classParser.buildContext = newBuildContext(options)
+	}
+	directoryPaths, err := classParser.plannedDirectories(options)
+	if err != nil {
+		return nil, err
+	}
+	err = classParser.parseDirectories(directoryPaths, options.Concurrency)
+	if err != nil {
+		return nil, err
+	}
+	for _, filePath := range options.Files {
+		err := classParser.parseFile(filePath)
+		if err != nil {
+			if err = classParser.parseFailed(filePath, err); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	classParser.addImplementations()
+	err = classParser.SetRenderingOptions(options.RenderingOptions)
+	if err != nil {
+		return nil, err
+	}
+	err = classParser.ApplyOptions(options.Options...)
+	if err != nil {
+		return nil, err
+	}
+	return classParser, nil
+}
+
+// PlannedDirectories returns the directories NewClassDiagramWithOptions would parse with the given options, in the order
+// they would be parsed, without parsing them. The recursive walk skips the ignored, vendor, hidden and too deep
+// directories the same way, so it shows why a package is missing from the diagram. The Files of the options are left
+// out. With ContinueOnError, the directories that cannot be walked are left out instead of failing
+func PlannedDirectories(options *ClassDiagramOptions) ([]string, error) {
+	p := &ClassParser{
+		fs:              options.FileSystem,
+		continueOnError: options.ContinueOnError,
 	}
+	return p.plannedDirectories(options)
+}
+
+// plannedDirectories walks the directories of the given options, when they are recursive, and returns the ones to parse
+func (p *ClassParser) plannedDirectories(options *ClassDiagramOptions) ([]string, error) {
 	ignoreDirectoryMap := map[string]struct{}{}
 	for _, dir := range options.IgnoredDirectories {
 		ignoreDirectoryMap[dir] = struct{}{}
 	}
+	var directoryPaths []string
 	for _, directoryPath := range options.Directories {
 		if options.Recursive {
-			err := afero.Walk(options.FileSystem, directoryPath, func(path string, info os.FileInfo, err error) error {
+			err := afero.Walk(p.fileSystem(), directoryPath, func(path string, info os.FileInfo, err error) error {
 				if err != nil {
-					return err
+					return p.parseFailed(path, err)
 				}
 				if info.IsDir() {
-					if strings.HasPrefix(info.Name(), ".") || info.Name() == "vendor" {
+					if skipDirectory(options, path, info.Name(), ignoreDirectoryMap) {
 						return filepath.SkipDir
 					}
-					if _, ok := ignoreDirectoryMap[path]; ok {
+					if options.MaxDepth >= 0 && directoryDepth(directoryPath, path) > options.MaxDepth {
 						return filepath.SkipDir
 					}
-					err := classParser.parseDirectory(path)
-					if err != nil {
-						return err
-					}
+					directoryPaths = append(directoryPaths, path)
 				}
 				return nil
 			})
@@ -179,29 +696,110 @@ func NewClassDiagramWithOptions(options *ClassDiagramOptions) (*ClassParser, err
 				return nil, err
 			}
 		} else {
-			err := classParser.parseDirectory(directoryPath)
-			if err != nil {
-				return nil, err
-			}
+			directoryPaths = append(directoryPaths, directoryPath)
 		}
 	}
+	return directoryPaths, nil
+}
 
-	for s := range classParser.AllStructs {
-		st := classParser.getStruct(s)
-		if st != nil {
-			for i := range classParser.AllInterfaces {
-				inter := classParser.getStruct(i)
-				if st.ImplementsInterface(inter) {
-					st.AddToExtends(i)
-				}
+// Returns the ModuleBase of the given options. When it is not set, the name of the working directory is used for the OS
+// file system, since the directories are usually given relative to it, while nothing is used for any other file
+// system, which is not related to the working directory
+func getModuleBase(options *ClassDiagramOptions) (string, error) {
+	if options.ModuleBase != "" {
+		return options.ModuleBase, nil
+	}
+	if _, ok := options.FileSystem.(*afero.OsFs); !ok && options.FileSystem != nil {
+		return "", nil
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	return path.Base(cwd), nil
+}
+
+// Returns true if the recursive walk should not go into the directory with the given path and name
+func skipDirectory(options *ClassDiagramOptions, path string, name string, ignoreDirectoryMap map[string]struct{}) bool {
+	if strings.HasPrefix(name, ".") && !options.IncludeHidden {
+		return true
+	}
+	if name == "vendor" && !options.IncludeVendor {
+		return true
+	}
+	if _, ok := ignoreDirectoryMap[path]; ok {
+		return true
+	}
+	for pattern := range ignoreDirectoryMap {
+		if isGlobPattern(pattern) && matchDirectory(pattern, path, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true if the given ignored directory is a glob pattern instead of a path
+func isGlobPattern(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+// Returns true if the directory with the given path and name matches the glob pattern. Patterns without separators
+// are matched against the name of the directory and the others against its whole path, with ** matching any number
+// of directories
+func matchDirectory(pattern string, path string, name string) bool {
+	if !strings.ContainsAny(pattern, `/\`) {
+		match, err := filepath.Match(pattern, name)
+		return err == nil && match
+	}
+	return matchSegments(splitPath(pattern), splitPath(path))
+}
+
+func matchSegments(pattern []string, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
 			}
 		}
+		return false
 	}
-	err = classParser.SetRenderingOptions(options.RenderingOptions)
-	if err != nil {
-		return nil, err
+	if len(segments) == 0 {
+		return false
 	}
-	return classParser, nil
+	match, err := filepath.Match(pattern[0], segments[0])
+	return err == nil && match && matchSegments(pattern[1:], segments[1:])
+}
+
+// Returns how many levels of subdirectories the given path is below the root directory of the walk
+func directoryDepth(root string, path string) int {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return 0
+	}
+	return len(splitPath(rel))
+}
+
+// Returns the build context used to decide which files are parsed according to the given options
+func newBuildContext(options *ClassDiagramOptions) *build.Context {
+	context := build.Default
+	context.BuildTags = options.BuildTags
+	if options.GOOS != "" {
+		context.GOOS = options.GOOS
+	}
+	if options.GOARCH != "" {
+		context.GOARCH = options.GOARCH
+	}
+	if options.FileSystem != nil {
+		// The build constraints are read from the file system the files are parsed from
+		fs := options.FileSystem
+		context.OpenFile = func(path string) (io.ReadCloser, error) {
+			return fs.Open(path)
+		}
+	}
+	return &context
 }
 
 // NewClassDiagram returns a new classParser with which can Render the class diagram of
@@ -211,12 +809,192 @@ func NewClassDiagram(directoryPaths []string, ignoreDirectories []string, recurs
 		Directories:        directoryPaths,
 		IgnoredDirectories: ignoreDirectories,
 		Recursive:          recursive,
+		MaxDepth:           -1,
 		RenderingOptions:   map[RenderingOption]interface{}{},
 		FileSystem:         afero.NewOsFs(),
 	}
 	return NewClassDiagramWithOptions(options)
 }
 
+// NewClassDiagramWithFileSystem returns a new classParser for the given directories of the given file system, which are
+// not walked recursively, with the given rendering options set. Every file is read from fs, so the diagram of an
+// afero.NewMemMapFs() can be rendered in tests without writing to the disk
+func NewClassDiagramWithFileSystem(fs afero.Fs, directoryPaths []string, opts ...Option) (*ClassParser, error) {
+	options := &ClassDiagramOptions{
+		Directories:      directoryPaths,
+		RenderingOptions: map[RenderingOption]interface{}{},
+		Options:          opts,
+		FileSystem:       fs,
+	}
+	return NewClassDiagramWithOptions(options)
+}
+
+// NewClassDiagramFromDirectories returns a new classParser for the given directories, which are not walked recursively,
+// with the given rendering options set. e.g. NewClassDiagramFromDirectories(dirs, WithAggregations(true), WithTitle("X"))
+func NewClassDiagramFromDirectories(directoryPaths []string, opts ...Option) (*ClassParser, error) {
+	options := &ClassDiagramOptions{
+		Directories:      directoryPaths,
+		RenderingOptions: map[RenderingOption]interface{}{},
+		Options:          opts,
+		FileSystem:       afero.NewOsFs(),
+	}
+	return NewClassDiagramWithOptions(options)
+}
+
+// Adds an extends relationship from every struct to each interface it implements, including the standard interfaces
+// that were asked for
+func (p *ClassParser) addImplementations() {
+	p.addImplementationsOf(p.AllStructs, p.allInterfaces())
+}
+
+// allInterfaces returns every parsed interface along with the standard interfaces that were asked for, keyed by their
+// full name
+func (p *ClassParser) allInterfaces() map[string]*Struct {
+	interfaces := p.interfaces(p.AllInterfaces)
+	for i, inter := range p.stdInterfaces {
+		interfaces[i] = inter
+	}
+	return interfaces
+}
+
+// interfaces returns the parsed interfaces with the given full names, keyed by their full name
+func (p *ClassParser) interfaces(names map[string]struct{}) map[string]*Struct {
+	interfaces := make(map[string]*Struct, len(names)+len(p.stdInterfaces))
+	for i := range names {
+		if inter := p.getStruct(i); inter != nil {
+			interfaces[i] = inter
+		}
+	}
+	return interfaces
+}
+
+// addImplementationsOf adds an extends relationship from each one of the given structs to each one of the given
+// interfaces it implements, the methods promoted from the embedded types included. Structs are indexed by the
+// signatures of their methods once, so every interface only needs to look up the structs that have each one of its
+// methods
+func (p *ClassParser) addImplementationsOf(structs map[string]struct{}, interfaces map[string]*Struct) {
+	structsBySignature := map[string]map[string]struct{}{}
+	for s := range structs {
+		st := p.getStruct(s)
+		if st == nil {
+			continue
+		}
+		for _, f := range p.methodSet(st) {
+			signature := f.signature()
+			if _, ok := structsBySignature[signature]; !ok {
+				structsBySignature[signature] = map[string]struct{}{}
+			}
+			structsBySignature[signature][s] = struct{}{}
+		}
+	}
+	for i, inter := range interfaces {
+		if len(inter.Functions) == 0 {
+			continue
+		}
+		var implementations map[string]struct{}
+		for _, f := range inter.Functions {
+			structs := structsBySignature[f.signature()]
+			if implementations == nil {
+				implementations = make(map[string]struct{}, len(structs))
+				for s := range structs {
+					implementations[s] = struct{}{}
+				}
+			} else {
+				for s := range implementations {
+					if _, ok := structs[s]; !ok {
+						delete(implementations, s)
+					}
+				}
+			}
+			if len(implementations) == 0 {
+				break
+			}
+		}
+		for s := range implementations {
+			p.getStruct(s).AddToExtends(i)
+		}
+	}
+}
+
+// methodSet returns the methods of the given structure along with the ones promoted from the types it embeds. As in Go,
+// a promoted method is shadowed by the methods with the same name found at a shallower depth, and it is not promoted
+// when more than one type embedded at the same depth has it. Only the methods of the parsed types are known
+func (p *ClassParser) methodSet(st *Struct) []*Function {
+	result := append([]*Function{}, st.Functions...)
+	shadowed := make(map[string]struct{}, len(st.Functions))
+	for _, f := range st.Functions {
+		shadowed[f.Name] = struct{}{}
+	}
+	visited := map[*Struct]struct{}{st: {}}
+	current := []*Struct{st}
+	for len(current) > 0 {
+		var embedded []*Struct
+		for _, s := range current {
+			for e := range s.Composition {
+				resolved, ok := p.ResolveType(e, s)
+				if !ok {
+					continue
+				}
+				if es := p.Lookup(resolved); es != nil {
+					if _, ok := visited[es]; !ok {
+						visited[es] = struct{}{}
+						embedded = append(embedded, es)
+					}
+				}
+			}
+		}
+		found := map[string]int{}
+		for _, es := range embedded {
+			for _, f := range es.Functions {
+				found[f.Name]++
+			}
+		}
+		for _, es := range embedded {
+			for _, f := range es.Functions {
+				if _, ok := shadowed[f.Name]; !ok && found[f.Name] == 1 {
+					result = append(result, f)
+				}
+			}
+		}
+		for name := range found {
+			shadowed[name] = struct{}{}
+		}
+		current = embedded
+	}
+	return result
+}
+
+// embedders returns the given structs along with the parsed structs that embed any of them, directly or through other
+// embedded types, since the methods they promote could have changed
+func (p *ClassParser) embedders(structs map[string]struct{}) map[string]struct{} {
+	result := make(map[string]struct{}, len(structs))
+	for s := range structs {
+		result[s] = struct{}{}
+	}
+	for added := true; added; {
+		added = false
+		for s := range p.AllStructs {
+			if _, ok := result[s]; ok {
+				continue
+			}
+			st := p.getStruct(s)
+			if st == nil {
+				continue
+			}
+			for e := range st.Composition {
+				if resolved, ok := p.ResolveType(e, st); ok {
+					if _, ok := result[resolved]; ok {
+						result[s] = struct{}{}
+						added = true
+						break
+					}
+				}
+			}
+		}
+	}
+	return result
+}
+
 // parse the given ast.Package into the ClassParser Structure
 func (p *ClassParser) parsePackage(node ast.Node, base string) {
 	pack := node.(*ast.Package)
@@ -232,8 +1010,12 @@ func (p *ClassParser) parsePackage(node ast.Node, base string) {
 	sort.Strings(sortedFiles)
 	for _, fileName := range sortedFiles {
 
-		if !strings.HasSuffix(fileName, "_test.go") {
+		if p.includeTests || !strings.HasSuffix(fileName, "_test.go") {
+			p.currentFile = filepath.Clean(fileName)
 			f := pack.Files[fileName]
+			if p.skipGenerated && isGenerated(f) {
+				continue
+			}
 			for _, d := range f.Imports {
 				p.parseImports(d)
 			}
@@ -242,10 +1024,147 @@ func (p *ClassParser) parsePackage(node ast.Node, base string) {
 			}
 		}
 	}
+	p.removeIgnoredTypes(p.CurrentPackageName)
+}
+
+// isGenerated returns true when the given file has the comment of the generated files before its package clause (see
+// https://golang.org/s/generatedcode)
+func isGenerated(f *ast.File) bool {
+	for _, group := range f.Comments {
+		if group.Pos() >= f.Package {
+			break
+		}
+		for _, comment := range group.List {
+			if generatedCode.MatchString(comment.Text) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ImportedPackages returns the sorted namespaces of the other parsed packages imported by the given package. Imports of
+// packages that were not parsed, such as the standard library or other modules, are left out
+func (p *ClassParser) ImportedPackages(pack string) []string {
+	found := map[string]struct{}{}
+	for importPath := range p.PackageImports[pack] {
+		namespace, ok := p.packagePaths[importPath]
+		if !ok {
+			namespace = importPath
+		}
+		if _, parsed := p.Structure[namespace]; !parsed || namespace == pack {
+			continue
+		}
+		found[namespace] = struct{}{}
+	}
+	result := make([]string, 0, len(found))
+	for namespace := range found {
+		result = append(result, namespace)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Packages returns the sorted names of the parsed packages
+func (p *ClassParser) Packages() []string {
+	result := make([]string, 0, len(p.Structure))
+	for pack := range p.Structure {
+		result = append(result, pack)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Lookup returns the structure with the given fully qualified name, such as github.com.foo.bar.Baz, or nil if it was
+// not parsed
+func (p *ClassParser) Lookup(fqName string) *Struct {
+	split := strings.LastIndex(fqName, ".")
+	if split < 0 {
+		return nil
+	}
+	structures, ok := p.Structure[fqName[:split]]
+	if !ok {
+		return nil
+	}
+	if st, ok := structures[fqName[split+1:]]; ok {
+		return st
+	}
+	// Defined types are stored with their package prefix
+	return structures[fqName]
+}
+
+// ConstraintInterfaces returns the fully qualified names of the parsed interfaces the given type parameters are
+// constrained by, sorted and without duplicates. The constraints declared inline, such as interface{ ~int }, and the
+// interfaces that were not parsed, such as fmt.Stringer when the standard library is not parsed, are left out
+func (p *ClassParser) ConstraintInterfaces(typeParameters []*Field) []string {
+	found := map[string]struct{}{}
+	for _, tp := range typeParameters {
+		constraint := tp.FullType
+		// The type arguments of a generic constraint, as in Ordered[T], are not part of its name
+		if i := strings.Index(constraint, "["); i >= 0 {
+			constraint = constraint[:i]
+		}
+		if _, ok := p.AllInterfaces[constraint]; ok {
+			found[constraint] = struct{}{}
+		}
+	}
+	result := make([]string, 0, len(found))
+	for constraint := range found {
+		result = append(result, constraint)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// ResolveType returns the fully qualified name of the structure a type used by st refers to, such as the type of one
+// of its embedded fields, and whether that structure was parsed. Types without a package belong to the package of st,
+// and the names of the renamed structs are resolved to the name they are rendered with. The types that were not
+// parsed, such as bytes.Buffer when the standard library is not parsed, are returned with ok set to false, so the
+// renderers can draw them as stubs
+func (p *ClassParser) ResolveType(t string, st *Struct) (string, bool) {
+	if !strings.Contains(t, ".") {
+		t = fmt.Sprintf("%s.%s", p.GetPackageName(t, st), t)
+	}
+	if p.Lookup(t) != nil {
+		return t, true
+	}
+	split := strings.SplitN(t, ".", 2)
+	if renamed, ok := p.AllRenamedStructs[split[0]]; ok {
+		name := GenerateRenamedStructName(split[1])
+		if _, ok := renamed[name]; ok {
+			return fmt.Sprintf("%s.%s", split[0], name), true
+		}
+	}
+	return t, false
+}
+
+// RenderedPackageFunctions returns the functions declared without a receiver in the given package that are rendered
+// with the current rendering options. There are none unless PackageFunctions is set, and constructors are left out
+// when HideConstructors is set
+func (p *ClassParser) RenderedPackageFunctions(pack string) []*Function {
+	ro := p.RenderingOptions
+	if !ro.PackageFunctions {
+		return nil
+	}
+	result := make([]*Function, 0, len(p.PackageFunctions[pack]))
+	for _, function := range p.PackageFunctions[pack] {
+		if ro.HideConstructors && function.IsConstructor() {
+			continue
+		}
+		result = append(result, function)
+	}
+	return result
 }
 
 func (p *ClassParser) parseImports(impt *ast.ImportSpec) {
 	clean, _ := strconv.Unquote(impt.Path.Value)
+	if p.PackageImports == nil {
+		p.PackageImports = make(map[string]map[string]struct{})
+	}
+	if _, ok := p.PackageImports[p.CurrentPackageName]; !ok {
+		p.PackageImports[p.CurrentPackageName] = make(map[string]struct{})
+	}
+	p.PackageImports[p.CurrentPackageName][strings.ReplaceAll(clean, "/", ".")] = struct{}{}
 	if impt.Name != nil {
 		p.AllImports[impt.Name.Name] = strings.ReplaceAll(clean, "/", ".")
 	} else {
@@ -254,21 +1173,204 @@ func (p *ClassParser) parseImports(impt *ast.ImportSpec) {
 	}
 }
 
+// fileSystem returns the file system the files are read from, which is the OS one unless another one was given in the
+// ClassDiagramOptions
+func (p *ClassParser) fileSystem() afero.Fs {
+	if p.fs == nil {
+		return afero.NewOsFs()
+	}
+	return p.fs
+}
+
+// parseDirectory parses the go files of the given directory into a package for every package name they declare, the
+// way parser.ParseDir does, but reading them from the file system of the parser
 func (p *ClassParser) parseDirectory(directoryPath string) error {
 	fs := token.NewFileSet()
 
-	found := strings.LastIndex(directoryPath, p.RenderingOptions.ModuleBase)
-	base := strings.Split(directoryPath[found:], "/")
-	result, err := parser.ParseDir(fs, directoryPath, nil, 0)
+	base := p.getPackageBase(directoryPath)
+	infos, err := afero.ReadDir(p.fileSystem(), directoryPath)
 	if err != nil {
+		return p.parseFailed(directoryPath, err)
+	}
+	packages := map[string]*ast.Package{}
+	var packageNames []string
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".go") {
+			continue
+		}
+		if p.buildContext != nil {
+			match, err := p.buildContext.MatchFile(directoryPath, info.Name())
+			// Files that cannot be read are not filtered so reading them reports the error
+			if err == nil && !match {
+				continue
+			}
+		}
+		fileName := filepath.Join(directoryPath, info.Name())
+		src, err := afero.ReadFile(p.fileSystem(), fileName)
+		if err != nil {
+			if err = p.parseFailed(fileName, err); err != nil {
+				return err
+			}
+			continue
+		}
+		f, err := parser.ParseFile(fs, fileName, src, parser.ParseComments)
+		if err != nil {
+			if err = p.parseFailed(fileName, err); err != nil {
+				return err
+			}
+			continue
+		}
+		pack, ok := packages[f.Name.Name]
+		if !ok {
+			pack = &ast.Package{Name: f.Name.Name, Files: map[string]*ast.File{}}
+			packages[f.Name.Name] = pack
+			packageNames = append(packageNames, f.Name.Name)
+		}
+		pack.Files[fileName] = f
+	}
+	sort.Strings(packageNames)
+	for _, name := range packageNames {
+		p.parsePackage(packages[name], base)
+		if !strings.HasSuffix(name, "_test") {
+			p.addPackagePath(directoryPath, p.CurrentPackageName)
+		}
+	}
+	return nil
+}
+
+// parseFailed returns the given error of the directory or the file with the given path, unless the parser continues on
+// errors, in which case it is recorded as a ParseError and nil is returned
+func (p *ClassParser) parseFailed(path string, err error) error {
+	if !p.continueOnError {
 		return err
 	}
-	for _, v := range result {
-		p.parsePackage(v, strings.Join(base[:len(base)-1], "."))
+	p.errors = append(p.errors, &ParseError{Path: path, Err: err})
+	return nil
+}
+
+// Errors returns the errors of the directories and the files that could not be parsed when the ClassParser was
+// created with ContinueOnError. Nothing they declare is in the diagram
+func (p *ClassParser) Errors() []error {
+	return p.errors
+}
+
+//parseFile parses a single go file and adds its declarations to the diagram
+func (p *ClassParser) parseFile(filePath string) error {
+	directoryPath := filepath.Dir(filePath)
+	src, err := afero.ReadFile(p.fileSystem(), filePath)
+	if err != nil {
+		return err
+	}
+	err = p.parseSingleFile(p.getPackageBase(directoryPath), filePath, src)
+	if err != nil {
+		return err
+	}
+	if !strings.HasSuffix(p.CurrentPackageName, "_test") {
+		p.addPackagePath(directoryPath, p.CurrentPackageName)
+	}
+	return nil
+}
+
+//ParseSource parses the given go source and adds its declarations to the diagram. The name is only used to
+//report errors, no file is read. The source is treated as if it was at the root of the module.
+func (p *ClassParser) ParseSource(name string, src []byte) error {
+	err := p.parseSingleFile("", name, src)
+	if err != nil {
+		return err
+	}
+	p.addImplementations()
+	return nil
+}
+
+// Merge adds everything parsed by other to p, so the packages of several module roots parsed on their own can be
+// rendered in a single diagram. Packages are kept apart by their fully qualified names, and the structs of each parser
+// are connected to the interfaces of the other one they implement. It fails without merging anything when a structure
+// was parsed by both. The structures of other are shared with p, not copied
+func (p *ClassParser) Merge(other *ClassParser) error {
+	if other == nil || other == p {
+		return nil
+	}
+	for pack, structures := range other.Structure {
+		for name := range structures {
+			if _, ok := p.Structure[pack][name]; ok {
+				return fmt.Errorf("Cannot merge the diagrams, %s was parsed by both", qualifiedName(pack, name))
+			}
+		}
+	}
+	p.merge(other)
+	for name, inter := range other.stdInterfaces {
+		if p.stdInterfaces == nil {
+			p.stdInterfaces = make(map[string]*Struct)
+		}
+		p.stdInterfaces[name] = inter
+	}
+	p.addImplementations()
+	return nil
+}
+
+//parseSingleFile parses the given source of the file with the given name and feeds it to parsePackage as a package
+//of its own
+func (p *ClassParser) parseSingleFile(base string, fileName string, src []byte) error {
+	fs := token.NewFileSet()
+
+	f, err := parser.ParseFile(fs, fileName, src, parser.ParseComments)
+	if err != nil {
+		return err
 	}
+	pack := &ast.Package{
+		Name:  f.Name.Name,
+		Files: map[string]*ast.File{fileName: f},
+	}
+	p.parsePackage(pack, base)
 	return nil
 }
 
+//getPackageBase returns the dotted package base of the given directory, that is its import path without the last
+//element, which is replaced by the package name in the namespace
+func (p *ClassParser) getPackageBase(directoryPath string) string {
+	base := p.getImportPath(directoryPath)
+	if len(base) == 0 {
+		return ""
+	}
+	return strings.Join(base[:len(base)-1], ".")
+}
+
+//getImportPath returns the elements of the import path of the given directory. It is computed from the path of the
+//module declared in the nearest go.mod, or relative to the module base when there is none
+func (p *ClassParser) getImportPath(directoryPath string) []string {
+	if p.modules != nil {
+		if module := p.findModule(directoryPath); module != nil {
+			if importPath, ok := module.getImportPath(directoryPath); ok {
+				return importPath
+			}
+		}
+	}
+	moduleBase := p.RenderingOptions.ModuleBase
+	found := strings.LastIndex(directoryPath, moduleBase)
+	if moduleBase == "" || found < 0 {
+		// The whole path is used when there is no module base to start from
+		found = 0
+	}
+	return splitPath(directoryPath[found:])
+}
+
+//addPackagePath records the namespace of the package parsed from the given directory, so the imports of the other
+//packages can be resolved to it even when the package name differs from the name of the directory
+func (p *ClassParser) addPackagePath(directoryPath string, namespace string) {
+	if p.packagePaths == nil {
+		p.packagePaths = make(map[string]string)
+	}
+	p.packagePaths[strings.Join(p.getImportPath(directoryPath), ".")] = namespace
+}
+
+//splitPath splits a path into its elements. Both slashes and backslashes are taken as separators so that Windows
+//paths produce the same namespaces as their POSIX equivalent, regardless of the OS the diagram is generated on
+func splitPath(directoryPath string) []string {
+	return strings.FieldsFunc(filepath.ToSlash(directoryPath), func(r rune) bool {
+		return r == '/' || r == '\\'
+	})
+}
+
 // parse the given declaration looking for classes, interfaces, or member functions
 func (p *ClassParser) parseFileDeclarations(node ast.Decl) {
 	switch decl := node.(type) {
@@ -286,10 +1388,14 @@ func (p *ClassParser) handleFuncDecl(decl *ast.FuncDecl) {
 			return
 		}
 
-		// Only get in when the function is defined for a Structure. Global functions are not needed for class diagram
-		theType, _ := getFieldType(decl.Recv.List[0].Type, p.AllImports, p.CurrentPackageName)
+		// Methods are added to the structure of their receiver, functions without one to the functions of their package
+		receiverType, typeParameters := getReceiverType(decl.Recv.List[0].Type)
+		theType, _ := getFieldType(receiverType, p.AllImports, p.CurrentPackageName)
 		theType = replacePackageConstant(theType, "")
 		theType = strings.Trim(theType, "*.")
+		if p.isIgnored(fmt.Sprintf("%s.%s", p.CurrentPackageName, theType)) {
+			return
+		}
 		structure := p.getOrCreateStruct(theType)
 		if structure.Type == "" {
 			structure.Type = "class"
@@ -297,22 +1403,89 @@ func (p *ClassParser) handleFuncDecl(decl *ast.FuncDecl) {
 
 		fullName := fmt.Sprintf("%s%s", p.CurrentPackageName, theType)
 		p.AllStructs[fullName] = struct{}{}
-		structure.AddMethod(&ast.Field{
+		function := structure.addMethod(&ast.Field{
 			Names:   []*ast.Ident{decl.Name},
 			Doc:     decl.Doc,
 			Type:    decl.Type,
 			Tag:     nil,
 			Comment: nil,
-		}, p.AllImports)
+		}, p.AllImports, typeParameters)
+		if function != nil {
+			_, function.PointerReceiver = receiverType.(*ast.StarExpr)
+			function.DefinedIn = p.currentFile
+		}
+		return
+	}
+	if decl.Name.Name != "init" {
+		p.addPackageFunction(decl)
+	}
+}
+
+// addPackageFunction adds the given function, declared without a receiver, to the functions of the current package
+func (p *ClassParser) addPackageFunction(decl *ast.FuncDecl) {
+	typeParameters := getTypeParameters(decl.Type.TypeParams, p.AllImports, p.CurrentPackageName)
+	function := getFunctionWithTypeParameters(decl.Type, decl.Name.Name, p.AllImports, p.CurrentPackageName, getTypeParameterNames(typeParameters))
+	function.TypeParameters = typeParameters
+	function.Pos = decl.Name.Pos()
+	function.DefinedIn = p.currentFile
+	if p.PackageFunctions == nil {
+		p.PackageFunctions = make(map[string][]*Function)
 	}
+	p.PackageFunctions[p.CurrentPackageName] = append(p.PackageFunctions[p.CurrentPackageName], function)
 }
 
 func handleGenDecStructType(p *ClassParser, typeName string, c *ast.StructType) {
 	for _, f := range c.Fields.List {
+		if p.expandAnonymousStructs {
+			f = p.expandAnonymousStruct(typeName, f)
+		}
 		p.getOrCreateStruct(typeName).AddField(f, p.AllImports, p.CurrentPackageName)
 	}
 }
 
+// expandAnonymousStruct adds a class for the anonymous struct type of the given field, if it has one, and returns a
+// copy of the field that refers to that class instead. The struct can be held by a pointer, a slice, an array or a map,
+// so the field keeps its multiplicity. Embedded fields and fields without an anonymous struct are returned as they are
+func (p *ClassParser) expandAnonymousStruct(typeName string, f *ast.Field) *ast.Field {
+	if len(f.Names) == 0 {
+		return f
+	}
+	// Dots would be taken for package separators, so the name of the class is joined with an underscore
+	name := fmt.Sprintf("%s_%s", typeName, f.Names[0].Name)
+	fieldType, anonymous := replaceAnonymousStruct(f.Type, name)
+	if anonymous == nil {
+		return f
+	}
+	st := p.getOrCreateStruct(name)
+	st.Type = "class"
+	st.DefinedIn = p.currentFile
+	p.AllStructs[fmt.Sprintf("%s.%s", p.CurrentPackageName, name)] = struct{}{}
+	handleGenDecStructType(p, name, anonymous)
+	expanded := *f
+	expanded.Type = fieldType
+	return &expanded
+}
+
+// replaceAnonymousStruct returns a copy of the given field type in which the anonymous struct type is replaced by the
+// type with the given name, along with the replaced struct type. It returns the field type and nil when there is no
+// anonymous struct type
+func replaceAnonymousStruct(fieldType ast.Expr, name string) (ast.Expr, *ast.StructType) {
+	switch v := fieldType.(type) {
+	case *ast.StructType:
+		return &ast.Ident{NamePos: v.Pos(), Name: name}, v
+	case *ast.StarExpr:
+		x, anonymous := replaceAnonymousStruct(v.X, name)
+		return &ast.StarExpr{Star: v.Star, X: x}, anonymous
+	case *ast.ArrayType:
+		elt, anonymous := replaceAnonymousStruct(v.Elt, name)
+		return &ast.ArrayType{Lbrack: v.Lbrack, Len: v.Len, Elt: elt}, anonymous
+	case *ast.MapType:
+		value, anonymous := replaceAnonymousStruct(v.Value, name)
+		return &ast.MapType{Map: v.Map, Key: v.Key, Value: value}, anonymous
+	}
+	return fieldType, nil
+}
+
 func handleGenDecInterfaceType(p *ClassParser, typeName string, c *ast.InterfaceType) {
 	for _, f := range c.Methods.List {
 		switch t := f.Type.(type) {
@@ -321,9 +1494,21 @@ func handleGenDecInterfaceType(p *ClassParser, typeName string, c *ast.Interface
 			break
 		case *ast.Ident:
 			st := p.getOrCreateStruct(typeName)
+			if isPrimitive(t) && t.Name != "any" && t.Name != "comparable" && t.Name != "error" {
+				// A builtin type is a type set element of a constraint interface
+				st.TypeSet = append(st.TypeSet, t.Name)
+				break
+			}
+			// An embedded interface is generalized by the embedding one
 			f, _ := getFieldType(t, p.AllImports, st.PackageName)
 			f = replacePackageConstant(f, st.PackageName)
-			st.AddToComposition(f)
+			st.AddToExtends(f)
+			break
+		case *ast.BinaryExpr, *ast.UnaryExpr:
+			// Unions and approximations, such as ~int | ~string, are type set elements of a constraint interface
+			st := p.getOrCreateStruct(typeName)
+			f, _ := getFieldType(t, p.AllImports, st.PackageName)
+			st.TypeSet = append(st.TypeSet, replacePackageConstant(f, ""))
 			break
 		}
 	}
@@ -334,29 +1519,187 @@ func (p *ClassParser) handleGenDecl(decl *ast.GenDecl) {
 		// This might be a type of General Declaration we do not know how to handle.
 		return
 	}
+	if decl.Tok == token.CONST {
+		p.handleConstDecl(decl)
+		return
+	}
+	if decl.Tok == token.VAR {
+		p.handleVarDecl(decl)
+		return
+	}
 	for _, spec := range decl.Specs {
-		p.processSpec(spec)
+		// The doc comment of a single type declaration without parentheses belongs to the GenDecl
+		doc := decl.Doc
+		if decl.Lparen.IsValid() {
+			doc = nil
+		}
+		p.processSpec(spec, doc, decl.Doc)
+	}
+}
+
+// Adds the constants declared in the given const block to the named type of the package they belong to.
+// Constants with no type and no value take the type of the previous one, as it happens with iota enumerations.
+// Constants with no type whose value is a conversion, such as Color(iota), take the type they are converted to.
+// Constants with no value repeat the values of the previous ones, which are evaluated with the iota of their own line
+func (p *ClassParser) handleConstDecl(decl *ast.GenDecl) {
+	var constType ast.Expr
+	var values []ast.Expr
+	for index, spec := range decl.Specs {
+		v, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+		if v.Type != nil {
+			constType = v.Type
+		} else if len(v.Values) > 0 {
+			constType = getConversionType(v.Values[0])
+		}
+		if len(v.Values) > 0 {
+			values = v.Values
+		}
+		ident, ok := constType.(*ast.Ident)
+		if !ok || isPrimitive(ident) {
+			// Only constants of a named type declared in this package can be grouped
+			continue
+		}
+		// Named types are stored the same way aliases are (see processSpec)
+		if p.isIgnored(fmt.Sprintf("%s.%s", p.CurrentPackageName, ident.Name)) {
+			continue
+		}
+		st := p.getOrCreateStruct(fmt.Sprintf("%s.%s", p.CurrentPackageName, ident.Name))
+		for i, name := range v.Names {
+			if name.Name == "_" {
+				continue
+			}
+			st.AddConstant(name.Name, ident.Name)
+			last := st.Constants[len(st.Constants)-1]
+			last.DefinedIn = p.currentFile
+			if i < len(values) {
+				last.Value = getConstantValue(values[i], index)
+			}
+		}
+	}
+}
+
+// getConstantValue returns the value of a constant declared with the given expression on the line of the const block
+// with the given index, which is the value of iota on that line, when it can be evaluated without knowing the other
+// declarations: literals, iota and the arithmetic, such as 1 << iota, and conversions, such as Color(iota), made of
+// them. It returns an empty string otherwise
+func getConstantValue(value ast.Expr, index int) string {
+	result := evaluateConstant(value, index)
+	if result.Kind() == constant.Unknown {
+		return ""
+	}
+	return result.ExactString()
+}
+
+func evaluateConstant(value ast.Expr, index int) constant.Value {
+	switch v := value.(type) {
+	case *ast.BasicLit:
+		return constant.MakeFromLiteral(v.Value, v.Kind, 0)
+	case *ast.Ident:
+		if v.Name == "iota" {
+			return constant.MakeInt64(int64(index))
+		}
+	case *ast.ParenExpr:
+		return evaluateConstant(v.X, index)
+	case *ast.CallExpr:
+		if getConversionType(v) != nil {
+			return evaluateConstant(v.Args[0], index)
+		}
+	case *ast.UnaryExpr:
+		x := evaluateConstant(v.X, index)
+		if x.Kind() == constant.Int && (v.Op == token.ADD || v.Op == token.SUB || v.Op == token.XOR) {
+			return constant.UnaryOp(v.Op, x, 0)
+		}
+	case *ast.BinaryExpr:
+		// Only integer arithmetic is evaluated, which is what the iota sequences use
+		x := evaluateConstant(v.X, index)
+		y := evaluateConstant(v.Y, index)
+		if x.Kind() != constant.Int || y.Kind() != constant.Int {
+			break
+		}
+		switch v.Op {
+		case token.SHL, token.SHR:
+			if s, ok := constant.Uint64Val(y); ok {
+				return constant.Shift(x, v.Op, uint(s))
+			}
+		case token.QUO, token.REM:
+			if constant.Sign(y) != 0 {
+				op := v.Op
+				if op == token.QUO {
+					// QUO_ASSIGN forces the integer division of the operands
+					op = token.QUO_ASSIGN
+				}
+				return constant.BinaryOp(x, op, y)
+			}
+		case token.ADD, token.SUB, token.MUL, token.AND, token.OR, token.XOR, token.AND_NOT:
+			return constant.BinaryOp(x, v.Op, y)
+		}
 	}
+	return constant.MakeUnknown()
 }
 
-func (p *ClassParser) processSpec(spec ast.Spec) {
+// getConversionType returns the type the given constant value is converted to, or nil if it is not a conversion.
+// Calls to builtin functions look like conversions and are not taken into account
+func getConversionType(value ast.Expr) ast.Expr {
+	call, ok := value.(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return nil
+	}
+	if ident, ok := call.Fun.(*ast.Ident); ok {
+		switch ident.Name {
+		case "len", "cap", "real", "imag", "min", "max":
+			return nil
+		}
+	}
+	return call.Fun
+}
+
+// processSpec adds the type declared by the given spec, documented by doc, to the diagram. The plantuml directives of
+// groupDoc, the doc comment of the whole declaration, apply to every type it declares
+func (p *ClassParser) processSpec(spec ast.Spec, doc *ast.CommentGroup, groupDoc *ast.CommentGroup) {
 	var typeName string
 	var alias *Alias
+	var directives typeDirectives
 	declarationType := "alias"
 	switch v := spec.(type) {
 	case *ast.TypeSpec:
 		typeName = v.Name.Name
+		directives = getTypeDirectives(groupDoc, v.Doc)
+		if directives.ignore {
+			p.ignoreType(fmt.Sprintf("%s.%s", p.CurrentPackageName, typeName))
+			return
+		}
+		if v.Doc != nil {
+			doc = v.Doc
+		}
+		typeParameters := getTypeParameters(v.TypeParams, p.AllImports, p.CurrentPackageName)
 		switch c := v.Type.(type) {
 		case *ast.StructType:
 			declarationType = "class"
+			p.getOrCreateStruct(typeName).TypeParameters = typeParameters
 			handleGenDecStructType(p, typeName, c)
 		case *ast.InterfaceType:
 			declarationType = "interface"
+			p.getOrCreateStruct(typeName).TypeParameters = typeParameters
 			handleGenDecInterfaceType(p, typeName, c)
+		case *ast.FuncType:
+			// Function types are not connected to the anonymous type they are declared with, their signature is
+			// rendered instead
+			declarationType = "func"
+			structure := p.getOrCreateStruct(typeName)
+			structure.TypeParameters = typeParameters
+			structure.Signature = getFunctionWithTypeParameters(c, "func", p.AllImports, p.CurrentPackageName, getTypeParameterNames(typeParameters))
 		default:
+			// type A = B declares an alias while type A B defines a new type
+			if !v.Assign.IsValid() {
+				declarationType = "type"
+			}
 			basicType, _ := getFieldType(getBasicType(c), p.AllImports, p.CurrentPackageName)
 
 			aliasType, _ := getFieldType(c, p.AllImports, p.CurrentPackageName)
+			aliasType = replaceTypeParameters(aliasType, getTypeParameterNames(typeParameters))
 			aliasType = replacePackageConstant(aliasType, "")
 			if !IsPrimitiveString(typeName) {
 				typeName = fmt.Sprintf("%s.%s", p.CurrentPackageName, typeName)
@@ -366,6 +1709,13 @@ func (p *ClassParser) processSpec(spec ast.Spec) {
 				packageName = BuiltinPackageName
 			}
 			alias = getNewAlias(fmt.Sprintf("%s.%s", packageName, aliasType), p.CurrentPackageName, typeName)
+			// Instantiations are connected to their generic type, which is parsed as a class of its own
+			if genericType, arguments, ok := getInstantiation(c, p.AllImports, p.CurrentPackageName, getTypeParameterNames(typeParameters)); ok {
+				alias.Name = genericType
+				alias.TypeArguments = arguments
+			}
+			alias.DefinedType = declarationType == "type"
+			p.getOrCreateStruct(typeName).TypeParameters = typeParameters
 
 		}
 	default:
@@ -373,15 +1723,22 @@ func (p *ClassParser) processSpec(spec ast.Spec) {
 		return
 	}
 	p.getOrCreateStruct(typeName).Type = declarationType
+	p.getOrCreateStruct(typeName).DefinedIn = p.currentFile
+	if doc != nil {
+		p.getOrCreateStruct(typeName).Doc = doc.Text()
+	}
+	if directives.note != "" {
+		p.getOrCreateStruct(typeName).Note = directives.note
+	}
 	fullName := fmt.Sprintf("%s.%s", p.CurrentPackageName, typeName)
 	switch declarationType {
 	case "interface":
 		p.AllInterfaces[fullName] = struct{}{}
 	case "class":
 		p.AllStructs[fullName] = struct{}{}
-	case "alias":
+	case "alias", "type":
 		p.AllAliases[typeName] = alias
-		if strings.Count(alias.Name, ".") > 1 {
+		if strings.Count(alias.Name, ".") > 1 && len(alias.TypeArguments) == 0 {
 			pack := strings.SplitN(alias.Name, ".", 2)
 			if _, ok := p.AllRenamedStructs[pack[0]]; !ok {
 				p.AllRenamedStructs[pack[0]] = map[string]string{}
@@ -411,6 +1768,31 @@ func getBasicType(theType ast.Expr) ast.Expr {
 	return theType
 }
 
+// Returns the receiver type of a method without its type parameters along with the names of those parameters.
+// For example *Stack[T] will return *Stack and [T]
+func getReceiverType(receiver ast.Expr) (ast.Expr, []string) {
+	switch t := receiver.(type) {
+	case *ast.StarExpr:
+		x, typeParameters := getReceiverType(t.X)
+		return &ast.StarExpr{Star: t.Star, X: x}, typeParameters
+	case *ast.IndexExpr:
+		return t.X, getIdentNames([]ast.Expr{t.Index})
+	case *ast.IndexListExpr:
+		return t.X, getIdentNames(t.Indices)
+	}
+	return receiver, nil
+}
+
+func getIdentNames(expressions []ast.Expr) []string {
+	names := make([]string, 0, len(expressions))
+	for _, e := range expressions {
+		if ident, ok := e.(*ast.Ident); ok {
+			names = append(names, ident.Name)
+		}
+	}
+	return names
+}
+
 func (p *ClassParser) GetPackageName(t string, st *Struct) string {
 
 	packageName := st.PackageName
@@ -449,36 +1831,173 @@ func (p *ClassParser) getStruct(structName string) *Struct {
 	return pack[split[len(split)-1]]
 }
 
+// IncludedStructures returns the structures of the given package that are included by the IncludePattern and
+// ExcludePattern rendering options. When HideEmptyClasses is set, the structures that would be rendered without any
+// member and without any connection are left out too. The renderers that need the structures of every package get them
+// from AllIncludedStructures instead, which looks for the connections once
+func (p *ClassParser) IncludedStructures(pack string) map[string]*Struct {
+	var connected map[string]struct{}
+	if p.RenderingOptions.HideEmptyClasses {
+		connected = p.connectedStructures()
+	}
+	return p.includedStructures(pack, connected)
+}
+
+// AllIncludedStructures returns the structures IncludedStructures returns for every parsed package, keyed by package.
+// The connected structures HideEmptyClasses keeps are found once for all the packages, so it is meant to be called once
+// per render
+func (p *ClassParser) AllIncludedStructures() map[string]map[string]*Struct {
+	var connected map[string]struct{}
+	if p.RenderingOptions.HideEmptyClasses {
+		connected = p.connectedStructures()
+	}
+	result := make(map[string]map[string]*Struct, len(p.Structure))
+	for pack := range p.Structure {
+		result[pack] = p.includedStructures(pack, connected)
+	}
+	return result
+}
+
+// includedStructures returns the structures of the given package that are included, given the fully qualified names of
+// the connected structures when HideEmptyClasses is set
+func (p *ClassParser) includedStructures(pack string, connected map[string]struct{}) map[string]*Struct {
+	structures := p.Structure[pack]
+	ro := p.RenderingOptions
+	if ro.IncludePattern == nil && ro.ExcludePattern == nil && !ro.HideEmptyClasses {
+		return structures
+	}
+	result := make(map[string]*Struct, len(structures))
+	for name, structure := range structures {
+		fullName := qualifiedName(pack, name)
+		if !ro.IsIncluded(fullName) {
+			continue
+		}
+		if ro.HideEmptyClasses && !p.hasRenderedMembers(structure) {
+			if _, ok := connected[fullName]; !ok {
+				continue
+			}
+		}
+		result[name] = structure
+	}
+	return result
+}
+
+// qualifiedName returns the fully qualified name of the structure with the given name in the given package. Some
+// structures, such as defined types, are already stored with their package prefix
+func qualifiedName(pack string, name string) string {
+	if strings.HasPrefix(name, pack+".") {
+		return name
+	}
+	return fmt.Sprintf("%s.%s", pack, name)
+}
+
+// hasRenderedMembers returns true if any field, method or constant of the structure is rendered with the current
+// rendering options
+func (p *ClassParser) hasRenderedMembers(structure *Struct) bool {
+	ro := p.RenderingOptions
+	if ro.Constants && len(structure.Constants) > 0 {
+		return true
+	}
+	if ro.Fields && len(structure.TypeSet) > 0 {
+		return true
+	}
+	if ro.Fields {
+		for _, field := range structure.Fields {
+			if field.Embedded && ro.EmbeddedAsComposition {
+				continue
+			}
+			if ro.PrivateMembers || field.IsExported() {
+				return true
+			}
+		}
+	}
+	if ro.Methods && structure.Signature != nil {
+		return true
+	}
+	if ro.Methods && !(ro.HideInterfaceMethods && structure.Type == "interface") {
+		for _, method := range structure.Functions {
+			if ro.PrivateMembers || method.IsExported() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// connectedStructures returns the fully qualified names of the types at either end of a connection that is rendered
+// with the current rendering options
+func (p *ClassParser) connectedStructures() map[string]struct{} {
+	ro := p.RenderingOptions
+	result := map[string]struct{}{}
+	connect := func(source string, target string) {
+		if ro.IsIncluded(source) && ro.IsIncluded(target) {
+			result[source] = struct{}{}
+			result[target] = struct{}{}
+		}
+	}
+	for pack, structures := range p.Structure {
+		for name, structure := range structures {
+			fullName := qualifiedName(pack, name)
+			if ro.Compositions && (structure.Type != "class" || ro.EmbeddedAsComposition) {
+				for c := range structure.Composition {
+					if !strings.Contains(c, ".") {
+						c = fmt.Sprintf("%s.%s", p.GetPackageName(c, structure), c)
+					}
+					if ro.IsSelfReference(fullName, c) {
+						continue
+					}
+					connect(c, fullName)
+				}
+			}
+			if ro.Implementations {
+				for c := range structure.Extends {
+					if !strings.Contains(c, ".") {
+						c = fmt.Sprintf("%s.%s", structure.PackageName, c)
+					}
+					connect(c, fullName)
+				}
+			}
+			if ro.Aggregations {
+				aggregations := []map[string]struct{}{structure.Aggregations}
+				if ro.AggregatePrivateMembers {
+					aggregations = append(aggregations, structure.PrivateAggregations)
+				}
+				for _, aggregationMap := range aggregations {
+					for a := range aggregationMap {
+						if p.GetPackageName(a, structure) == BuiltinPackageName {
+							continue
+						}
+						if !strings.Contains(a, ".") {
+							a = fmt.Sprintf("%s.%s", structure.PackageName, a)
+						}
+						if ro.IsSelfReference(fullName, a) {
+							continue
+						}
+						connect(fullName, a)
+					}
+				}
+			}
+		}
+	}
+	if ro.Aliases {
+		for _, alias := range p.AllAliases {
+			connect(alias.Name, alias.AliasOf)
+		}
+	}
+	return result
+}
+
 // SetRenderingOptions Sets the rendering options for the Render() Function
 func (p *ClassParser) SetRenderingOptions(ro map[RenderingOption]interface{}) error {
 	for option, val := range ro {
-		switch option {
-		case RenderAggregations:
-			p.RenderingOptions.Aggregations = val.(bool)
-		case RenderAliases:
-			p.RenderingOptions.Aliases = val.(bool)
-		case RenderCompositions:
-			p.RenderingOptions.Compositions = val.(bool)
-		case RenderFields:
-			p.RenderingOptions.Fields = val.(bool)
-		case RenderImplementations:
-			p.RenderingOptions.Implementations = val.(bool)
-		case RenderMethods:
-			p.RenderingOptions.Methods = val.(bool)
-		case RenderConnectionLabels:
-			p.RenderingOptions.ConnectionLabels = val.(bool)
-		case RenderTitle:
-			p.RenderingOptions.Title = val.(string)
-		case RenderNotes:
-			p.RenderingOptions.Notes = val.(string)
-		case AggregatePrivateMembers:
-			p.RenderingOptions.AggregatePrivateMembers = val.(bool)
-		case RenderPrivateMembers:
-			p.RenderingOptions.PrivateMembers = val.(bool)
-		default:
-			return fmt.Errorf("Invalid Rendering option %v", option)
+		opt, err := getOption(option, val)
+		if err != nil {
+			return err
+		}
+		err = p.ApplyOptions(opt)
+		if err != nil {
+			return fmt.Errorf("option %s: %w", option, err)
 		}
-
 	}
 	return nil
 }
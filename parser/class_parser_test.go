@@ -1,10 +1,17 @@
 package parser
 
 import (
+	"errors"
 	"go/ast"
-	"io/ioutil"
+	goparser "go/parser"
+	"go/token"
+	"path/filepath"
 	"reflect"
+	"sort"
+	"strings"
 	"testing"
+
+	"github.com/spf13/afero"
 )
 
 func TestLineBuilder(t *testing.T) {
@@ -17,6 +24,57 @@ func TestLineBuilder(t *testing.T) {
 
 }
 
+func TestLineWriter(t *testing.T) {
+	b := &strings.Builder{}
+	w := NewLineWriter(b)
+	w.WriteLineWithDepth(1, "text")
+	result := "    text\n"
+	if b.String() != result || w.Err() != nil {
+		t.Errorf("TestLineWriter: Expected text to be %s with no error, got %s and %v", result, b.String(), w.Err())
+	}
+
+	w = NewLineWriter(&failingWriter{})
+	w.WriteLineWithDepth(0, "text")
+	w.WriteLineWithDepth(0, "text")
+	if w.Err() == nil {
+		t.Error("TestLineWriter: Expected an error, got nil")
+	}
+}
+
+func TestLineIndentation(t *testing.T) {
+	s := NewLineStringBuilder("\t")
+	s.WriteLineWithDepth(2, "text")
+	if s.String() != "\t\ttext\n" {
+		t.Errorf("TestLineIndentation: Expected the builder to indent with tabs, got %q", s.String())
+	}
+	b := &strings.Builder{}
+	w := NewLineWriterWithIndent(b, "  ")
+	w.Indent(1)
+	w.WriteLineWithDepth(1, "text")
+	if b.String() != "    text\n" {
+		t.Errorf("TestLineIndentation: Expected the writer to indent with two spaces, got %q", b.String())
+	}
+	parser := getEmptyParser("main")
+	if err := parser.ApplyOptions(WithIndent(" \t")); err != nil || parser.RenderingOptions.Indent != " \t" {
+		t.Errorf("TestLineIndentation: Expected spaces and tabs to be accepted, got %v", err)
+	}
+	if err := parser.ApplyOptions(WithIndent("--")); err == nil {
+		t.Error("TestLineIndentation: Expected an error for an indentation that is not made of spaces and tabs")
+	}
+}
+
+type failingWriter struct {
+	calls int
+}
+
+func (fw *failingWriter) Write(p []byte) (int, error) {
+	fw.calls++
+	if fw.calls > 1 {
+		panic("failingWriter: unexpected write after an error")
+	}
+	return 0, errors.New("write failed")
+}
+
 func TestGetOrCreateStruct(t *testing.T) {
 	tt := []struct {
 		name          string
@@ -154,266 +212,6 @@ func TestGetStruct(t *testing.T) {
 	}
 }
 
-func TestRenderStructFields(t *testing.T) {
-	parser := getEmptyParser("main")
-
-	st := &Struct{
-		Fields: []*Field{
-			{
-				Name: "privateField",
-				Type: "int",
-			},
-			{
-				Name: "PublicField",
-				Type: "string",
-			},
-		},
-	}
-	privateFields := &LineStringBuilder{}
-	publicFields := &LineStringBuilder{}
-	parser.renderStructFields(st, privateFields, publicFields)
-	if privateFields.String() != "        - privateField int\n" {
-		t.Errorf("TestRenderStructFields: expected privateFields to be [        - privateField int\\n] got [%v]", privateFields.String())
-	}
-	if publicFields.String() != "        + PublicField string\n" {
-		t.Errorf("TestRenderStructFields: expected publicFields to be [        + PublicField int\\n] got [%v]", publicFields.String())
-	}
-}
-
-func TestRenderStructures(t *testing.T) {
-
-	structMap := map[string]*Struct{
-		"MainClass": getTestStruct(),
-	}
-	lineB := &LineStringBuilder{}
-	parser := getEmptyParser("main")
-	parser.renderStructures("main", structMap, lineB)
-	expectedResult := "namespace main {\n    class MainClass << (S,Aquamarine) >> {\n        - privateField int\n\n        + PublicField error\n\n        - foo( int,  string) (error, int)\n\n        + Boo( string,  int) int\n\n    }\n}\n\"foopack.AnotherClass\" *-- \"main.MainClass\"\n\n\"main.NewClass\" <|-- \"main.MainClass\"\n\n"
-	if lineB.String() != expectedResult {
-		t.Errorf("TestRenderStructures: expected %s, got %s", expectedResult, lineB.String())
-	}
-	st := getTestStruct()
-	st.Aggregations = map[string]struct{}{"File": {}}
-	st.PrivateAggregations = map[string]struct{}{"File": {}}
-	st.PrivateAggregations = map[string]struct{}{"File2": {}}
-	structMap = map[string]*Struct{
-		"MainClass": st,
-	}
-	lineB = &LineStringBuilder{}
-	parser = getEmptyParser("main")
-	parser.SetRenderingOptions(map[RenderingOption]interface{}{
-		RenderAggregations: true,
-	})
-	parser.renderStructures("main", structMap, lineB)
-	expectedResult = "namespace main {\n    class MainClass << (S,Aquamarine) >> {\n        - privateField int\n\n        + PublicField error\n\n        - foo( int,  string) (error, int)\n\n        + Boo( string,  int) int\n\n    }\n}\n\"foopack.AnotherClass\" *-- \"main.MainClass\"\n\n\"main.NewClass\" <|-- \"main.MainClass\"\n\n\"main.MainClass\" o-- \"main.File\"\n\n"
-	if lineB.String() != expectedResult {
-		t.Errorf("TestRenderStructures: expected %s, got %s", expectedResult, lineB.String())
-	}
-
-	lineB = &LineStringBuilder{}
-	parser = getEmptyParser("main")
-	parser.SetRenderingOptions(map[RenderingOption]interface{}{
-		RenderAggregations:      true,
-		AggregatePrivateMembers: true,
-	})
-	parser.renderStructures("main", structMap, lineB)
-	expectedResult = "namespace main {\n    class MainClass << (S,Aquamarine) >> {\n        - privateField int\n\n        + PublicField error\n\n        - foo( int,  string) (error, int)\n\n        + Boo( string,  int) int\n\n    }\n}\n\"foopack.AnotherClass\" *-- \"main.MainClass\"\n\n\"main.NewClass\" <|-- \"main.MainClass\"\n\n\"main.MainClass\" o-- \"main.File\"\n\"main.MainClass\" o-- \"main.File2\"\n\n"
-	if lineB.String() != expectedResult {
-		t.Errorf("TestRenderStructures: expected %s, got %s", expectedResult, lineB.String())
-	}
-}
-
-func TestRenderStructure(t *testing.T) {
-	parser := getEmptyParser("main")
-	st := getTestStruct()
-	lineBuilder := &LineStringBuilder{}
-	compositionBuilder := &LineStringBuilder{}
-	extendBuilder := &LineStringBuilder{}
-	aggregationsBuilder := &LineStringBuilder{}
-	parser.renderStructure(st, "main", "TestClass", lineBuilder, compositionBuilder, extendBuilder, aggregationsBuilder)
-	expectedLineBuilder := "    class TestClass << (S,Aquamarine) >> {\n        - privateField int\n\n        + PublicField error\n\n        - foo( int,  string) (error, int)\n\n        + Boo( string,  int) int\n\n    }\n"
-	if lineBuilder.String() != expectedLineBuilder {
-		t.Errorf("TestRenderStructure: Expected lineBuilder [%s] got [%s]", expectedLineBuilder, lineBuilder.String())
-	}
-	expectedComposition := "\"foopack.AnotherClass\" *-- \"main.TestClass\"\n"
-	if compositionBuilder.String() != expectedComposition {
-		t.Errorf("TestRenderStructure: Expected compositionBuilder %s got %s", expectedComposition, compositionBuilder.String())
-	}
-	expectedExtends := "\"main.NewClass\" <|-- \"main.TestClass\"\n"
-	if extendBuilder.String() != expectedExtends {
-		t.Errorf("TestRenderStructure: Expected extendBuilder %s got %s", expectedExtends, extendBuilder.String())
-	}
-	expectedAggregations := ""
-	if aggregationsBuilder.String() != expectedAggregations {
-		t.Errorf("TestRenderStructure: Expected aggregationsBuilder %s got %s", expectedAggregations, aggregationsBuilder.String())
-	}
-}
-
-func getTestStruct() *Struct {
-	return &Struct{
-		Type:        "class",
-		PackageName: "main",
-		Composition: map[string]struct{}{
-			"foopack.AnotherClass": {},
-		},
-		Extends: map[string]struct{}{
-			"NewClass": {},
-		},
-		Aggregations: map[string]struct{}{},
-		Fields: []*Field{
-			{
-				Name: "privateField",
-				Type: "int",
-			},
-			{
-				Name: "PublicField",
-				Type: "error",
-			},
-		},
-		Functions: []*Function{
-			{
-				Name: "foo",
-				Parameters: []*Field{
-					{
-						Type: "int",
-					},
-					{
-						Type: "string",
-					},
-				},
-				ReturnValues: []string{"error", "int"},
-			},
-			{
-				Name: "Boo",
-				Parameters: []*Field{
-					{
-						Type: "string",
-					},
-					{
-						Type: "int",
-					},
-				},
-				ReturnValues: []string{"int"},
-			},
-		},
-	}
-}
-
-func TestRenderCompositions(t *testing.T) {
-	parser := getEmptyParser("main")
-	st := &Struct{
-		PackageName: "main",
-		Composition: map[string]struct{}{
-			"foopack.AnotherClass": {},
-		},
-		Extends: map[string]struct{}{
-			"foopack.YetAnotherClass": {},
-		},
-	}
-	extendsBuilder := &LineStringBuilder{}
-	parser.renderCompositions(st, "TestClass", extendsBuilder)
-	expectedResult := "\"foopack.AnotherClass\" *-- \"main.TestClass\"\n"
-	if extendsBuilder.String() != expectedResult {
-		t.Errorf("TestRenderCompositions: Expected %s got %s", expectedResult, extendsBuilder.String())
-	}
-
-	st = &Struct{
-		PackageName: "main",
-		Composition: map[string]struct{}{
-			"AnotherClass": {},
-		},
-	}
-	extendsBuilder = &LineStringBuilder{}
-	parser.renderCompositions(st, "TestClass", extendsBuilder)
-	expectedResult = "\"main.AnotherClass\" *-- \"main.TestClass\"\n"
-	if extendsBuilder.String() != expectedResult {
-		t.Errorf("TestRenderCompositions: Expected %s got %s", expectedResult, extendsBuilder.String())
-	}
-
-	st = &Struct{
-		PackageName: "main",
-		Composition: map[string]struct{}{
-			"int": {},
-		},
-	}
-	extendsBuilder = &LineStringBuilder{}
-	parser.renderCompositions(st, "TestClass", extendsBuilder)
-	expectedResult = "\"" + BuiltinPackageName + ".int\" *-- \"main.TestClass\"\n"
-	if extendsBuilder.String() != expectedResult {
-		t.Errorf("TestRenderCompositions: Expected %s got %s", expectedResult, extendsBuilder.String())
-	}
-}
-func TestRenderExtends(t *testing.T) {
-	parser := getEmptyParser("main")
-	st := &Struct{
-		PackageName: "main",
-		Extends: map[string]struct{}{
-			"foopack.AnotherClass": {},
-		},
-	}
-	extendsBuilder := &LineStringBuilder{}
-	parser.renderExtends(st, "TestClass", extendsBuilder)
-	expectedResult := "\"foopack.AnotherClass\" <|-- \"main.TestClass\"\n"
-	if extendsBuilder.String() != expectedResult {
-		t.Errorf("TestRenderExtends: Expected %s got %s", expectedResult, extendsBuilder.String())
-	}
-
-	st = &Struct{
-		PackageName: "main",
-		Extends: map[string]struct{}{
-			"AnotherClass": {},
-		},
-	}
-	extendsBuilder = &LineStringBuilder{}
-	parser.renderExtends(st, "TestClass", extendsBuilder)
-	expectedResult = "\"main.AnotherClass\" <|-- \"main.TestClass\"\n"
-	if extendsBuilder.String() != expectedResult {
-		t.Errorf("TestRenderExtends: Expected %s got %s", expectedResult, extendsBuilder.String())
-	}
-}
-func TestRenderStructMethods(t *testing.T) {
-
-	parser := getEmptyParser("main")
-
-	st := &Struct{
-		Functions: []*Function{
-			{
-				Name: "foo",
-				Parameters: []*Field{
-					{
-						Type: "int",
-					},
-					{
-						Type: "string",
-					},
-				},
-				ReturnValues: []string{"error", "int"},
-			},
-			{
-				Name: "Bar",
-				Parameters: []*Field{
-					{
-						Type: "int",
-					},
-					{
-						Type: "string",
-					},
-				},
-				ReturnValues: []string{"int"},
-			},
-		},
-	}
-	privateFunctions := &LineStringBuilder{}
-	publicFunctions := &LineStringBuilder{}
-	parser.renderStructMethods(st, privateFunctions, publicFunctions)
-	if privateFunctions.String() != "        - foo( int,  string) (error, int)\n" {
-		t.Errorf("TestRenderStructMethods: expected privateFields to be [        - foo( int,  string) (error, int)\\n] got [%v]", privateFunctions.String())
-	}
-	if publicFunctions.String() != "        + Bar( int,  string) int\n" {
-		t.Errorf("TestRenderStructMethods: expected publicFields to be [        + Bar( int,  string) int\\n] got [%v]", publicFunctions.String())
-	}
-}
-
 func getEmptyParser(packageName string) *ClassParser {
 	result := &ClassParser{
 		RenderingOptions: &RenderingOptions{
@@ -480,12 +278,12 @@ func TestNewClassDiagram(t *testing.T) {
 				Exists bool
 			}{
 				{
-					Name:   "testingsupport.test",
+					Name:   "github.com.jfeliu007.goplantuml.testingsupport.test",
 					Type:   "class",
 					Exists: true,
 				},
 				{
-					Name:   "subfolder.test2",
+					Name:   "github.com.jfeliu007.goplantuml.testingsupport.subfolder.test2",
 					Type:   "interface",
 					Exists: true,
 				},
@@ -502,12 +300,12 @@ func TestNewClassDiagram(t *testing.T) {
 				Exists bool
 			}{
 				{
-					Name:   "testingsupport.test",
+					Name:   "github.com.jfeliu007.goplantuml.testingsupport.test",
 					Type:   "class",
 					Exists: true,
 				},
 				{
-					Name:   "subfolder.test2",
+					Name:   "github.com.jfeliu007.goplantuml.testingsupport.subfolder.test2",
 					Type:   "interface",
 					Exists: false,
 				},
@@ -549,29 +347,6 @@ func TestNewClassDiagram(t *testing.T) {
 	}
 }
 
-func TestRender(t *testing.T) {
-
-	parser, err := NewClassDiagram([]string{"../testingsupport"}, []string{}, false)
-	if err != nil {
-		t.Errorf("TestRender: expected no errors, got %s", err.Error())
-		return
-	}
-	parser.SetRenderingOptions(map[RenderingOption]interface{}{
-		RenderTitle:          "Test Title",
-		RenderNotes:          "Notes Example 1\nNotes Example 1 continues\nNotes Example 2",
-		RenderPrivateMembers: true,
-	})
-
-	resultRender := parser.Render()
-	result, err := ioutil.ReadFile("../testingsupport/testingsupport.puml")
-	if err != nil {
-		t.Errorf("TestRender: expected no errors reading testing file, got %s", err.Error())
-	}
-	if string(result) != resultRender {
-		t.Errorf("TestRender: Expected renders to be the same as %s , but got %s", result, resultRender)
-	}
-}
-
 func TestGetPackageName(t *testing.T) {
 	p := getEmptyParser("main")
 	s := &Struct{
@@ -583,24 +358,6 @@ func TestGetPackageName(t *testing.T) {
 	}
 }
 
-func TestMultipleFolders(t *testing.T) {
-	parser, err := NewClassDiagram([]string{"../testingsupport/subfolder3", "../testingsupport/subfolder2"}, []string{}, false)
-
-	if err != nil {
-		t.Errorf("TestMultipleFolders: expected no errors, got %s", err.Error())
-		return
-	}
-
-	resultRender := parser.Render()
-	result, err := ioutil.ReadFile("../testingsupport/subfolder1-2.puml")
-	if err != nil {
-		t.Errorf("TestMultipleFolders: expected no errors reading testing file, got %s", err.Error())
-	}
-	if string(result) != resultRender {
-		t.Errorf("TestMultipleFolders: Expected renders to be the same as %s , but got %s", result, resultRender)
-	}
-}
-
 func TestIgnoreDirectories(t *testing.T) {
 
 	parser, err := NewClassDiagram([]string{"../testingsupport"}, []string{}, true)
@@ -608,7 +365,7 @@ func TestIgnoreDirectories(t *testing.T) {
 		t.Errorf("TestIgnoreDirectories: expected no errors, got %s", err.Error())
 		return
 	}
-	st := parser.getStruct("subfolder2.Subfolder2")
+	st := parser.getStruct("github.com.jfeliu007.goplantuml.testingsupport.subfolder2.Subfolder2")
 	if st == nil {
 		t.Errorf("TestIgnoreDirectories: expected st to not be nil, got %v", st)
 		return
@@ -620,47 +377,13 @@ func TestIgnoreDirectories(t *testing.T) {
 		t.Errorf("TestIgnoreDirectories: expected no errors, got %s", err.Error())
 		return
 	}
-	st = parser.getStruct("subfolder2.Subfolder2")
+	st = parser.getStruct("github.com.jfeliu007.goplantuml.testingsupport.subfolder2.Subfolder2")
 	if st != nil {
 		t.Errorf("TestIgnoreDirectories: expected st to be nil, got %v", st)
 		return
 	}
 }
 
-func TestRenderAggregations(t *testing.T) {
-	parser := getEmptyParser("main")
-	st := &Struct{
-		PackageName: "main",
-		Aggregations: map[string]struct{}{
-			"File": {},
-		},
-	}
-	parser.RenderingOptions.Aggregations = true
-	aggregationsBuilder := &LineStringBuilder{}
-	parser.renderAggregations(st, "TestClass", aggregationsBuilder)
-	expectedResult := "\"main.TestClass\" o-- \"main.File\"\n"
-	if aggregationsBuilder.String() != expectedResult {
-		t.Errorf("TestRenderExtends: Expected %s got %s", expectedResult, aggregationsBuilder.String())
-	}
-
-	st = &Struct{
-		PackageName: "main",
-		Fields: []*Field{
-			{
-				Name: "file",
-				Type: "File",
-			},
-		},
-	}
-	parser.RenderingOptions.Aggregations = true
-	aggregationsBuilder = &LineStringBuilder{}
-	parser.renderAggregations(st, "TestClass", aggregationsBuilder)
-	expectedResult = ""
-	if aggregationsBuilder.String() != expectedResult {
-		t.Errorf("TestRenderExtends: Expected %s got %s", expectedResult, aggregationsBuilder.String())
-	}
-}
-
 func TestSetRenderingOptions(t *testing.T) {
 	parser := getEmptyParser("main")
 	emptyRenderingOptions := &RenderingOptions{
@@ -707,17 +430,23 @@ func TestSetRenderingOptions(t *testing.T) {
 	}
 }
 
-func TestRenderCompositionFromInterfaces(t *testing.T) {
+func TestRenderExtendsFromInterfaces(t *testing.T) {
 
 	parser, err := NewClassDiagram([]string{"../testingsupport/subfolder"}, []string{}, false)
 
 	if err != nil {
-		t.Errorf("TestIgnoreDirectories: expected no errors, got %s", err.Error())
+		t.Errorf("TestRenderExtendsFromInterfaces: expected no errors, got %s", err.Error())
 		return
 	}
-	st := parser.getStruct("subfolder.test2")
-	if _, ok := st.Composition["subfolder.TestInterfaceAsField"]; !ok {
-		t.Errorf("TestRenderCompositionFromInterfaces: expected st to have a composition dependency to subfolder.TestInterfaceAsField")
+	st := parser.getStruct("github.com.jfeliu007.goplantuml.testingsupport.subfolder.test2")
+	if st == nil {
+		t.Fatal("TestRenderExtendsFromInterfaces: expected test2 to exist")
+	}
+	if _, ok := st.Extends["github.com.jfeliu007.goplantuml.testingsupport.subfolder.TestInterfaceAsField"]; !ok {
+		t.Errorf("TestRenderExtendsFromInterfaces: expected st to extend TestInterfaceAsField, got %v", st.Extends)
+	}
+	if len(st.Composition) != 0 {
+		t.Errorf("TestRenderExtendsFromInterfaces: expected st to have no compositions, got %v", st.Composition)
 	}
 }
 
@@ -787,7 +516,7 @@ func TestGetBasic(t *testing.T) {
 					},
 				},
 			},
-			ExpecterResult: "<font color=blue>struct</font>{int, string}",
+			ExpecterResult: "struct{int, string}",
 		},
 		{
 			Name: "*int",
@@ -810,7 +539,7 @@ func TestGetBasic(t *testing.T) {
 	}
 	for _, tc := range tt {
 		t.Run(tc.Name, func(t *testing.T) {
-			basicType, _ := getFieldType(getBasicType(tc.Input), map[string]string{})
+			basicType, _ := getFieldType(getBasicType(tc.Input), map[string]string{}, "main")
 			if basicType != tc.ExpecterResult {
 				t.Errorf("Expected %s got %s", tc.ExpecterResult, basicType)
 			}
@@ -818,125 +547,6 @@ func TestGetBasic(t *testing.T) {
 	}
 }
 
-func TestRenderingOptions(t *testing.T) {
-	tt := []struct {
-		Name             string
-		RenderingOptions map[RenderingOption]interface{}
-		InputFolder      string
-		ExpectedResult   string
-	}{
-		{
-			Name:        "Show Fields",
-			InputFolder: "../testingsupport/renderingoptions",
-			RenderingOptions: map[RenderingOption]interface{}{
-				RenderPrivateMembers: true,
-			},
-			ExpectedResult: `@startuml
-namespace renderingoptions {
-    class Test << (S,Aquamarine) >> {
-        - integer int
-
-        - function() 
-
-    }
-}
-
-
-@enduml
-`,
-		}, {
-			Name:        "Hide Fields",
-			InputFolder: "../testingsupport/renderingoptions",
-			RenderingOptions: map[RenderingOption]interface{}{
-				RenderFields:         false,
-				RenderPrivateMembers: true,
-			},
-			ExpectedResult: `@startuml
-namespace renderingoptions {
-    class Test << (S,Aquamarine) >> {
-        - integer int
-
-        - function() 
-
-    }
-}
-
-
-hide fields
-@enduml
-`,
-		},
-		{
-			Name:        "Show Methods",
-			InputFolder: "../testingsupport/renderingoptions",
-			RenderingOptions: map[RenderingOption]interface{}{
-				RenderPrivateMembers: true,
-			},
-			ExpectedResult: `@startuml
-namespace renderingoptions {
-    class Test << (S,Aquamarine) >> {
-        - integer int
-
-        - function() 
-
-    }
-}
-
-
-@enduml
-`,
-		}, {
-			Name:        "Hide Methods",
-			InputFolder: "../testingsupport/renderingoptions",
-			RenderingOptions: map[RenderingOption]interface{}{
-				RenderMethods:        false,
-				RenderPrivateMembers: true,
-			},
-			ExpectedResult: `@startuml
-namespace renderingoptions {
-    class Test << (S,Aquamarine) >> {
-        - integer int
-
-        - function() 
-
-    }
-}
-
-
-hide methods
-@enduml
-`,
-		}, {
-			Name:             "Hide Private Members",
-			InputFolder:      "../testingsupport/renderingoptions",
-			RenderingOptions: map[RenderingOption]interface{}{},
-			ExpectedResult: `@startuml
-namespace renderingoptions {
-    class Test << (S,Aquamarine) >> {
-    }
-}
-
-
-@enduml
-`,
-		},
-	}
-	for _, tc := range tt {
-		t.Run(tc.Name, func(t *testing.T) {
-			parser, err := NewClassDiagram([]string{tc.InputFolder}, []string{}, false)
-			parser.SetRenderingOptions(tc.RenderingOptions)
-			if err != nil {
-				t.Errorf(err.Error())
-				return
-			}
-			result := parser.Render()
-			if result != tc.ExpectedResult {
-				t.Errorf("Expected \n%v\ngot\n%v\n", tc.ExpectedResult, result)
-			}
-		})
-	}
-}
-
 func TestHandleGenDecl(t *testing.T) {
 	parser := getEmptyParser("main")
 	defer func() {
@@ -950,57 +560,15 @@ func TestHandleGenDecl(t *testing.T) {
 	})
 }
 
-func TestConnectionLabelsRendering(t *testing.T) {
-	parser, err := NewClassDiagram([]string{"../testingsupport/connectionlabels"}, []string{}, false)
+func TestNewClassDiagramWithOptions(t *testing.T) {
+	options := &ClassDiagramOptions{
+		RenderingOptions: map[RenderingOption]interface{}{
+			RenderAggregations: true,
+		},
+	}
+	parser, err := NewClassDiagramWithOptions(options)
 	if err != nil {
-		t.Errorf("TestConnectionLabelsRendering: expected no error but got %s", err.Error())
-		return
-	}
-	parser.SetRenderingOptions(map[RenderingOption]interface{}{
-		RenderConnectionLabels: true,
-		RenderAggregations:     true,
-		RenderPrivateMembers:   true,
-	})
-	result := parser.Render()
-	expectedResult := `@startuml
-namespace connectionlabels {
-    interface AbstractInterface  {
-        - interfaceFunction() bool
-
-    }
-    class ImplementsAbstractInterface << (S,Aquamarine) >> {
-        + PublicUse AbstractInterface
-
-        - interfaceFunction() bool
-
-    }
-    class connectionlabels.AliasOfInt << (T, #FF7700) >>  {
-    }
-}
-"connectionlabels.AliasOfInt" *-- "extends""connectionlabels.ImplementsAbstractInterface"
-
-"connectionlabels.AbstractInterface" <|-- "implements""connectionlabels.ImplementsAbstractInterface"
-
-"connectionlabels.ImplementsAbstractInterface""uses" o-- "connectionlabels.AbstractInterface"
-
-"__builtin__.int" #.. "alias of""connectionlabels.AliasOfInt"
-@enduml
-`
-	if result != expectedResult {
-		t.Errorf("TestConnectionLabelsRendering: expecting \n%s\n got \n%s\n", expectedResult, result)
-	}
-
-}
-
-func TestNewClassDiagramWithOptions(t *testing.T) {
-	options := &ClassDiagramOptions{
-		RenderingOptions: map[RenderingOption]interface{}{
-			RenderAggregations: true,
-		},
-	}
-	parser, err := NewClassDiagramWithOptions(options)
-	if err != nil {
-		t.Errorf(err.Error())
+		t.Errorf(err.Error())
 	}
 	if parser.RenderingOptions.Aggregations != true {
 		t.Errorf("TestNewClassDiagramWithOptions: Expected Aggregations to be true got false")
@@ -1026,31 +594,1913 @@ func TestClassParser_handleFuncDecl(t *testing.T) {
 	}
 }
 
-func TestParametrizedTypeDeclarations(t *testing.T) {
-	parser, err := NewClassDiagram([]string{"../testingsupport/parenthesizedtypedeclarations"}, []string{}, false)
+func TestGenericTypeDeclarations(t *testing.T) {
+	source := `package main
+
+type Stack[T any] struct {
+	items []T
+	Top   *T
+}
+
+func (s *Stack[E]) Push(v E) {}
+
+func (s *Stack[T]) Pop() (T, bool) {}
+`
+	f, err := goparser.ParseFile(token.NewFileSet(), "stack.go", source, 0)
 	if err != nil {
-		t.Errorf("TestConnectionLabelsRendering: expected no error but got %s", err.Error())
-		return
+		t.Fatalf("TestGenericTypeDeclarations: expected no errors, got %s", err.Error())
+	}
+	parser := getEmptyParser("main")
+	for _, d := range f.Decls {
+		parser.parseFileDeclarations(d)
+	}
+	st := parser.getStruct("main.Stack")
+	if st == nil {
+		t.Fatal("TestGenericTypeDeclarations: expected main.Stack to exist, got nil")
+	}
+	expectedTypeParameters := []*Field{{Name: "T", Type: "any", FullType: "any"}}
+	if !reflect.DeepEqual(st.TypeParameters, expectedTypeParameters) {
+		t.Errorf("TestGenericTypeDeclarations: expected type parameters %v, got %v", expectedTypeParameters, st.TypeParameters)
+	}
+	if st.Fields[0].Type != "[]T" || st.Fields[1].Type != "*T" {
+		t.Errorf("TestGenericTypeDeclarations: expected fields of type []T and *T, got %s and %s", st.Fields[0].Type, st.Fields[1].Type)
+	}
+	if len(st.Aggregations) != 0 || len(st.PrivateAggregations) != 0 {
+		t.Errorf("TestGenericTypeDeclarations: expected no aggregations to type parameters, got %v %v", st.Aggregations, st.PrivateAggregations)
+	}
+	if len(st.Functions) != 2 {
+		t.Fatalf("TestGenericTypeDeclarations: expected 2 methods, got %d", len(st.Functions))
+	}
+	if st.Functions[0].Parameters[0].Type != "E" {
+		t.Errorf("TestGenericTypeDeclarations: expected Push parameter to be of type E, got %s", st.Functions[0].Parameters[0].Type)
+	}
+	if !reflect.DeepEqual(st.Functions[1].ReturnValues, []string{"T", "bool"}) {
+		t.Errorf("TestGenericTypeDeclarations: expected Pop to return [T bool], got %v", st.Functions[1].ReturnValues)
+	}
+}
+
+func TestConstantDeclarations(t *testing.T) {
+	source := `package main
+
+const (
+	Red Color = iota
+	Green
+	_
+	Blue
+)
+
+const Untyped = 5
+
+const (
+	A Kind = "a"
+	B      = "b"
+)
+
+const (
+	Idle   = State(iota)
+	Running
+	Length = len("abc")
+)
+
+type Color int
+
+type Kind string
+
+type State int
+`
+	f, err := goparser.ParseFile(token.NewFileSet(), "enum.go", source, 0)
+	if err != nil {
+		t.Fatalf("TestConstantDeclarations: expected no errors, got %s", err.Error())
+	}
+	parser := getEmptyParser("main")
+	for _, d := range f.Decls {
+		parser.parseFileDeclarations(d)
+	}
+	color := parser.Structure["main"]["main.Color"]
+	if color == nil {
+		t.Fatal("TestConstantDeclarations: expected main.Color to exist, got nil")
+	}
+	expectedConstants := []*Field{{Name: "Red", Type: "Color", Value: "0"}, {Name: "Green", Type: "Color", Value: "1"}, {Name: "Blue", Type: "Color", Value: "3"}}
+	if !reflect.DeepEqual(color.Constants, expectedConstants) {
+		t.Errorf("TestConstantDeclarations: expected constants %v, got %v", expectedConstants, color.Constants)
+	}
+	if color.Type != "type" {
+		t.Errorf("TestConstantDeclarations: expected main.Color to be a defined type, got %s", color.Type)
+	}
+	kind := parser.Structure["main"]["main.Kind"]
+	expectedConstants = []*Field{{Name: "A", Type: "Kind", Value: `"a"`}}
+	if kind == nil || !reflect.DeepEqual(kind.Constants, expectedConstants) {
+		t.Errorf("TestConstantDeclarations: expected main.Kind to have constants %v, got %v", expectedConstants, kind)
+	}
+	state := parser.Structure["main"]["main.State"]
+	expectedConstants = []*Field{{Name: "Idle", Type: "State", Value: "0"}, {Name: "Running", Type: "State", Value: "1"}}
+	if state == nil || !reflect.DeepEqual(state.Constants, expectedConstants) {
+		t.Errorf("TestConstantDeclarations: expected main.State to have constants %v, got %v", expectedConstants, state)
+	}
+}
+
+func TestAliasAndDefinedTypeDeclarations(t *testing.T) {
+	source := `package main
+
+type Celsius float64
+
+type Temperature = float64
+`
+	f, err := goparser.ParseFile(token.NewFileSet(), "types.go", source, 0)
+	if err != nil {
+		t.Fatalf("TestAliasAndDefinedTypeDeclarations: expected no errors, got %s", err.Error())
+	}
+	parser := getEmptyParser("main")
+	for _, d := range f.Decls {
+		parser.parseFileDeclarations(d)
+	}
+	tt := []struct {
+		name                string
+		expectedType        string
+		expectedDefinedType bool
+	}{
+		{
+			name:                "main.Celsius",
+			expectedType:        "type",
+			expectedDefinedType: true,
+		},
+		{
+			name:                "main.Temperature",
+			expectedType:        "alias",
+			expectedDefinedType: false,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			st := parser.Structure["main"][tc.name]
+			if st == nil || st.Type != tc.expectedType {
+				t.Errorf("Expected %s to be of type %s, got %v", tc.name, tc.expectedType, st)
+			}
+			alias := parser.AllAliases[tc.name]
+			if alias == nil || alias.DefinedType != tc.expectedDefinedType {
+				t.Errorf("Expected alias %s to have DefinedType %t, got %v", tc.name, tc.expectedDefinedType, alias)
+			}
+		})
+	}
+}
+
+func TestSetRenderingOptionsMemberOrder(t *testing.T) {
+	parser := getEmptyParser("main")
+	err := parser.SetRenderingOptions(map[RenderingOption]interface{}{
+		RenderMemberOrder: MemberOrderSource,
+	})
+	if err != nil || parser.RenderingOptions.MemberOrder != MemberOrderSource {
+		t.Errorf("TestSetRenderingOptionsMemberOrder: expected member order to be %s with no error, got %s and %v", MemberOrderSource, parser.RenderingOptions.MemberOrder, err)
+	}
+	err = parser.SetRenderingOptions(map[RenderingOption]interface{}{
+		RenderMemberOrder: MemberOrder("random"),
+	})
+	if err == nil {
+		t.Error("TestSetRenderingOptionsMemberOrder: expected error got nil")
+	}
+}
+
+func TestAddImplementations(t *testing.T) {
+	source := `package main
+
+type Reader interface {
+	Read(p []byte) (int, error)
+}
+
+type ReadCloser interface {
+	Read(p []byte) (int, error)
+	Close() error
+}
+
+type Empty interface {
+}
+
+type File struct {
+}
+
+func (f *File) Read(b []byte) (int, error) {}
+
+func (f *File) Close() error {}
+
+type Buffer struct {
+}
+
+func (b *Buffer) Read(b []byte) (int, error) {}
+
+type Other struct {
+}
+
+func (o *Other) Read(b []int) (int, error) {}
+`
+	f, err := goparser.ParseFile(token.NewFileSet(), "impl.go", source, 0)
+	if err != nil {
+		t.Fatalf("TestAddImplementations: expected no errors, got %s", err.Error())
+	}
+	parser := getEmptyParser("main")
+	for _, d := range f.Decls {
+		parser.parseFileDeclarations(d)
+	}
+	parser.addImplementations()
+	tt := []struct {
+		name            string
+		expectedExtends map[string]struct{}
+	}{
+		{
+			name:            "main.File",
+			expectedExtends: map[string]struct{}{"main.Reader": {}, "main.ReadCloser": {}},
+		},
+		{
+			name:            "main.Buffer",
+			expectedExtends: map[string]struct{}{"main.Reader": {}},
+		},
+		{
+			name:            "main.Other",
+			expectedExtends: map[string]struct{}{},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			st := parser.getStruct(tc.name)
+			for i := range parser.AllInterfaces {
+				if st.ImplementsInterface(parser.getStruct(i)) != arrayContains(st.Extends, i) {
+					t.Errorf("Expected extends of %s to match ImplementsInterface for %s", tc.name, i)
+				}
+			}
+			if !reflect.DeepEqual(st.Extends, tc.expectedExtends) {
+				t.Errorf("Expected %s to extend %v, got %v", tc.name, tc.expectedExtends, st.Extends)
+			}
+		})
 	}
-	parser.SetRenderingOptions(map[RenderingOption]interface{}{})
-	result := parser.Render()
-	expectedResult := `@startuml
-namespace parenthesizedtypedeclarations {
-    interface Bar  {
-        + Bar() 
+}
+
+func TestAddPromotedImplementations(t *testing.T) {
+	source := `package main
+
+type Reader interface {
+	Read(p []byte) (int, error)
+}
+
+type ReadCloser interface {
+	Read(p []byte) (int, error)
+	Close() error
+}
+
+type File struct {
+}
 
-    }
-    interface Foo  {
-        + Foo() 
+func (f *File) Read(b []byte) (int, error) {}
+
+func (f *File) Close() error {}
+
+type Buffer struct {
+}
+
+func (b *Buffer) Read(b []byte) (int, error) {}
+
+type Handle struct {
+	*File
+}
+
+type Wrapper struct {
+	Handle
+}
 
-    }
+type Ambiguous struct {
+	File
+	*Buffer
 }
 
+type Shadowed struct {
+	File
+}
 
-@enduml
+func (s *Shadowed) Read(b []int) (int, error) {}
 `
-	if result != expectedResult {
-		t.Errorf("TestConnectionLabelsRendering: expecting \n%s\n got \n%s\n", expectedResult, result)
+	f, err := goparser.ParseFile(token.NewFileSet(), "impl.go", source, 0)
+	if err != nil {
+		t.Fatalf("TestAddPromotedImplementations: expected no errors, got %s", err.Error())
+	}
+	parser := getEmptyParser("main")
+	for _, d := range f.Decls {
+		parser.parseFileDeclarations(d)
+	}
+	parser.addImplementations()
+	tt := []struct {
+		name            string
+		expectedExtends map[string]struct{}
+	}{
+		{
+			name:            "main.Handle",
+			expectedExtends: map[string]struct{}{"main.Reader": {}, "main.ReadCloser": {}},
+		},
+		{
+			name:            "main.Wrapper",
+			expectedExtends: map[string]struct{}{"main.Reader": {}, "main.ReadCloser": {}},
+		},
+		{
+			name:            "main.Ambiguous",
+			expectedExtends: map[string]struct{}{},
+		},
+		{
+			name:            "main.Shadowed",
+			expectedExtends: map[string]struct{}{},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			st := parser.getStruct(tc.name)
+			if !reflect.DeepEqual(st.Extends, tc.expectedExtends) {
+				t.Errorf("Expected %s to extend %v, got %v", tc.name, tc.expectedExtends, st.Extends)
+			}
+		})
+	}
+}
+
+func TestBuildTags(t *testing.T) {
+	tt := []struct {
+		name           string
+		options        *ClassDiagramOptions
+		expectedExists map[string]bool
+	}{
+		{
+			name:    "No build constraints",
+			options: &ClassDiagramOptions{},
+			expectedExists: map[string]bool{
+				"testingsupport.buildtags.Common":      true,
+				"testingsupport.buildtags.OnlyWindows": true,
+				"testingsupport.buildtags.OnlyLinux":   true,
+				"testingsupport.buildtags.OnlyCustom":  true,
+			},
+		},
+		{
+			name:    "Windows",
+			options: &ClassDiagramOptions{GOOS: "windows"},
+			expectedExists: map[string]bool{
+				"testingsupport.buildtags.Common":      true,
+				"testingsupport.buildtags.OnlyWindows": true,
+				"testingsupport.buildtags.OnlyLinux":   false,
+				"testingsupport.buildtags.OnlyCustom":  false,
+			},
+		},
+		{
+			name:    "Linux with custom tag",
+			options: &ClassDiagramOptions{GOOS: "linux", BuildTags: []string{"custom"}},
+			expectedExists: map[string]bool{
+				"testingsupport.buildtags.Common":      true,
+				"testingsupport.buildtags.OnlyWindows": false,
+				"testingsupport.buildtags.OnlyLinux":   true,
+				"testingsupport.buildtags.OnlyCustom":  true,
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			parser := getEmptyParser("main")
+			parser.AllImports = make(map[string]string)
+			parser.RenderingOptions.ModuleBase = "testingsupport"
+			if len(tc.options.BuildTags) > 0 || tc.options.GOOS != "" {
+				parser.buildContext = newBuildContext(tc.options)
+			}
+			err := parser.parseDirectory("../testingsupport/buildtags")
+			if err != nil {
+				t.Fatalf("Expected no errors, got %s", err.Error())
+			}
+			for name, exists := range tc.expectedExists {
+				if (parser.getStruct(name) != nil) != exists {
+					t.Errorf("Expected %s to exist: %t", name, exists)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	parser := getEmptyParser("main")
+	parser.AllImports = make(map[string]string)
+	parser.RenderingOptions.ModuleBase = "testingsupport"
+	err := parser.parseFile("../testingsupport/buildtags/windows.go")
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
 	}
+	if parser.getStruct("testingsupport.buildtags.OnlyWindows") == nil {
+		t.Errorf("Expected testingsupport.buildtags.OnlyWindows to exist")
+	}
+	for _, name := range []string{"testingsupport.buildtags.Common", "testingsupport.buildtags.OnlyLinux"} {
+		if parser.getStruct(name) != nil {
+			t.Errorf("Expected %s not to exist", name)
+		}
+	}
+	err = parser.parseFile("../testingsupport/buildtags/missing.go")
+	if err == nil {
+		t.Errorf("Expected an error when parsing a missing file")
+	}
+}
+
+func TestParseSource(t *testing.T) {
+	parser := getEmptyParser("main")
+	parser.AllImports = make(map[string]string)
+	err := parser.ParseSource("pasted.go", []byte(`package pasted
+
+type Speaker interface {
+	Speak() string
+}
+
+type Dog struct {
+	Name string
+}
+
+func (d *Dog) Speak() string {
+	return d.Name
+}
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	st := parser.getStruct(".pasted.Dog")
+	if st == nil {
+		t.Fatalf("Expected .pasted.Dog to exist")
+	}
+	if _, ok := st.Extends[".pasted.Speaker"]; !ok {
+		t.Errorf("Expected .pasted.Dog to implement .pasted.Speaker, got %v", st.Extends)
+	}
+	err = parser.ParseSource("broken.go", []byte("package broken\ntype"))
+	if err == nil {
+		t.Errorf("Expected an error when parsing invalid source")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	parse := func(name string, src string) *ClassParser {
+		parser := getEmptyParser("main")
+		parser.AllImports = make(map[string]string)
+		if err := parser.ParseSource(name, []byte(src)); err != nil {
+			t.Fatalf("Expected no errors, got %s", err.Error())
+		}
+		return parser
+	}
+	shapes := parse("shapes.go", `package shapes
+
+type Shape interface {
+	Area() float64
+}
+`)
+	geometry := parse("geometry.go", `package geometry
+
+type Square struct {
+	Side float64
+}
+
+func (s Square) Area() float64 {
+	return s.Side * s.Side
+}
+`)
+	if err := shapes.Merge(geometry); err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	if !reflect.DeepEqual(shapes.Packages(), []string{".geometry", ".shapes", "main"}) {
+		t.Errorf("Expected the packages of both diagrams, got %v", shapes.Packages())
+	}
+	st := shapes.getStruct(".geometry.Square")
+	if st == nil {
+		t.Fatalf("Expected .geometry.Square to be merged")
+	}
+	if _, ok := st.Extends[".shapes.Shape"]; !ok {
+		t.Errorf("Expected .geometry.Square to implement .shapes.Shape once merged, got %v", st.Extends)
+	}
+	if _, ok := shapes.AllStructs[".geometry.Square"]; !ok {
+		t.Errorf("Expected .geometry.Square to be in AllStructs")
+	}
+	again := parse("geometry.go", `package geometry
+
+type Square struct{}
+`)
+	if err := shapes.Merge(again); err == nil {
+		t.Errorf("Expected an error when both diagrams parsed .geometry.Square")
+	}
+}
+
+func TestNewClassDiagramWithFileSystem(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	files := map[string]string{
+		"/project/go.mod": "module example.com/project\n",
+		"/project/shapes/shape.go": `package shapes
+
+type Shape interface {
+	Area() float64
+}
+`,
+		"/project/shapes/square.go": `package shapes
+
+type Square struct {
+	Side float64
+}
+
+func (s Square) Area() float64 {
+	return s.Side * s.Side
+}
+`,
+	}
+	for name, content := range files {
+		if err := afero.WriteFile(fs, name, []byte(content), 0644); err != nil {
+			t.Fatalf("Expected no errors, got %s", err.Error())
+		}
+	}
+	parser, err := NewClassDiagramWithFileSystem(fs, []string{"/project/shapes"})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	st := parser.getStruct("example.com.project.shapes.Square")
+	if st == nil {
+		t.Fatalf("Expected Square to be parsed in the namespace of the go.mod of the file system, got %v", parser.Packages())
+	}
+	if _, ok := st.Extends["example.com.project.shapes.Shape"]; !ok {
+		t.Errorf("Expected Square to implement Shape, got %v", st.Extends)
+	}
+	if _, err := NewClassDiagramWithFileSystem(fs, []string{"/project/missing"}); err == nil {
+		t.Errorf("Expected an error for a directory that is not in the file system")
+	}
+}
 
+func TestModuleBaseWithFileSystem(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	if err := afero.WriteFile(fs, "/work/project/shapes/shape.go", []byte("package shapes\n\ntype Square struct{}\n"), 0644); err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	tt := []struct {
+		name       string
+		moduleBase string
+		expected   string
+	}{
+		{
+			name:       "Module base",
+			moduleBase: "project",
+			expected:   "project.shapes",
+		},
+		{
+			name:     "No module base",
+			expected: "work.project.shapes",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			parser, err := NewClassDiagramWithOptions(&ClassDiagramOptions{
+				FileSystem:  fs,
+				Directories: []string{"/work/project/shapes"},
+				ModuleBase:  tc.moduleBase,
+			})
+			if err != nil {
+				t.Fatalf("Expected no errors, got %s", err.Error())
+			}
+			if !reflect.DeepEqual(parser.Packages(), []string{tc.expected}) {
+				t.Errorf("Expected the package to be %s, got %v", tc.expected, parser.Packages())
+			}
+		})
+	}
+}
+
+func TestSkipGenerated(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	files := map[string]string{
+		"/project/api/api.go": "package api\n\ntype Server struct{}\n",
+		"/project/api/api.pb.go": `// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: api.proto
+
+package api
+
+type Request struct{}
+`,
+		"/project/api/mock.go": `// Package api holds the API.
+package api
+
+// Code generated by mockgen. DO NOT EDIT.
+
+type MockServer struct{}
+`,
+	}
+	for name, content := range files {
+		if err := afero.WriteFile(fs, name, []byte(content), 0644); err != nil {
+			t.Fatalf("Expected no errors, got %s", err.Error())
+		}
+	}
+	tt := []struct {
+		name          string
+		skipGenerated bool
+		expected      []string
+	}{
+		{
+			name:          "Skipped",
+			skipGenerated: true,
+			// The comment is only taken into account before the package clause
+			expected: []string{"MockServer", "Server"},
+		},
+		{
+			name:     "Parsed",
+			expected: []string{"MockServer", "Request", "Server"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			parser, err := NewClassDiagramWithOptions(&ClassDiagramOptions{
+				FileSystem:    fs,
+				Directories:   []string{"/project/api"},
+				ModuleBase:    "project",
+				SkipGenerated: tc.skipGenerated,
+			})
+			if err != nil {
+				t.Fatalf("Expected no errors, got %s", err.Error())
+			}
+			var result []string
+			for name := range parser.Structure["project.api"] {
+				result = append(result, name)
+			}
+			sort.Strings(result)
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("Expected the structures to be %v, got %v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestGetPackageBase(t *testing.T) {
+	tt := []struct {
+		name          string
+		directoryPath string
+		expected      string
+	}{
+		{
+			name:          "POSIX path",
+			directoryPath: "/home/user/testingsupport/subfolder/subfolder2",
+			expected:      "testingsupport.subfolder",
+		},
+		{
+			name:          "Windows path",
+			directoryPath: `C:\Users\user\testingsupport\subfolder\subfolder2`,
+			expected:      "testingsupport.subfolder",
+		},
+		{
+			name:          "Trailing separator",
+			directoryPath: `C:\Users\user\testingsupport\subfolder\subfolder2\`,
+			expected:      "testingsupport.subfolder",
+		},
+		{
+			name:          "Module root",
+			directoryPath: "/home/user/testingsupport",
+			expected:      "",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			parser := getEmptyParser("main")
+			parser.RenderingOptions.ModuleBase = "testingsupport"
+			result := parser.getPackageBase(tc.directoryPath)
+			if result != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestIncludeTests(t *testing.T) {
+	tt := []struct {
+		name           string
+		includeTests   bool
+		expectedExists map[string]bool
+	}{
+		{
+			name:         "Tests excluded",
+			includeTests: false,
+			expectedExists: map[string]bool{
+				"testingsupport.withtests.Service":       true,
+				"testingsupport.withtests.mockService":   false,
+				"testingsupport.withtests_test.External": false,
+			},
+		},
+		{
+			name:         "Tests included",
+			includeTests: true,
+			expectedExists: map[string]bool{
+				"testingsupport.withtests.Service":       true,
+				"testingsupport.withtests.mockService":   true,
+				"testingsupport.withtests_test.External": true,
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			parser := getEmptyParser("main")
+			parser.AllImports = make(map[string]string)
+			parser.RenderingOptions.ModuleBase = "testingsupport"
+			parser.includeTests = tc.includeTests
+			err := parser.parseDirectory("../testingsupport/withtests")
+			if err != nil {
+				t.Fatalf("Expected no errors, got %s", err.Error())
+			}
+			for name, exists := range tc.expectedExists {
+				if (parser.getStruct(name) != nil) != exists {
+					t.Errorf("Expected %s to exist: %t", name, exists)
+				}
+			}
+		})
+	}
+}
+
+func TestSkipDirectory(t *testing.T) {
+	ignored := map[string]struct{}{"root/ignored": {}, "*_mock": {}, "**/testdata": {}, "root/gen/**": {}}
+	tt := []struct {
+		name     string
+		options  *ClassDiagramOptions
+		path     string
+		expected bool
+	}{
+		{
+			name:     "Regular directory",
+			options:  &ClassDiagramOptions{},
+			path:     "root/pkg",
+			expected: false,
+		},
+		{
+			name:     "Ignored directory",
+			options:  &ClassDiagramOptions{IncludeVendor: true, IncludeHidden: true},
+			path:     "root/ignored",
+			expected: true,
+		},
+		{
+			name:     "Vendor directory",
+			options:  &ClassDiagramOptions{IncludeHidden: true},
+			path:     "root/vendor",
+			expected: true,
+		},
+		{
+			name:     "Vendor directory included",
+			options:  &ClassDiagramOptions{IncludeVendor: true},
+			path:     "root/vendor",
+			expected: false,
+		},
+		{
+			name:     "Hidden directory",
+			options:  &ClassDiagramOptions{IncludeVendor: true},
+			path:     "root/.hidden",
+			expected: true,
+		},
+		{
+			name:     "Hidden directory included",
+			options:  &ClassDiagramOptions{IncludeHidden: true},
+			path:     "root/.hidden",
+			expected: false,
+		},
+		{
+			name:     "Name matching a pattern",
+			options:  &ClassDiagramOptions{},
+			path:     "root/pkg/service_mock",
+			expected: true,
+		},
+		{
+			name:     "Path matching a pattern with a leading double star",
+			options:  &ClassDiagramOptions{},
+			path:     "root/pkg/testdata",
+			expected: true,
+		},
+		{
+			name:     "Path matching a pattern with a trailing double star",
+			options:  &ClassDiagramOptions{},
+			path:     "root/gen/proto/v1",
+			expected: true,
+		},
+		{
+			name:     "Path not matching any pattern",
+			options:  &ClassDiagramOptions{},
+			path:     "root/pkg/testdata_helpers",
+			expected: false,
+		},
+		{
+			name:     "Exact path of a child of an ignored directory",
+			options:  &ClassDiagramOptions{},
+			path:     "root/ignored/child",
+			expected: false,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			result := skipDirectory(tc.options, tc.path, filepath.Base(tc.path), ignored)
+			if result != tc.expected {
+				t.Errorf("Expected skipDirectory to be %t, got %t", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestDirectoryDepth(t *testing.T) {
+	tt := []struct {
+		name     string
+		root     string
+		path     string
+		expected int
+	}{
+		{
+			name:     "Root",
+			root:     "/module",
+			path:     "/module",
+			expected: 0,
+		},
+		{
+			name:     "Child",
+			root:     "/module",
+			path:     "/module/parser",
+			expected: 1,
+		},
+		{
+			name:     "Grandchild",
+			root:     "/module/",
+			path:     "/module/render/plantuml",
+			expected: 2,
+		},
+		{
+			name:     "Relative root",
+			root:     ".",
+			path:     "render/plantuml",
+			expected: 2,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			result := directoryDepth(filepath.FromSlash(tc.root), filepath.FromSlash(tc.path))
+			if result != tc.expected {
+				t.Errorf("Expected depth %d, got %d", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestPlannedDirectories(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	for _, file := range []string{
+		"/project/api/api.go",
+		"/project/api/v1/v1.go",
+		"/project/vendor/lib/lib.go",
+		"/project/.git/hooks/hook.go",
+		"/project/internal/mocks/mock.go",
+	} {
+		if err := afero.WriteFile(fs, file, []byte("package p\n"), 0644); err != nil {
+			t.Fatalf("Expected no errors, got %s", err.Error())
+		}
+	}
+	tt := []struct {
+		name     string
+		options  *ClassDiagramOptions
+		expected []string
+	}{
+		{
+			name:     "NotRecursive",
+			options:  &ClassDiagramOptions{FileSystem: fs, Directories: []string{"/project/api"}, MaxDepth: -1},
+			expected: []string{"/project/api"},
+		},
+		{
+			name: "Recursive",
+			options: &ClassDiagramOptions{
+				FileSystem:         fs,
+				Directories:        []string{"/project"},
+				IgnoredDirectories: []string{"mock*"},
+				Recursive:          true,
+				MaxDepth:           -1,
+			},
+			expected: []string{"/project", "/project/api", "/project/api/v1", "/project/internal"},
+		},
+		{
+			name: "MaxDepth",
+			options: &ClassDiagramOptions{
+				FileSystem:    fs,
+				Directories:   []string{"/project"},
+				Recursive:     true,
+				MaxDepth:      1,
+				IncludeVendor: true,
+			},
+			expected: []string{"/project", "/project/api", "/project/internal", "/project/vendor"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := PlannedDirectories(tc.options)
+			if err != nil {
+				t.Fatalf("Expected no errors, got %s", err.Error())
+			}
+			for i := range result {
+				result[i] = filepath.ToSlash(result[i])
+			}
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("Expected the directories to be %v, got %v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestDocComments(t *testing.T) {
+	source := `package main
+
+// Service handles the requests.
+// It is safe for concurrent use.
+type Service struct {
+}
+
+type (
+	// Reader reads.
+	Reader interface {
+		Read() error
+	}
+
+	Writer interface {
+		Write() error
+	}
+)
+
+// Count is a defined type
+type Count int
+`
+	f, err := goparser.ParseFile(token.NewFileSet(), "doc.go", source, goparser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser := getEmptyParser("main")
+	parser.AllImports = make(map[string]string)
+	for _, d := range f.Decls {
+		parser.parseFileDeclarations(d)
+	}
+	expected := map[string]string{
+		"Service":    "Service handles the requests.\nIt is safe for concurrent use.\n",
+		"Reader":     "Reader reads.\n",
+		"Writer":     "",
+		"main.Count": "Count is a defined type\n",
+	}
+	for name, doc := range expected {
+		st := parser.Structure["main"][name]
+		if st == nil {
+			t.Fatalf("Expected %s to exist", name)
+		}
+		if st.Doc != doc {
+			t.Errorf("Expected doc of %s to be %q, got %q", name, doc, st.Doc)
+		}
+	}
+}
+
+func TestImportedPackages(t *testing.T) {
+	sources := map[string]string{
+		"testingsupport/a/a.go": `package a
+
+import (
+	"fmt"
+
+	"testingsupport/b"
+	"testingsupport/cmd"
+)
+`,
+		"testingsupport/b/b.go": `package b
+
+import "testingsupport/a"
+`,
+		"testingsupport/cmd/main.go": `package main
+
+import "testingsupport/b"
+`,
+	}
+	parser := getEmptyParser("main")
+	parser.AllImports = make(map[string]string)
+	parser.RenderingOptions.ModuleBase = "testingsupport"
+	var fileNames []string
+	for fileName := range sources {
+		fileNames = append(fileNames, fileName)
+	}
+	sort.Strings(fileNames)
+	for _, fileName := range fileNames {
+		f, err := goparser.ParseFile(token.NewFileSet(), fileName, sources[fileName], goparser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		directoryPath := filepath.Dir(fileName)
+		parser.parsePackage(&ast.Package{Name: f.Name.Name, Files: map[string]*ast.File{fileName: f}}, parser.getPackageBase(directoryPath))
+		parser.addPackagePath(directoryPath, parser.CurrentPackageName)
+	}
+	expected := map[string][]string{
+		"testingsupport.a":    {"testingsupport.b", "testingsupport.main"},
+		"testingsupport.b":    {"testingsupport.a"},
+		"testingsupport.main": {"testingsupport.b"},
+	}
+	for pack, imported := range expected {
+		if result := parser.ImportedPackages(pack); !reflect.DeepEqual(result, imported) {
+			t.Errorf("Expected %s to import %v, got %v", pack, imported, result)
+		}
+	}
+}
+
+func TestPointerReceiver(t *testing.T) {
+	source := `package main
+
+type Service struct {
+}
+
+func (s *Service) Start() {
+}
+
+func (s Service) Name() string {
+	return ""
+}
+
+type Stack[T any] struct {
+}
+
+func (s *Stack[T]) Push(v T) {
+}
+`
+	f, err := goparser.ParseFile(token.NewFileSet(), "receiver.go", source, goparser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser := getEmptyParser("main")
+	parser.AllImports = make(map[string]string)
+	for _, d := range f.Decls {
+		parser.parseFileDeclarations(d)
+	}
+	expected := map[string]map[string]bool{
+		"Service": {"Start": true, "Name": false},
+		"Stack":   {"Push": true},
+	}
+	for name, methods := range expected {
+		st := parser.Structure["main"][name]
+		if st == nil {
+			t.Fatalf("Expected %s to exist", name)
+		}
+		for _, function := range st.Functions {
+			if function.PointerReceiver != methods[function.Name] {
+				t.Errorf("Expected %s.%s to have a pointer receiver: %t", name, function.Name, methods[function.Name])
+			}
+		}
+		if len(st.Functions) != len(methods) {
+			t.Errorf("Expected %s to have %d methods, got %d", name, len(methods), len(st.Functions))
+		}
+	}
+}
+
+func TestInterfaceEmbedding(t *testing.T) {
+	source := `package main
+
+type Reader interface {
+	Read() error
+}
+
+type Writer interface {
+	Write() error
+}
+
+type ReadWriter interface {
+	Reader
+	Writer
+}
+
+type ReadWriteCloser interface {
+	ReadWriter
+	Close() error
+}
+
+type File struct {
+	Reader
+}
+`
+	f, err := goparser.ParseFile(token.NewFileSet(), "embedding.go", source, goparser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser := getEmptyParser("main")
+	parser.AllImports = make(map[string]string)
+	for _, d := range f.Decls {
+		parser.parseFileDeclarations(d)
+	}
+	tt := []struct {
+		name                string
+		expectedExtends     map[string]struct{}
+		expectedComposition map[string]struct{}
+	}{
+		{
+			name:                "ReadWriter",
+			expectedExtends:     map[string]struct{}{"main.Reader": {}, "main.Writer": {}},
+			expectedComposition: map[string]struct{}{},
+		},
+		{
+			name:                "ReadWriteCloser",
+			expectedExtends:     map[string]struct{}{"main.ReadWriter": {}},
+			expectedComposition: map[string]struct{}{},
+		},
+		{
+			name:                "File",
+			expectedExtends:     map[string]struct{}{},
+			expectedComposition: map[string]struct{}{"main.Reader": {}},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			st := parser.Structure["main"][tc.name]
+			if st == nil {
+				t.Fatalf("Expected %s to exist", tc.name)
+			}
+			if !reflect.DeepEqual(st.Extends, tc.expectedExtends) {
+				t.Errorf("Expected extends to be %v, got %v", tc.expectedExtends, st.Extends)
+			}
+			if !reflect.DeepEqual(st.Composition, tc.expectedComposition) {
+				t.Errorf("Expected composition to be %v, got %v", tc.expectedComposition, st.Composition)
+			}
+		})
+	}
+}
+
+func TestLookupAndPackages(t *testing.T) {
+	parser := getEmptyParser("main")
+	parser.AllImports = make(map[string]string)
+	source := `package main
+
+type Foo struct {
+	Bar string
+}
+
+type Color int
+
+const Red Color = 0
+`
+	f, err := goparser.ParseFile(token.NewFileSet(), "lookup.go", source, 0)
+	if err != nil {
+		t.Fatalf("TestLookupAndPackages: expected no errors, got %s", err.Error())
+	}
+	for _, d := range f.Decls {
+		parser.parseFileDeclarations(d)
+	}
+	parser.Structure["other.pkg"] = map[string]*Struct{}
+	if packages := parser.Packages(); !reflect.DeepEqual(packages, []string{"main", "other.pkg"}) {
+		t.Errorf("TestLookupAndPackages: expected packages [main other.pkg], got %v", packages)
+	}
+	if st := parser.Lookup("main.Foo"); st == nil || !reflect.DeepEqual(st.FieldNames(), []string{"Bar"}) {
+		t.Errorf("TestLookupAndPackages: expected main.Foo to have the field Bar, got %v", st)
+	}
+	if st := parser.Lookup("main.Color"); st == nil || len(st.Constants) != 1 {
+		t.Errorf("TestLookupAndPackages: expected main.Color to have one constant, got %v", st)
+	}
+	for _, name := range []string{"main.Missing", "other.pkg.Foo", "missing.Foo", "Foo"} {
+		if st := parser.Lookup(name); st != nil {
+			t.Errorf("TestLookupAndPackages: expected %s not to be found, got %v", name, st)
+		}
+	}
+}
+
+func TestFuncTypeDeclarations(t *testing.T) {
+	parser := getEmptyParser("main")
+	parser.AllImports = map[string]string{"http": "net.http"}
+	source := `package main
+
+import "net/http"
+
+// HandlerFunc handles a request
+type HandlerFunc func(http.ResponseWriter, *http.Request)
+
+type Mapper[T any] func(value T) (result T, err error)
+
+func (f HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f(w, r)
+}
+`
+	f, err := goparser.ParseFile(token.NewFileSet(), "functypes.go", source, goparser.ParseComments)
+	if err != nil {
+		t.Fatalf("TestFuncTypeDeclarations: expected no errors, got %s", err.Error())
+	}
+	for _, d := range f.Decls {
+		parser.parseFileDeclarations(d)
+	}
+	if len(parser.AllAliases) != 0 || len(parser.AllRenamedStructs) != 0 {
+		t.Errorf("TestFuncTypeDeclarations: expected function types not to be aliases, got %v and %v", parser.AllAliases, parser.AllRenamedStructs)
+	}
+	tt := []struct {
+		name      string
+		signature string
+		methods   []string
+	}{
+		{
+			name:      "HandlerFunc",
+			signature: "func(net.http.ResponseWriter, *net.http.Request)",
+			methods:   []string{"ServeHTTP"},
+		},
+		{
+			name:      "Mapper",
+			signature: "func(value T) (result T, err error)",
+			methods:   []string{},
+		},
+	}
+	for _, tc := range tt {
+		st := parser.Structure["main"][tc.name]
+		if st == nil {
+			t.Fatalf("TestFuncTypeDeclarations: expected %s to be parsed", tc.name)
+		}
+		if st.Type != "func" || st.Signature == nil {
+			t.Fatalf("TestFuncTypeDeclarations: expected %s to be a func with a signature, got %s and %v", tc.name, st.Type, st.Signature)
+		}
+		if signature := st.Signature.String(); signature != tc.signature {
+			t.Errorf("TestFuncTypeDeclarations: expected the signature of %s to be %s, got %s", tc.name, tc.signature, signature)
+		}
+		if methods := st.MethodNames(); !reflect.DeepEqual(methods, tc.methods) {
+			t.Errorf("TestFuncTypeDeclarations: expected the methods of %s to be %v, got %v", tc.name, tc.methods, methods)
+		}
+	}
+	if doc := parser.Structure["main"]["HandlerFunc"].Doc; doc != "HandlerFunc handles a request\n" {
+		t.Errorf("TestFuncTypeDeclarations: expected the doc comment to be kept, got %q", doc)
+	}
+}
+
+func TestConstraintInterfaces(t *testing.T) {
+	parser := getEmptyParser("main")
+	parser.AllImports = make(map[string]string)
+	source := `package main
+
+type Number interface {
+	~int | ~int64 | float64
+}
+
+type Ordered interface {
+	Number | ~string
+	comparable
+}
+
+type Integer interface{ int }
+
+type Box[T interface{ ~int | ~string }] struct {
+	Value T
+}
+`
+	f, err := goparser.ParseFile(token.NewFileSet(), "constraints.go", source, 0)
+	if err != nil {
+		t.Fatalf("TestConstraintInterfaces: expected no errors, got %s", err.Error())
+	}
+	for _, d := range f.Decls {
+		parser.parseFileDeclarations(d)
+	}
+	expected := map[string][]string{
+		"Number":  {"~int | ~int64 | float64"},
+		"Ordered": {".Number | ~string"},
+		"Integer": {"int"},
+	}
+	for name, typeSet := range expected {
+		st := parser.Structure["main"][name]
+		if st == nil || st.Type != "interface" {
+			t.Fatalf("TestConstraintInterfaces: expected %s to be an interface, got %v", name, st)
+		}
+		if !reflect.DeepEqual(st.TypeSet, typeSet) {
+			t.Errorf("TestConstraintInterfaces: expected the type set of %s to be %v, got %v", name, typeSet, st.TypeSet)
+		}
+	}
+	if extends := parser.Structure["main"]["Ordered"].Extends; len(extends) != 1 {
+		t.Errorf("TestConstraintInterfaces: expected Ordered to embed comparable, got %v", extends)
+	}
+	typeParameters := parser.Structure["main"]["Box"].TypeParameters
+	if len(typeParameters) != 1 || typeParameters[0].Type != "interface{~int | ~string}" {
+		t.Errorf("TestConstraintInterfaces: expected the type parameter of Box to be constrained by interface{~int | ~string}, got %v", typeParameters)
+	}
+}
+
+func TestExpandAnonymousStructs(t *testing.T) {
+	source := `package main
+
+type Parent struct {
+	Meta struct {
+		ID   int
+		Tags []string
+		Info struct {
+			Author string
+		}
+	}
+	items []*struct{ Value int }
+	Name string
+}
+`
+	tt := []struct {
+		name              string
+		expand            bool
+		expectedFields    []*Field
+		expectedStructs   []string
+		expectedAggregate map[string]struct{}
+	}{
+		{
+			name:   "Inline",
+			expand: false,
+			expectedFields: []*Field{
+				{Name: "Meta", Type: "struct{int, []string, struct{string}}", FullType: "struct{int, []string, struct{string}}"},
+				{Name: "items", Type: "[]*struct{int}", FullType: "[]*struct{int}"},
+				{Name: "Name", Type: "string", FullType: "string"},
+			},
+			expectedStructs:   []string{"Parent"},
+			expectedAggregate: map[string]struct{}{},
+		},
+		{
+			name:   "Expanded",
+			expand: true,
+			expectedFields: []*Field{
+				{Name: "Meta", Type: ".Parent_Meta", FullType: "main.Parent_Meta"},
+				{Name: "items", Type: "[]*.Parent_items", FullType: "[]*main.Parent_items"},
+				{Name: "Name", Type: "string", FullType: "string"},
+			},
+			expectedStructs:   []string{"Parent", "Parent_Meta", "Parent_Meta_Info", "Parent_items"},
+			expectedAggregate: map[string]struct{}{"main.Parent_Meta": {}},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := goparser.ParseFile(token.NewFileSet(), "anonymous.go", source, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			parser := getEmptyParser("main")
+			parser.AllImports = make(map[string]string)
+			parser.expandAnonymousStructs = tc.expand
+			for _, d := range f.Decls {
+				parser.parseFileDeclarations(d)
+			}
+			structs := []string{}
+			for name, st := range parser.Structure["main"] {
+				if st.Type != "class" {
+					t.Errorf("Expected %s to be a class, got %s", name, st.Type)
+				}
+				structs = append(structs, name)
+			}
+			sort.Strings(structs)
+			if !reflect.DeepEqual(structs, tc.expectedStructs) {
+				t.Errorf("Expected structs %v, got %v", tc.expectedStructs, structs)
+			}
+			parent := parser.Structure["main"]["Parent"]
+			for _, field := range parent.Fields {
+				field.Pos = 0
+			}
+			if !reflect.DeepEqual(parent.Fields, tc.expectedFields) {
+				t.Errorf("Expected fields %v, got %v", tc.expectedFields, parent.Fields)
+			}
+			if !reflect.DeepEqual(parent.Aggregations, tc.expectedAggregate) {
+				t.Errorf("Expected aggregations %v, got %v", tc.expectedAggregate, parent.Aggregations)
+			}
+			if tc.expand {
+				if _, ok := parent.PrivateAggregations["main.Parent_items"]; !ok {
+					t.Errorf("Expected main.Parent_items to be privately aggregated, got %v", parent.PrivateAggregations)
+				}
+				if parent.Multiplicities["main.Parent_items"] != "0..*" {
+					t.Errorf("Expected main.Parent_items to have multiplicity 0..*, got %q", parent.Multiplicities["main.Parent_items"])
+				}
+				if _, ok := parser.AllStructs["main.Parent_Meta_Info"]; !ok {
+					t.Errorf("Expected main.Parent_Meta_Info to be a known struct, got %v", parser.AllStructs)
+				}
+				meta := parser.Structure["main"]["Parent_Meta"]
+				if !reflect.DeepEqual(meta.FieldNames(), []string{"ID", "Tags", "Info"}) {
+					t.Errorf("Expected Parent_Meta to have the fields [ID Tags Info], got %v", meta.FieldNames())
+				}
+			}
+		})
+	}
+}
+
+func TestPackageFunctions(t *testing.T) {
+	source := `package main
+
+func init() {}
+
+func New(name string) *Foo {
+	return nil
+}
+
+func (f *Foo) Method() {}
+
+func first[T any](values []T) (result T, ok bool) {
+	return
+}
+
+type Foo struct{}
+`
+	f, err := goparser.ParseFile(token.NewFileSet(), "functions.go", source, 0)
+	if err != nil {
+		t.Fatalf("TestPackageFunctions: expected no errors, got %s", err.Error())
+	}
+	parser := getEmptyParser("main")
+	parser.AllImports = make(map[string]string)
+	for _, d := range f.Decls {
+		parser.parseFileDeclarations(d)
+	}
+	functions := parser.PackageFunctions["main"]
+	if len(functions) != 2 {
+		t.Fatalf("TestPackageFunctions: expected 2 functions, got %v", functions)
+	}
+	if functions[0].String() != "New(name string) *main.Foo" {
+		t.Errorf("TestPackageFunctions: expected New(name string) *main.Foo, got %s", functions[0].String())
+	}
+	if functions[1].String() != "first(values []T) (result T, ok bool)" {
+		t.Errorf("TestPackageFunctions: expected first(values []T) (result T, ok bool), got %s", functions[1].String())
+	}
+	if names := parser.Structure["main"]["Foo"].MethodNames(); !reflect.DeepEqual(names, []string{"Method"}) {
+		t.Errorf("TestPackageFunctions: expected Foo to only have the method Method, got %v", names)
+	}
+}
+
+func TestStdInterfaces(t *testing.T) {
+	source := `package main
+
+type MyError struct{}
+
+func (e *MyError) Error() string {
+	return ""
+}
+
+func (e MyError) String() string {
+	return ""
+}
+
+type Buffer struct{}
+
+func (b *Buffer) Read(p []byte) (n int, err error) {
+	return 0, nil
+}
+
+func (b *Buffer) Write(data []byte) (int, error) {
+	return 0, nil
+}
+`
+	f, err := goparser.ParseFile(token.NewFileSet(), "std.go", source, 0)
+	if err != nil {
+		t.Fatalf("TestStdInterfaces: expected no errors, got %s", err.Error())
+	}
+	parser := getEmptyParser("main")
+	parser.AllImports = make(map[string]string)
+	parser.stdInterfaces, err = getStdInterfaces([]string{"error", "fmt.Stringer", "io.ReadWriter", "io.Closer", "sort.Interface"})
+	if err != nil {
+		t.Fatalf("TestStdInterfaces: expected no errors, got %s", err.Error())
+	}
+	for _, d := range f.Decls {
+		parser.parseFileDeclarations(d)
+	}
+	parser.addImplementations()
+	expected := map[string]map[string]struct{}{
+		"MyError": {"builtin.error": {}, "fmt.Stringer": {}},
+		"Buffer":  {"io.ReadWriter": {}},
+	}
+	for name, extends := range expected {
+		if st := parser.Structure["main"][name]; !reflect.DeepEqual(st.Extends, extends) {
+			t.Errorf("TestStdInterfaces: expected %s to implement %v, got %v", name, extends, st.Extends)
+		}
+	}
+	_, err = getStdInterfaces([]string{"io.Unknown"})
+	if err == nil {
+		t.Error("TestStdInterfaces: expected an error for an unknown interface, got nil")
+	}
+}
+
+func TestResolveCrossPackageCompositions(t *testing.T) {
+	parser := getEmptyParser("github.com.example.base")
+	parser.AllImports = make(map[string]string)
+	sources := map[string]string{
+		"github.com.example.base": `package base
+
+type Base struct {
+	ID int
+}
+`,
+		"main": `package main
+
+import (
+	"bytes"
+
+	"github.com/example/base"
+	other "github.com/example/base"
+)
+
+type Local struct{}
+
+type Document struct {
+	base.Base
+	bytes.Buffer
+	Local
+}
+
+type Copy struct {
+	*other.Base
+}
+`,
+	}
+	for _, pack := range []string{"github.com.example.base", "main"} {
+		parser.CurrentPackageName = pack
+		parser.Structure[pack] = map[string]*Struct{}
+		f, err := goparser.ParseFile(token.NewFileSet(), pack+".go", sources[pack], 0)
+		if err != nil {
+			t.Fatalf("TestResolveCrossPackageCompositions: expected no errors, got %s", err.Error())
+		}
+		for _, d := range f.Imports {
+			parser.parseImports(d)
+		}
+		for _, d := range f.Decls {
+			parser.parseFileDeclarations(d)
+		}
+	}
+	tt := []struct {
+		structure string
+		expected  map[string]bool
+	}{
+		{
+			structure: "Document",
+			expected: map[string]bool{
+				"github.com.example.base.Base": true,
+				"bytes.Buffer":                 false,
+				"main.Local":                   true,
+			},
+		},
+		{
+			structure: "Copy",
+			expected: map[string]bool{
+				"github.com.example.base.Base": true,
+			},
+		},
+	}
+	for _, tc := range tt {
+		st := parser.Structure["main"][tc.structure]
+		result := map[string]bool{}
+		for c := range st.Composition {
+			name, ok := parser.ResolveType(c, st)
+			result[name] = ok
+		}
+		if !reflect.DeepEqual(result, tc.expected) {
+			t.Errorf("TestResolveCrossPackageCompositions: expected the compositions of %s to resolve to %v, got %v", tc.structure, tc.expected, result)
+		}
+	}
+}
+
+func TestMultipleEmbeddings(t *testing.T) {
+	parser := getEmptyParser("main")
+	parser.AllImports = make(map[string]string)
+	err := parser.ParseSource("embeddings.go", []byte(`package embeddings
+
+import (
+	"io"
+	"sync"
+)
+
+type Base struct {
+	ID int
+}
+
+type Resource struct {
+	sync.Mutex
+	io.Reader
+	*Base
+	Name string
+}
+`))
+	if err != nil {
+		t.Fatalf("TestMultipleEmbeddings: expected no errors, got %s", err.Error())
+	}
+	st := parser.getStruct(".embeddings.Resource")
+	if st == nil {
+		t.Fatalf("TestMultipleEmbeddings: expected .embeddings.Resource to exist")
+	}
+	// Every embedded type is composed on its own, with the package it belongs to
+	expected := map[string]struct{}{
+		"sync.Mutex":       {},
+		"io.Reader":        {},
+		".embeddings.Base": {},
+	}
+	if !reflect.DeepEqual(st.Composition, expected) {
+		t.Errorf("TestMultipleEmbeddings: expected the compositions to be %v, got %v", expected, st.Composition)
+	}
+	embedded := 0
+	for _, f := range st.Fields {
+		if f.Embedded {
+			embedded++
+		}
+	}
+	if embedded != 3 || len(st.Fields) != 4 {
+		t.Errorf("TestMultipleEmbeddings: expected 3 embedded fields out of 4, got %d out of %d", embedded, len(st.Fields))
+	}
+	if len(st.Aggregations) != 0 {
+		t.Errorf("TestMultipleEmbeddings: expected the embedded types not to be aggregated, got %v", st.Aggregations)
+	}
+}
+
+func TestContinueOnError(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	files := map[string]string{
+		"/project/good/good.go":   "package good\n\ntype Good struct{}\n",
+		"/project/broken/ok.go":   "package broken\n\ntype Kept struct{}\n",
+		"/project/broken/typo.go": "package broken\n\ntype Typo struct {\n",
+	}
+	for name, content := range files {
+		if err := afero.WriteFile(fs, name, []byte(content), 0644); err != nil {
+			t.Fatalf("Expected no errors, got %s", err.Error())
+		}
+	}
+	options := &ClassDiagramOptions{
+		FileSystem:  fs,
+		Directories: []string{"/project/good", "/project/broken", "/project/missing"},
+	}
+	if _, err := NewClassDiagramWithOptions(options); err == nil {
+		t.Fatalf("Expected an error without ContinueOnError")
+	}
+	options.ContinueOnError = true
+	parser, err := NewClassDiagramWithOptions(options)
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	for _, name := range []string{"project.good.Good", "project.broken.Kept"} {
+		if parser.getStruct(name) == nil {
+			t.Errorf("Expected %s to be parsed, got %v", name, parser.Packages())
+		}
+	}
+	if parser.getStruct("project.broken.Typo") != nil {
+		t.Errorf("Expected Typo not to be parsed")
+	}
+	var paths []string
+	for _, e := range parser.Errors() {
+		parseError, ok := e.(*ParseError)
+		if !ok {
+			t.Fatalf("Expected a ParseError, got %T", e)
+		}
+		paths = append(paths, parseError.Path)
+	}
+	expected := []string{"/project/broken/typo.go", "/project/missing"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Expected errors for %v, got %v", expected, paths)
+	}
+}
+
+func TestConstantValues(t *testing.T) {
+	parser, err := NewClassDiagramWithOptions(&ClassDiagramOptions{})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = parser.ParseSource("values.go", []byte(`package values
+
+type Size int
+
+const (
+	_       = iota
+	KB Size = 1 << (10 * iota)
+	MB
+	Half = Size(MB / 2)
+	Neg  = -KB
+)
+
+type Name string
+
+const (
+	First Name = "first"
+	Other      = First
+)
+
+type Ratio float64
+
+const Third Ratio = 1.0 / 3
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	tt := []struct {
+		structure string
+		expected  []string
+	}{
+		// Half is converted from another constant, which is not evaluated
+		{structure: ".values.Size", expected: []string{"1024", "1048576", ""}},
+		{structure: ".values.Name", expected: []string{`"first"`}},
+		{structure: ".values.Ratio", expected: []string{""}},
+	}
+	for _, tc := range tt {
+		st := parser.Lookup(tc.structure)
+		if st == nil {
+			t.Fatalf("Expected %s to exist", tc.structure)
+		}
+		var values []string
+		for _, c := range st.Constants {
+			values = append(values, c.Value)
+		}
+		if !reflect.DeepEqual(values, tc.expected) {
+			t.Errorf("Expected the values of %s to be %q, got %q", tc.structure, tc.expected, values)
+		}
+	}
+}
+
+func TestVariadicFuncTypes(t *testing.T) {
+	parser, err := NewClassDiagramWithOptions(&ClassDiagramOptions{})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = parser.ParseSource("variadic.go", []byte(`package variadic
+
+type Logger struct {
+	Log  func(...any)
+	Wrap func(func(...string), ...func(...int)) error
+}
+
+func (l *Logger) Each(fns ...func(...any)) {}
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	st := parser.Lookup(".variadic.Logger")
+	if st == nil {
+		t.Fatalf("Expected .variadic.Logger to exist")
+	}
+	var types []string
+	for _, f := range st.Fields {
+		types = append(types, f.Type)
+	}
+	expected := []string{"func(...any) ", "func(func(...string) , ...func(...int) ) error"}
+	if !reflect.DeepEqual(types, expected) {
+		t.Errorf("Expected the field types to be %q, got %q", expected, types)
+	}
+	if len(st.Functions) != 1 || len(st.Functions[0].Parameters) != 1 {
+		t.Fatalf("Expected Each to have a single parameter, got %v", st.Functions)
+	}
+	if parameter := st.Functions[0].Parameters[0].Type; parameter != "...func(...any) " {
+		t.Errorf("Expected the parameter of Each to be %q, got %q", "...func(...any) ", parameter)
+	}
+}
+
+func TestConstraintInterfacesOfTypeParameters(t *testing.T) {
+	parser, err := NewClassDiagramWithOptions(&ClassDiagramOptions{})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = parser.ParseSource("sets.go", []byte(`package sets
+
+import "fmt"
+
+type Key interface {
+	comparable
+	fmt.Stringer
+}
+
+type Ordered[T any] interface {
+	Less(T) bool
+}
+
+type Set[K Key, V Key, O Ordered[O], S fmt.Stringer, N interface{ ~int }] struct{}
+
+func Max[T Ordered[T]](values ...T) T {
+	return values[0]
+}
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	st := parser.Lookup(".sets.Set")
+	if st == nil {
+		t.Fatalf("Expected .sets.Set to exist")
+	}
+	expected := []string{".sets.Key", ".sets.Ordered"}
+	if result := parser.ConstraintInterfaces(st.TypeParameters); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected the constraints of Set to be %v, got %v", expected, result)
+	}
+	functions := parser.PackageFunctions[".sets"]
+	if len(functions) != 1 {
+		t.Fatalf("Expected a single package function, got %d", len(functions))
+	}
+	expected = []string{".sets.Ordered"}
+	if result := parser.ConstraintInterfaces(functions[0].TypeParameters); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected the constraints of Max to be %v, got %v", expected, result)
+	}
+}
+
+func TestInterfaceCollectionAggregations(t *testing.T) {
+	tt := []struct {
+		name                 string
+		field                string
+		expectedMultiplicity string
+	}{
+		{name: "Slice", field: "Handlers []Handler", expectedMultiplicity: "0..*"},
+		{name: "NestedSlice", field: "Handlers [][]Handler", expectedMultiplicity: "0..*"},
+		{name: "Array", field: "Handlers [4]Handler", expectedMultiplicity: "4"},
+		{name: "MapValue", field: "Handlers map[string]Handler", expectedMultiplicity: "0..*"},
+		{name: "MapKey", field: "Handlers map[Handler]int", expectedMultiplicity: "0..*"},
+		{name: "SliceOfPointers", field: "Handlers []*Handler", expectedMultiplicity: "0..*"},
+		{name: "Single", field: "Handlers Handler", expectedMultiplicity: ""},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			parser, err := NewClassDiagramWithOptions(&ClassDiagramOptions{})
+			if err != nil {
+				t.Fatalf("Expected no errors, got %s", err.Error())
+			}
+			err = parser.ParseSource("web.go", []byte(`package web
+
+type Handler interface {
+	Serve()
+}
+
+type Router struct {
+	`+tc.field+`
+}
+`))
+			if err != nil {
+				t.Fatalf("Expected no errors, got %s", err.Error())
+			}
+			st := parser.Lookup(".web.Router")
+			if st == nil {
+				t.Fatalf("Expected .web.Router to exist")
+			}
+			// The interfaces held by the collections are aggregated the same way the structs are
+			expected := map[string]struct{}{".web.Handler": {}}
+			if !reflect.DeepEqual(st.Aggregations, expected) {
+				t.Errorf("Expected the aggregations to be %v, got %v", expected, st.Aggregations)
+			}
+			if multiplicity := st.Multiplicities[".web.Handler"]; multiplicity != tc.expectedMultiplicity {
+				t.Errorf("Expected the multiplicity to be %q, got %q", tc.expectedMultiplicity, multiplicity)
+			}
+		})
+	}
+}
+
+func TestParenthesizedAndGenericFieldTypes(t *testing.T) {
+	parser, err := NewClassDiagramWithOptions(&ClassDiagramOptions{})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = parser.ParseSource("tree.go", []byte(`package tree
+
+type Pair[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+type Node struct {
+	Parent   (*Node)
+	Children ([]*Node)
+	Labels   Pair[string, *Node]
+}
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	st := parser.Lookup(".tree.Node")
+	if st == nil {
+		t.Fatalf("Expected .tree.Node to exist")
+	}
+	var types []string
+	for _, f := range st.Fields {
+		types = append(types, f.Type)
+	}
+	expected := []string{"*.Node", "[]*.Node", ".Pair[string, *.Node]"}
+	if !reflect.DeepEqual(types, expected) {
+		t.Errorf("Expected the field types to be %q, got %q", expected, types)
+	}
+	expectedAggregations := map[string]struct{}{".tree.Node": {}, ".tree.Pair": {}}
+	if !reflect.DeepEqual(st.Aggregations, expectedAggregations) {
+		t.Errorf("Expected the aggregations to be %v, got %v", expectedAggregations, st.Aggregations)
+	}
+	// The multiplicity of the slice is found through the parentheses
+	if multiplicity := st.Multiplicities[".tree.Node"]; multiplicity != "0..*" {
+		t.Errorf("Expected the multiplicity of .tree.Node to be %q, got %q", "0..*", multiplicity)
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	files := map[string]string{
+		"/project/go.mod":        "module example.com/project\n",
+		"/project/root.go":       "package project\n\ntype Root struct{}\n",
+		"/project/a/a.go":        "package a\n\ntype A struct{}\n",
+		"/project/a/b/b.go":      "package b\n\ntype B struct{}\n",
+		"/project/a/b/c/deep.go": "package c\n\ntype C struct{}\n",
+	}
+	for name, content := range files {
+		if err := afero.WriteFile(fs, name, []byte(content), 0644); err != nil {
+			t.Fatalf("Expected no errors, got %s", err.Error())
+		}
+	}
+	tt := []struct {
+		name     string
+		maxDepth int
+		expected []string
+	}{
+		{
+			name:     "Only the given directories",
+			maxDepth: 0,
+			expected: []string{"example.com.project"},
+		},
+		{
+			name:     "One level",
+			maxDepth: 1,
+			expected: []string{"example.com.project", "example.com.project.a"},
+		},
+		{
+			name:     "Not limited",
+			maxDepth: -1,
+			expected: []string{"example.com.project", "example.com.project.a", "example.com.project.a.b", "example.com.project.a.b.c"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			parser, err := NewClassDiagramWithOptions(&ClassDiagramOptions{
+				FileSystem:  fs,
+				Directories: []string{"/project"},
+				Recursive:   true,
+				MaxDepth:    tc.maxDepth,
+			})
+			if err != nil {
+				t.Fatalf("Expected no errors, got %s", err.Error())
+			}
+			if packages := parser.Packages(); !reflect.DeepEqual(packages, tc.expected) {
+				t.Errorf("Expected packages %v, got %v", tc.expected, packages)
+			}
+		})
+	}
 }
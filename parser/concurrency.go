@@ -0,0 +1,134 @@
+package parser
+
+import (
+	"runtime"
+	"sync"
+)
+
+// parseDirectories parses the given directories with up to concurrency workers, or GOMAXPROCS workers when it is 0 or
+// less. Every directory is parsed by a parser of its own and the results are merged in the order of the directories,
+// so the diagram does not depend on the order in which the workers finish. It returns the error of the first
+// directory that failed, if any
+func (p *ClassParser) parseDirectories(directoryPaths []string, concurrency int) error {
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	if concurrency > len(directoryPaths) {
+		concurrency = len(directoryPaths)
+	}
+	results := make([]*ClassParser, len(directoryPaths))
+	errs := make([]error, len(directoryPaths))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				worker := p.newWorker()
+				errs[i] = worker.parseDirectory(directoryPaths[i])
+				results[i] = worker
+			}
+		}()
+	}
+	for i := range directoryPaths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	for i, result := range results {
+		if errs[i] != nil {
+			return errs[i]
+		}
+		p.merge(result)
+	}
+	return nil
+}
+
+// newWorker returns an empty parser with the same options as p. The cache of modules is shared, guarded by the mutex
+// of p
+func (p *ClassParser) newWorker() *ClassParser {
+	return &ClassParser{
+		RenderingOptions:       p.RenderingOptions,
+		Structure:              make(map[string]map[string]*Struct),
+		AllInterfaces:          make(map[string]struct{}),
+		AllStructs:             make(map[string]struct{}),
+		AllImports:             make(map[string]string),
+		AllAliases:             make(map[string]*Alias),
+		AllRenamedStructs:      make(map[string]map[string]string),
+		buildContext:           p.buildContext,
+		includeTests:           p.includeTests,
+		expandAnonymousStructs: p.expandAnonymousStructs,
+		modules:                p.modules,
+		modulesMutex:           p.modulesMutex,
+		fs:                     p.fs,
+		skipGenerated:          p.skipGenerated,
+		continueOnError:        p.continueOnError,
+	}
+}
+
+// merge adds everything parsed by the given worker to p. Types already known to p are kept, while imports, aliases
+// and package paths of the worker replace the ones with the same name, as if the worker had been parsed by p itself
+func (p *ClassParser) merge(worker *ClassParser) {
+	for pack, structures := range worker.Structure {
+		if _, ok := p.Structure[pack]; !ok {
+			p.Structure[pack] = make(map[string]*Struct)
+		}
+		for name, structure := range structures {
+			if _, ok := p.Structure[pack][name]; !ok {
+				p.Structure[pack][name] = structure
+			}
+		}
+	}
+	for name := range worker.AllInterfaces {
+		p.AllInterfaces[name] = struct{}{}
+	}
+	for name := range worker.AllStructs {
+		p.AllStructs[name] = struct{}{}
+	}
+	for name, importPath := range worker.AllImports {
+		p.AllImports[name] = importPath
+	}
+	for name, alias := range worker.AllAliases {
+		p.AllAliases[name] = alias
+	}
+	for pack, renamed := range worker.AllRenamedStructs {
+		if _, ok := p.AllRenamedStructs[pack]; !ok {
+			p.AllRenamedStructs[pack] = map[string]string{}
+		}
+		for renamedClass, name := range renamed {
+			p.AllRenamedStructs[pack][renamedClass] = name
+		}
+	}
+	for pack, imports := range worker.PackageImports {
+		if p.PackageImports == nil {
+			p.PackageImports = make(map[string]map[string]struct{})
+		}
+		if _, ok := p.PackageImports[pack]; !ok {
+			p.PackageImports[pack] = make(map[string]struct{})
+		}
+		for importPath := range imports {
+			p.PackageImports[pack][importPath] = struct{}{}
+		}
+	}
+	for pack, functions := range worker.PackageFunctions {
+		if p.PackageFunctions == nil {
+			p.PackageFunctions = make(map[string][]*Function)
+		}
+		p.PackageFunctions[pack] = append(p.PackageFunctions[pack], functions...)
+	}
+	for pack, variables := range worker.PackageVars {
+		if p.PackageVars == nil {
+			p.PackageVars = make(map[string][]*Variable)
+		}
+		p.PackageVars[pack] = append(p.PackageVars[pack], variables...)
+	}
+	for importPath, namespace := range worker.packagePaths {
+		if p.packagePaths == nil {
+			p.packagePaths = make(map[string]string)
+		}
+		p.packagePaths[importPath] = namespace
+	}
+	p.errors = append(p.errors, worker.errors...)
+	p.CurrentPackageName = worker.CurrentPackageName
+}
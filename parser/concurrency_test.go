@@ -0,0 +1,99 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestParseDirectoriesIsDeterministic(t *testing.T) {
+	directoryPaths := []string{
+		"../testingsupport",
+		"../testingsupport/subfolder",
+		"../testingsupport/subfolder2",
+		"../testingsupport/subfolder3",
+		"../testingsupport/connectionlabels",
+		"../testingsupport/renderingoptions",
+		"../testingsupport/withtests",
+	}
+	var expected *ClassParser
+	for _, concurrency := range []int{1, 0, 3, len(directoryPaths) + 1} {
+		parser := getEmptyParser("main")
+		parser.AllImports = make(map[string]string)
+		parser.RenderingOptions.ModuleBase = "testingsupport"
+		err := parser.parseDirectories(directoryPaths, concurrency)
+		if err != nil {
+			t.Fatalf("Expected no errors, got %s", err.Error())
+		}
+		parser.addImplementations()
+		if expected == nil {
+			expected = parser
+			continue
+		}
+		if !reflect.DeepEqual(parser.Structure, expected.Structure) {
+			t.Errorf("Expected the same structure with concurrency %d", concurrency)
+		}
+		if !reflect.DeepEqual(parser.AllImports, expected.AllImports) {
+			t.Errorf("Expected the same imports with concurrency %d, got %v instead of %v", concurrency, parser.AllImports, expected.AllImports)
+		}
+		if !reflect.DeepEqual(parser.AllAliases, expected.AllAliases) {
+			t.Errorf("Expected the same aliases with concurrency %d", concurrency)
+		}
+	}
+}
+
+func TestParseDirectoriesError(t *testing.T) {
+	parser := getEmptyParser("main")
+	parser.AllImports = make(map[string]string)
+	parser.RenderingOptions.ModuleBase = "testingsupport"
+	err := parser.parseDirectories([]string{"../testingsupport/subfolder", "../testingsupport/doesnotexist"}, 2)
+	if err == nil {
+		t.Fatal("Expected an error for a missing directory")
+	}
+}
+
+func TestNewClassDiagramWithOptionsConcurrency(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	files := map[string]string{
+		"/shop/go.mod":             "module example.com/shop\n",
+		"/shop/users/user.go":      "package users\n\ntype User struct {\n\tName string\n}\n",
+		"/shop/orders/order.go":    "package orders\n\nimport \"example.com/shop/users\"\n\ntype Order struct {\n\tBuyer *users.User\n}\n",
+		"/shop/items/item.go":      "package items\n\ntype Item struct {\n\tPrice int\n}\n\ntype Price = int\n",
+		"/shop/carts/cart.go":      "package carts\n\nimport \"example.com/shop/items\"\n\ntype Cart struct {\n\titems.Item\n}\n",
+		"/shop/payments/method.go": "package payments\n\ntype Method interface {\n\tPay(amount int) error\n}\n",
+	}
+	for name, content := range files {
+		if err := afero.WriteFile(fs, name, []byte(content), 0644); err != nil {
+			t.Fatalf("Expected no errors, got %s", err.Error())
+		}
+	}
+	directories := []string{"/shop/users", "/shop/orders", "/shop/items", "/shop/carts", "/shop/payments"}
+	var expected *ClassParser
+	for _, concurrency := range []int{1, len(directories)} {
+		parser, err := NewClassDiagramWithOptions(&ClassDiagramOptions{
+			FileSystem:  fs,
+			Directories: directories,
+			Concurrency: concurrency,
+		})
+		if err != nil {
+			t.Fatalf("Expected no errors, got %s", err.Error())
+		}
+		if len(parser.Structure) != len(directories) {
+			t.Errorf("Expected %d packages with concurrency %d, got %v", len(directories), concurrency, parser.Packages())
+		}
+		if expected == nil {
+			expected = parser
+			continue
+		}
+		if !reflect.DeepEqual(parser.Structure, expected.Structure) {
+			t.Errorf("Expected the same structure with concurrency %d as with concurrency 1", concurrency)
+		}
+		if !reflect.DeepEqual(parser.AllImports, expected.AllImports) {
+			t.Errorf("Expected the same imports with concurrency %d, got %v instead of %v", concurrency, parser.AllImports, expected.AllImports)
+		}
+		if !reflect.DeepEqual(parser.AllAliases, expected.AllAliases) {
+			t.Errorf("Expected the same aliases with concurrency %d", concurrency)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"go/ast"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ignoreDirective leaves the type it documents out of the diagram, whatever the other options are
+	ignoreDirective = "//plantuml:ignore"
+	// noteDirective attaches the text that follows it, quoted or not, to the type it documents as a note
+	noteDirective = "//plantuml:note"
+)
+
+// typeDirectives holds the plantuml directives found in the doc comment of a type declaration
+type typeDirectives struct {
+	ignore bool
+	note   string
+}
+
+// getTypeDirectives returns the plantuml directives of the given doc comments. The directives of the first ones, such
+// as the doc comment of a parenthesized type declaration, apply to the following ones as well
+func getTypeDirectives(docs ...*ast.CommentGroup) typeDirectives {
+	var result typeDirectives
+	for _, doc := range docs {
+		if doc == nil {
+			continue
+		}
+		for _, comment := range doc.List {
+			text := strings.TrimSpace(comment.Text)
+			switch {
+			case text == ignoreDirective:
+				result.ignore = true
+			case strings.HasPrefix(text, noteDirective+" "):
+				note := strings.TrimSpace(strings.TrimPrefix(text, noteDirective))
+				if unquoted, err := strconv.Unquote(note); err == nil {
+					note = unquoted
+				}
+				result.note = note
+			}
+		}
+	}
+	return result
+}
+
+// ignoreType records that the type with the given fully qualified name is left out of the diagram
+func (p *ClassParser) ignoreType(fullName string) {
+	if p.ignoredTypes == nil {
+		p.ignoredTypes = make(map[string]struct{})
+	}
+	p.ignoredTypes[fullName] = struct{}{}
+}
+
+// isIgnored returns true if the type with the given fully qualified name was marked with the ignore directive
+func (p *ClassParser) isIgnored(fullName string) bool {
+	_, ok := p.ignoredTypes[fullName]
+	return ok
+}
+
+// removeIgnoredTypes removes the types of the given package that were marked with the ignore directive, which could
+// have been created by the methods or the constants declared before them
+func (p *ClassParser) removeIgnoredTypes(pack string) {
+	for name := range p.Structure[pack] {
+		fullName := qualifiedName(pack, name)
+		if !p.isIgnored(fullName) {
+			continue
+		}
+		delete(p.Structure[pack], name)
+		delete(p.AllStructs, fullName)
+		delete(p.AllInterfaces, fullName)
+		delete(p.AllAliases, fullName)
+	}
+}
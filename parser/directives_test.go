@@ -0,0 +1,82 @@
+package parser
+
+import (
+	"testing"
+)
+
+func TestTypeDirectives(t *testing.T) {
+	parser, err := NewClassDiagramWithOptions(&ClassDiagramOptions{})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = parser.ParseSource("directives.go", []byte(`package directives
+
+func (c *cache) Get(key string) string { return "" }
+
+//plantuml:ignore
+type cache struct{}
+
+const (
+	Idle State = iota
+	Running
+)
+
+//plantuml:ignore
+type State int
+
+// Registry holds the services
+//plantuml:note "Safe for concurrent use"
+type Registry struct {
+	cache *cache
+}
+
+//plantuml:ignore
+type (
+	first  struct{}
+	second interface{}
+)
+
+type (
+	// Kept is not ignored
+	Kept struct{}
+	//plantuml:note unquoted text
+	Noted struct{}
+)
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	for _, name := range []string{".directives.cache", ".directives.State", ".directives.first", ".directives.second"} {
+		if parser.Lookup(name) != nil {
+			t.Errorf("Expected %s to be ignored", name)
+		}
+		if _, ok := parser.AllStructs[name]; ok {
+			t.Errorf("Expected %s not to be in AllStructs", name)
+		}
+		if _, ok := parser.AllInterfaces[name]; ok {
+			t.Errorf("Expected %s not to be in AllInterfaces", name)
+		}
+	}
+	tt := []struct {
+		name string
+		note string
+		doc  string
+	}{
+		{name: ".directives.Registry", note: "Safe for concurrent use", doc: "Registry holds the services\n"},
+		{name: ".directives.Kept", note: "", doc: "Kept is not ignored\n"},
+		{name: ".directives.Noted", note: "unquoted text", doc: ""},
+	}
+	for _, tc := range tt {
+		st := parser.Lookup(tc.name)
+		if st == nil {
+			t.Errorf("Expected %s to be parsed", tc.name)
+			continue
+		}
+		if st.Note != tc.note {
+			t.Errorf("Expected the note of %s to be %q, got %q", tc.name, tc.note, st.Note)
+		}
+		if st.Doc != tc.doc {
+			t.Errorf("Expected the doc comment of %s to be %q, got %q", tc.name, tc.doc, st.Doc)
+		}
+	}
+}
@@ -2,9 +2,11 @@ package parser
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 
 	"go/ast"
+	"go/token"
 )
 
 const packageConstant = "{packageName}"
@@ -14,6 +16,35 @@ type Field struct {
 	Name     string
 	Type     string
 	FullType string
+	Pos      token.Pos
+	Embedded bool
+	// Comment is the trailing comment of a struct field, or its doc comment when it has none, in a single line
+	Comment string
+	// DefinedIn is the path of the file a constant was declared in. It is only set for the constants of a Struct, whose
+	// type can be declared in another file, and for the package level variables
+	DefinedIn string
+	// Value is the value of a constant, such as 5 or "a", when it is a literal or it is derived from iota. It is empty
+	// otherwise
+	Value string
+}
+
+//String returns the field as it is declared, which is only its type for embedded fields
+func (f *Field) String() string {
+	if f.Embedded || f.Name == "" {
+		return f.Type
+	}
+	return fmt.Sprintf("%s %s", f.Name, f.Type)
+}
+
+//IsExported returns true when the field is exported following the Go rules, that is when its name starts with an
+//upper case letter. Names starting with an underscore are not exported. Embedded fields are named after their type,
+//without its package, pointer and type arguments
+func (f *Field) IsExported() bool {
+	name := f.Name
+	if f.Embedded && name == "" {
+		name = getEmbeddedFieldName(f.Type)
+	}
+	return token.IsExported(name)
 }
 
 //Returns a string representation of the given expression if it was recognized.
@@ -25,7 +56,7 @@ func getFieldType(exp ast.Expr, aliases map[string]string, packageName string) (
 	case *ast.ArrayType:
 		return getArrayType(v, aliases, packageName)
 	case *ast.SelectorExpr:
-		return getSelectorExp(v, aliases)
+		return getSelectorExp(v, aliases, packageName)
 	case *ast.MapType:
 		return getMapType(v, aliases, packageName)
 	case *ast.StarExpr:
@@ -40,10 +71,107 @@ func getFieldType(exp ast.Expr, aliases map[string]string, packageName string) (
 		return getFuncType(v, aliases, packageName)
 	case *ast.Ellipsis:
 		return getEllipsis(v, aliases, packageName)
+	case *ast.IndexExpr:
+		return getIndexExpr(v, aliases, packageName)
+	case *ast.IndexListExpr:
+		return getIndexListExpr(v, aliases, packageName)
+	case *ast.BinaryExpr:
+		return getBinaryExpr(v, aliases, packageName)
+	case *ast.UnaryExpr:
+		return getUnaryExpr(v, aliases, packageName)
+	case *ast.ParenExpr:
+		return getParenExpr(v, aliases, packageName)
 	}
 	return "", []string{}
 }
 
+//getMultiplicity returns the multiplicity of the types held by a field of the given type. Slices and maps can hold
+//any number of elements, arrays hold as many as their length and any other type has no multiplicity
+func getMultiplicity(exp ast.Expr) string {
+	switch v := exp.(type) {
+	case *ast.StarExpr:
+		return getMultiplicity(v.X)
+	case *ast.ParenExpr:
+		return getMultiplicity(v.X)
+	case *ast.MapType:
+		return "0..*"
+	case *ast.ArrayType:
+		switch l := v.Len.(type) {
+		case *ast.BasicLit:
+			return l.Value
+		case *ast.Ident:
+			return l.Name
+		}
+		return "0..*"
+	}
+	return ""
+}
+
+//getValueTypes returns the types held by value by a field of the given type, which are the ones that are not behind
+//a pointer or a channel. Slices, arrays and maps hold their elements by value, while the type arguments of a generic
+//type are not held by the field at all
+func getValueTypes(exp ast.Expr, aliases map[string]string, packageName string) []string {
+	switch v := exp.(type) {
+	case *ast.Ident, *ast.SelectorExpr:
+		_, fundamentalTypes := getFieldType(v, aliases, packageName)
+		return fundamentalTypes
+	case *ast.IndexExpr:
+		return getValueTypes(v.X, aliases, packageName)
+	case *ast.IndexListExpr:
+		return getValueTypes(v.X, aliases, packageName)
+	case *ast.ParenExpr:
+		return getValueTypes(v.X, aliases, packageName)
+	case *ast.ArrayType:
+		return getValueTypes(v.Elt, aliases, packageName)
+	case *ast.MapType:
+		return append(getValueTypes(v.Key, aliases, packageName), getValueTypes(v.Value, aliases, packageName)...)
+	}
+	return nil
+}
+
+//getBuiltinTypes returns the builtin types a field of the given type refers to, such as string and int for
+//map[string][]int, once each and in the order they appear
+func getBuiltinTypes(exp ast.Expr) []string {
+	var result []string
+	found := map[string]struct{}{}
+	ast.Inspect(exp, func(n ast.Node) bool {
+		switch v := n.(type) {
+		case *ast.SelectorExpr:
+			// The selected name belongs to another package, even when it is named like a builtin type
+			return false
+		case *ast.Field:
+			// The names of the parameters and of the fields of anonymous structs are not types
+			for _, t := range getBuiltinTypes(v.Type) {
+				if _, ok := found[t]; !ok {
+					found[t] = struct{}{}
+					result = append(result, t)
+				}
+			}
+			return false
+		case *ast.Ident:
+			if _, ok := found[v.Name]; !ok && isPrimitive(v) {
+				found[v.Name] = struct{}{}
+				result = append(result, v.Name)
+			}
+		}
+		return true
+	})
+	return result
+}
+
+//getFieldComment returns the trailing comment of the field, or its doc comment when it has none, collapsed into a
+//single trimmed line
+func getFieldComment(field *ast.Field) string {
+	group := field.Comment
+	if group == nil {
+		group = field.Doc
+	}
+	if group == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(group.Text()), " ")
+}
+
 func getIdent(v *ast.Ident, aliases map[string]string) (string, []string) {
 
 	if isPrimitive(v) {
@@ -58,13 +186,20 @@ func getArrayType(v *ast.ArrayType, aliases map[string]string, packageName strin
 	return fmt.Sprintf("[]%s", t), fundamentalTypes
 }
 
-func getSelectorExp(v *ast.SelectorExpr, aliases map[string]string) (string, []string) {
+func getSelectorExp(v *ast.SelectorExpr, aliases map[string]string, packageName string) (string, []string) {
 
-	packageName := v.X.(*ast.Ident).Name
-	if realPackageName, ok := aliases[packageName]; ok {
-		packageName = realPackageName
+	ident, ok := v.X.(*ast.Ident)
+	if !ok {
+		// Nested selectors like pkg.Outer.Inner are resolved from the innermost one outwards
+		x, _ := getFieldType(v.X, aliases, packageName)
+		t := fmt.Sprintf("%s.%s", x, v.Sel.Name)
+		return t, []string{t}
 	}
-	t := fmt.Sprintf("%s.%s", packageName, v.Sel.Name)
+	selectorPackage := ident.Name
+	if realPackageName, ok := aliases[selectorPackage]; ok {
+		selectorPackage = realPackageName
+	}
+	t := fmt.Sprintf("%s.%s", selectorPackage, v.Sel.Name)
 	return t, []string{t}
 }
 
@@ -84,6 +219,12 @@ func getStarExp(v *ast.StarExpr, aliases map[string]string, packageName string)
 func getChanType(v *ast.ChanType, aliases map[string]string, packageName string) (string, []string) {
 
 	t, f := getFieldType(v.Value, aliases, packageName)
+	switch v.Dir {
+	case ast.SEND:
+		return fmt.Sprintf("chan<- %s", t), f
+	case ast.RECV:
+		return fmt.Sprintf("<-chan %s", t), f
+	}
 	return fmt.Sprintf("chan %s", t), f
 }
 
@@ -101,16 +242,30 @@ func getInterfaceType(v *ast.InterfaceType, aliases map[string]string, packageNa
 
 	methods := make([]string, 0)
 	for _, field := range v.Methods.List {
-		methodName := ""
-		if field.Names != nil {
-			methodName = field.Names[0].Name
-		}
 		t, _ := getFieldType(field.Type, aliases, packageName)
-		methods = append(methods, methodName+" "+t)
+		if field.Names == nil {
+			// Embedded interfaces and type set elements, such as ~int | ~string, have no name
+			methods = append(methods, t)
+			continue
+		}
+		methods = append(methods, field.Names[0].Name+" "+t)
 	}
 	return fmt.Sprintf("interface{%s}", strings.Join(methods, "; ")), []string{}
 }
 
+//getBinaryExpr returns the union of the terms of a type set, such as ~int | ~string in a constraint interface
+func getBinaryExpr(v *ast.BinaryExpr, aliases map[string]string, packageName string) (string, []string) {
+	t1, f1 := getFieldType(v.X, aliases, packageName)
+	t2, f2 := getFieldType(v.Y, aliases, packageName)
+	return fmt.Sprintf("%s %s %s", t1, v.Op, t2), append(f1, f2...)
+}
+
+//getUnaryExpr returns a term of a type set, such as ~int, which stands for all the types whose underlying type is int
+func getUnaryExpr(v *ast.UnaryExpr, aliases map[string]string, packageName string) (string, []string) {
+	t, f := getFieldType(v.X, aliases, packageName)
+	return fmt.Sprintf("%s%s", v.Op, t), f
+}
+
 func getFuncType(v *ast.FuncType, aliases map[string]string, packageName string) (string, []string) {
 
 	function := getFunction(v, "", aliases, packageName)
@@ -136,6 +291,82 @@ func getEllipsis(v *ast.Ellipsis, aliases map[string]string, packageName string)
 	return fmt.Sprintf("...%s", t), []string{}
 }
 
+func getIndexExpr(v *ast.IndexExpr, aliases map[string]string, packageName string) (string, []string) {
+
+	t, f := getFieldType(v.X, aliases, packageName)
+	index, indexFundamentalTypes := getFieldType(v.Index, aliases, packageName)
+	return fmt.Sprintf("%s[%s]", t, index), append(f, indexFundamentalTypes...)
+}
+
+func getIndexListExpr(v *ast.IndexListExpr, aliases map[string]string, packageName string) (string, []string) {
+
+	t, f := getFieldType(v.X, aliases, packageName)
+	indices := make([]string, 0)
+	for _, index := range v.Indices {
+		indexType, indexFundamentalTypes := getFieldType(index, aliases, packageName)
+		indices = append(indices, indexType)
+		f = append(f, indexFundamentalTypes...)
+	}
+	return fmt.Sprintf("%s[%s]", t, strings.Join(indices, ", ")), f
+}
+
+//Returns the type inside the parentheses, so (*T) is rendered as *T. The parentheses are only kept around receive-only
+//channels, since chan (<-chan T) would be read as chan<- (chan T) without them
+func getParenExpr(v *ast.ParenExpr, aliases map[string]string, packageName string) (string, []string) {
+
+	t, f := getFieldType(v.X, aliases, packageName)
+	if c, ok := v.X.(*ast.ChanType); ok && c.Dir == ast.RECV {
+		return fmt.Sprintf("(%s)", t), f
+	}
+	return t, f
+}
+
+//Returns the type parameters declared in the given field list (e.g. [K comparable, V any]) as fields
+//holding the name of the parameter and its constraint
+func getTypeParameters(typeParams *ast.FieldList, aliases map[string]string, packageName string) []*Field {
+	result := make([]*Field, 0)
+	if typeParams == nil {
+		return result
+	}
+	for _, tp := range typeParams.List {
+		for _, name := range tp.Names {
+			result = append(result, &Field{
+				Name: name.Name,
+			})
+		}
+	}
+	names := getTypeParameterNames(result)
+	i := 0
+	for _, tp := range typeParams.List {
+		constraint, _ := getFieldType(tp.Type, aliases, packageName)
+		constraint = replaceTypeParameters(constraint, names)
+		for range tp.Names {
+			result[i].Type = replacePackageConstant(constraint, "")
+			result[i].FullType = replacePackageConstant(constraint, packageName)
+			i++
+		}
+	}
+	return result
+}
+
+//Returns the name of an embedded field of the given type, which is the name of the type without its package,
+//pointer or type arguments. For example *foo.Bar[int] returns Bar
+func getEmbeddedFieldName(theType string) string {
+	name := strings.TrimPrefix(theType, "*")
+	if i := strings.Index(name, "["); i >= 0 {
+		name = name[:i]
+	}
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+func getTypeParameterNames(typeParameters []*Field) []string {
+	names := make([]string, 0, len(typeParameters))
+	for _, tp := range typeParameters {
+		names = append(names, tp.Name)
+	}
+	return names
+}
+
 var globalPrimitives = map[string]struct{}{
 	"bool":        {},
 	"string":      {},
@@ -157,6 +388,8 @@ var globalPrimitives = map[string]struct{}{
 	"complex64":   {},
 	"complex128":  {},
 	"error":       {},
+	"any":         {},
+	"comparable":  {},
 	"*bool":       {},
 	"*string":     {},
 	"*int":        {},
@@ -189,5 +422,15 @@ func IsPrimitiveString(t string) bool {
 }
 
 func replacePackageConstant(field, packageName string) string {
-	return strings.Replace(field, packageConstant, packageName, 1)
+	return strings.ReplaceAll(field, packageConstant, packageName)
+}
+
+//Replaces the identifiers that refer to one of the given type parameters so that T is rendered as T
+//instead of as a type belonging to the current package
+func replaceTypeParameters(field string, typeParameters []string) string {
+	for _, tp := range typeParameters {
+		reg := regexp.MustCompile(regexp.QuoteMeta(fmt.Sprintf("%s.%s", packageConstant, tp)) + `\b`)
+		field = reg.ReplaceAllString(field, tp)
+	}
+	return field
 }
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"go/ast"
+	goparser "go/parser"
 )
 
 type NoMatchField struct {
@@ -29,11 +30,11 @@ func TestGetFieldType(t *testing.T) {
 		},
 		{
 			Name:           "Test *ast.Ident as not primitive",
-			ExpectedResult: fmt.Sprintf("%s%s", packageConstant, "TestClass"),
+			ExpectedResult: fmt.Sprintf("%s.%s", packageConstant, "TestClass"),
 			InputField: &ast.Ident{
 				Name: "TestClass",
 			},
-			ExpectedFundamentalTypes: []string{fmt.Sprintf("%s%s", packageConstant, "TestClass")},
+			ExpectedFundamentalTypes: []string{fmt.Sprintf("%s.%s", packageConstant, "TestClass")},
 		},
 		{
 			Name:           "Test *ast.ArrayType",
@@ -60,7 +61,7 @@ func TestGetFieldType(t *testing.T) {
 		},
 		{
 			Name:           "Test *ast.MapType",
-			ExpectedResult: "<font color=blue>map</font>[string]int",
+			ExpectedResult: "map[string]int",
 			InputField: &ast.MapType{
 				Key: &ast.Ident{
 					Name: "string",
@@ -83,7 +84,7 @@ func TestGetFieldType(t *testing.T) {
 		},
 		{
 			Name:           "Test *ast.ChanType",
-			ExpectedResult: "<font color=blue>chan</font> int",
+			ExpectedResult: "chan int",
 			InputField: &ast.ChanType{
 				Value: &ast.Ident{
 					Name: "int",
@@ -93,7 +94,7 @@ func TestGetFieldType(t *testing.T) {
 		},
 		{
 			Name:           "Test *ast.StructType",
-			ExpectedResult: "<font color=blue>struct</font>{int, string}",
+			ExpectedResult: "struct{int, string}",
 			InputField: &ast.StructType{
 				Fields: &ast.FieldList{
 					List: []*ast.Field{
@@ -114,7 +115,7 @@ func TestGetFieldType(t *testing.T) {
 		},
 		{
 			Name:           "Test *ast.InterfaceType",
-			ExpectedResult: "<font color=blue>interface</font>{Foo <font color=blue>func</font>(*FooComposed) *FooComposed}",
+			ExpectedResult: "interface{Foo func(*main.FooComposed) *main.FooComposed}",
 			InputField: &ast.InterfaceType{
 				Methods: &ast.FieldList{
 					List: []*ast.Field{
@@ -162,7 +163,7 @@ func TestGetFieldType(t *testing.T) {
 		},
 		{
 			Name:                     "Test *ast.FuncType with one result",
-			ExpectedResult:           "<font color=blue>func</font>(*FooComposed) *FooComposed",
+			ExpectedResult:           "func(*main.FooComposed) *main.FooComposed",
 			ExpectedFundamentalTypes: []string{},
 			InputField: &ast.FuncType{
 				Params: &ast.FieldList{
@@ -197,7 +198,7 @@ func TestGetFieldType(t *testing.T) {
 		},
 		{
 			Name:                     "Test *ast.FuncType with two results",
-			ExpectedResult:           "<font color=blue>func</font>(*FooComposed) (*FooComposed, *string)",
+			ExpectedResult:           "func(*main.FooComposed) (*main.FooComposed, *string)",
 			ExpectedFundamentalTypes: []string{},
 			InputField: &ast.FuncType{
 				Params: &ast.FieldList{
@@ -260,7 +261,7 @@ func TestGetFieldType(t *testing.T) {
 			inputAliasMap := map[string]string{
 				"puml": "goplantuml",
 			}
-			result, fundamentalTypes := getFieldType(tc.InputField, inputAliasMap)
+			result, fundamentalTypes := getFieldType(tc.InputField, inputAliasMap, "main")
 			if result != tc.ExpectedResult {
 				t.Errorf("Expected result to be %s, got %s", tc.ExpectedResult, result)
 			}
@@ -277,3 +278,183 @@ func TestIsPrimitiveStringPointer(t *testing.T) {
 		t.Errorf("TestIsPrimitiveStringPointer: expecting true, got false")
 	}
 }
+
+func TestGetFieldTypeWithTypeArguments(t *testing.T) {
+	tt := []struct {
+		Name                     string
+		ExpectedResult           string
+		ExpectedFundamentalTypes []string
+		InputField               ast.Expr
+	}{
+		{
+			Name:                     "Test *ast.IndexExpr",
+			ExpectedResult:           packageConstant + ".List[int]",
+			ExpectedFundamentalTypes: []string{packageConstant + ".List"},
+			InputField: &ast.IndexExpr{
+				X:     &ast.Ident{Name: "List"},
+				Index: &ast.Ident{Name: "int"},
+			},
+		},
+		{
+			Name:                     "Test *ast.IndexListExpr",
+			ExpectedResult:           packageConstant + ".Map[string, goplantuml.TestClass]",
+			ExpectedFundamentalTypes: []string{packageConstant + ".Map", "goplantuml.TestClass"},
+			InputField: &ast.IndexListExpr{
+				X: &ast.Ident{Name: "Map"},
+				Indices: []ast.Expr{
+					&ast.Ident{Name: "string"},
+					&ast.SelectorExpr{
+						X:   &ast.Ident{Name: "puml"},
+						Sel: &ast.Ident{Name: "TestClass"},
+					},
+				},
+			},
+		},
+		{
+			Name:                     "Test *ast.ParenExpr",
+			ExpectedResult:           "*" + packageConstant + ".TestClass",
+			ExpectedFundamentalTypes: []string{packageConstant + ".TestClass"},
+			InputField: &ast.ParenExpr{
+				X: &ast.StarExpr{X: &ast.Ident{Name: "TestClass"}},
+			},
+		},
+		{
+			Name:                     "Test *ast.ParenExpr around a receive-only channel",
+			ExpectedResult:           "chan (<-chan int)",
+			ExpectedFundamentalTypes: []string{},
+			InputField: &ast.ChanType{
+				Dir: ast.SEND | ast.RECV,
+				Value: &ast.ParenExpr{
+					X: &ast.ChanType{Dir: ast.RECV, Value: &ast.Ident{Name: "int"}},
+				},
+			},
+		},
+		{
+			Name:                     "Test nested *ast.SelectorExpr",
+			ExpectedResult:           "goplantuml.Outer.Inner",
+			ExpectedFundamentalTypes: []string{"goplantuml.Outer.Inner"},
+			InputField: &ast.SelectorExpr{
+				X: &ast.SelectorExpr{
+					X:   &ast.Ident{Name: "puml"},
+					Sel: &ast.Ident{Name: "Outer"},
+				},
+				Sel: &ast.Ident{Name: "Inner"},
+			},
+		},
+		{
+			Name:                     "Test *ast.SelectorExpr on an *ast.IndexExpr",
+			ExpectedResult:           "goplantuml.Type[int].Inner",
+			ExpectedFundamentalTypes: []string{"goplantuml.Type[int].Inner"},
+			InputField: &ast.SelectorExpr{
+				X: &ast.IndexExpr{
+					X: &ast.SelectorExpr{
+						X:   &ast.Ident{Name: "puml"},
+						Sel: &ast.Ident{Name: "Type"},
+					},
+					Index: &ast.Ident{Name: "int"},
+				},
+				Sel: &ast.Ident{Name: "Inner"},
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			result, fundamentalTypes := getFieldType(tc.InputField, map[string]string{"puml": "goplantuml"}, "main")
+			if result != tc.ExpectedResult {
+				t.Errorf("Expected result to be %s, got %s", tc.ExpectedResult, result)
+			}
+			if !reflect.DeepEqual(fundamentalTypes, tc.ExpectedFundamentalTypes) {
+				t.Errorf("Expected result to be %v, got %v", tc.ExpectedFundamentalTypes, fundamentalTypes)
+			}
+		})
+	}
+}
+
+func TestGetFieldTypeTypeSets(t *testing.T) {
+	tt := []struct {
+		Name           string
+		Source         string
+		ExpectedResult string
+	}{
+		{
+			Name:           "Approximation",
+			Source:         "~int",
+			ExpectedResult: "~int",
+		},
+		{
+			Name:           "Union of approximations",
+			Source:         "~int | ~string",
+			ExpectedResult: "~int | ~string",
+		},
+		{
+			Name:           "Union of types of other packages",
+			Source:         "puml.TestClass | *Local | (float64)",
+			ExpectedResult: "goplantuml.TestClass | *" + packageConstant + ".Local | float64",
+		},
+		{
+			Name:           "Constraint interface",
+			Source:         "interface{ ~int | ~string; String() string }",
+			ExpectedResult: "interface{~int | ~string; String func() string}",
+		},
+		{
+			Name:           "Embedded interface",
+			Source:         "interface{ any }",
+			ExpectedResult: "interface{any}",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			expr, err := goparser.ParseExpr(tc.Source)
+			if err != nil {
+				t.Fatalf("Expected no error parsing %s, got %s", tc.Source, err.Error())
+			}
+			result, _ := getFieldType(expr, map[string]string{"puml": "goplantuml"}, "main")
+			if result != tc.ExpectedResult {
+				t.Errorf("Expected result to be %s, got %s", tc.ExpectedResult, result)
+			}
+		})
+	}
+}
+
+func TestReplaceTypeParameters(t *testing.T) {
+	result := replaceTypeParameters(fmt.Sprintf("map[%s.T]%s.Tree", packageConstant, packageConstant), []string{"T"})
+	expected := fmt.Sprintf("map[T]%s.Tree", packageConstant)
+	if result != expected {
+		t.Errorf("TestReplaceTypeParameters: Expected result to be %s, got %s", expected, result)
+	}
+}
+
+func TestGetChanType(t *testing.T) {
+	tt := []struct {
+		Name           string
+		ExpectedResult string
+		Dir            ast.ChanDir
+	}{
+		{
+			Name:           "Bidirectional channel",
+			ExpectedResult: "chan int",
+			Dir:            ast.SEND | ast.RECV,
+		},
+		{
+			Name:           "Send only channel",
+			ExpectedResult: "chan<- int",
+			Dir:            ast.SEND,
+		},
+		{
+			Name:           "Receive only channel",
+			ExpectedResult: "<-chan int",
+			Dir:            ast.RECV,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			result, _ := getFieldType(&ast.ChanType{
+				Dir:   tc.Dir,
+				Value: &ast.Ident{Name: "int"},
+			}, map[string]string{}, "main")
+			if result != tc.ExpectedResult {
+				t.Errorf("Expected result to be %s, got %s", tc.ExpectedResult, result)
+			}
+		})
+	}
+}
@@ -1,8 +1,12 @@
 package parser
 
 import (
+	"fmt"
 	"go/ast"
+	"go/token"
 	"reflect"
+	"strings"
+	"unicode"
 )
 
 //Function holds the signature of a function with name, Parameters and Return values
@@ -12,6 +16,25 @@ type Function struct {
 	ReturnValues         []string
 	PackageName          string
 	FullNameReturnValues []string
+	// ReturnValueNames holds the name of every return value, in the same order as ReturnValues, when the return values
+	// are named. It is empty otherwise
+	ReturnValueNames []string
+	Pos              token.Pos
+	// PointerReceiver is true for methods declared with a pointer receiver (func (t *T)). It is always false for
+	// interface methods
+	PointerReceiver bool
+	// DefinedIn is the path of the file the method or the package function was declared in. It is not set for
+	// interface methods, which are always declared with their interface
+	DefinedIn string
+	// TypeParameters holds the type parameters of a generic package function, as in func Max[T Ordered](a, b T) T.
+	// Methods use the type parameters of their receiver type instead
+	TypeParameters []*Field
+}
+
+//IsExported returns true when the function is exported following the Go rules, that is when its name starts with an
+//upper case letter
+func (f *Function) IsExported() bool {
+	return token.IsExported(f.Name)
 }
 
 //SignturesAreEqual Returns true if the two functions have the same signature (parameter names are not checked)
@@ -30,9 +53,25 @@ func (f *Function) SignturesAreEqual(function *Function) bool {
 	return result
 }
 
+// signature returns a string that is equal for two functions only if SignturesAreEqual returns true for them, so it can
+// be used as a key to index functions
+func (f *Function) signature() string {
+	params := make([]string, 0, len(f.Parameters))
+	for _, p := range f.Parameters {
+		params = append(params, p.FullType)
+	}
+	return fmt.Sprintf("%s(%s)(%s)", f.Name, strings.Join(params, "\x00"), strings.Join(f.FullNameReturnValues, "\x00"))
+}
+
 // generate and return a function object from the given Functype. The names must be passed to this
 // function since the FuncType does not have this information
 func getFunction(f *ast.FuncType, name string, aliases map[string]string, packageName string) *Function {
+	return getFunctionWithTypeParameters(f, name, aliases, packageName, nil)
+}
+
+// same as getFunction, but the identifiers matching one of the given type parameters are kept as they are
+// instead of being treated as types of the current package
+func getFunctionWithTypeParameters(f *ast.FuncType, name string, aliases map[string]string, packageName string, typeParameters []string) *Function {
 	function := &Function{
 		Name:                 name,
 		Parameters:           make([]*Field, 0),
@@ -44,6 +83,7 @@ func getFunction(f *ast.FuncType, name string, aliases map[string]string, packag
 	if params != nil {
 		for _, pa := range params.List {
 			theType, _ := getFieldType(pa.Type, aliases, packageName)
+			theType = replaceTypeParameters(theType, typeParameters)
 			if pa.Names != nil {
 				if pa.Names != nil {
 					for _, fieldName := range pa.Names {
@@ -68,9 +108,59 @@ func getFunction(f *ast.FuncType, name string, aliases map[string]string, packag
 	if results != nil {
 		for _, pa := range results.List {
 			theType, _ := getFieldType(pa.Type, aliases, packageName)
-			function.ReturnValues = append(function.ReturnValues, replacePackageConstant(theType, packageName))
-			function.FullNameReturnValues = append(function.FullNameReturnValues, replacePackageConstant(theType, packageName))
+			theType = replaceTypeParameters(theType, typeParameters)
+			if len(pa.Names) == 0 {
+				function.ReturnValues = append(function.ReturnValues, replacePackageConstant(theType, packageName))
+				function.FullNameReturnValues = append(function.FullNameReturnValues, replacePackageConstant(theType, packageName))
+				continue
+			}
+			// Named return values sharing their type, as in (x, y int), are one return value each
+			for _, resultName := range pa.Names {
+				function.ReturnValues = append(function.ReturnValues, replacePackageConstant(theType, packageName))
+				function.FullNameReturnValues = append(function.FullNameReturnValues, replacePackageConstant(theType, packageName))
+				function.ReturnValueNames = append(function.ReturnValueNames, resultName.Name)
+			}
 		}
 	}
 	return function
 }
+
+// HasNamedReturnValues returns true if the return values of the function are named
+func (f *Function) HasNamedReturnValues() bool {
+	return len(f.ReturnValueNames) > 0
+}
+
+// NamedReturnValues returns every return value preceded by its name, when it has one
+func (f *Function) NamedReturnValues() []string {
+	result := make([]string, 0, len(f.ReturnValues))
+	for i, returnValue := range f.ReturnValues {
+		if i < len(f.ReturnValueNames) {
+			returnValue = fmt.Sprintf("%s %s", f.ReturnValueNames[i], returnValue)
+		}
+		result = append(result, returnValue)
+	}
+	return result
+}
+
+// String returns the signature of the function, as in Read(p []byte) (n int, err error)
+func (f *Function) String() string {
+	params := make([]string, 0, len(f.Parameters))
+	for _, p := range f.Parameters {
+		params = append(params, p.String())
+	}
+	returnValues := f.NamedReturnValues()
+	returns := ""
+	if len(returnValues) > 1 || f.HasNamedReturnValues() {
+		returns = fmt.Sprintf(" (%s)", strings.Join(returnValues, ", "))
+	} else if len(returnValues) == 1 {
+		returns = " " + returnValues[0]
+	}
+	return fmt.Sprintf("%s(%s)%s", f.Name, strings.Join(params, ", "), returns)
+}
+
+// IsConstructor returns true if the function is named New, or New followed by an exported name such as NewReader, as
+// constructors are by convention. It only makes sense for functions declared without a receiver
+func (f *Function) IsConstructor() bool {
+	name := strings.TrimPrefix(f.Name, "New")
+	return len(name) < len(f.Name) && (name == "" || unicode.IsUpper([]rune(name)[0]))
+}
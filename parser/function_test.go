@@ -2,6 +2,7 @@ package parser
 
 import (
 	"go/ast"
+	goparser "go/parser"
 	"reflect"
 	"testing"
 )
@@ -118,3 +119,79 @@ func TestGetFunction(t *testing.T) {
 		})
 	}
 }
+
+func TestNamedReturnValues(t *testing.T) {
+	tt := []struct {
+		name              string
+		source            string
+		expectedTypes     []string
+		expectedNames     []string
+		expectedRendering []string
+	}{
+		{
+			name:              "Unnamed",
+			source:            "func() (int, error)",
+			expectedTypes:     []string{"int", "error"},
+			expectedRendering: []string{"int", "error"},
+		},
+		{
+			name:              "Named",
+			source:            "func() (n int, err error)",
+			expectedTypes:     []string{"int", "error"},
+			expectedNames:     []string{"n", "err"},
+			expectedRendering: []string{"n int", "err error"},
+		},
+		{
+			name:              "Named sharing their type",
+			source:            "func() (x, y int)",
+			expectedTypes:     []string{"int", "int"},
+			expectedNames:     []string{"x", "y"},
+			expectedRendering: []string{"x int", "y int"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			expr, err := goparser.ParseExpr(tc.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			function := getFunction(expr.(*ast.FuncType), "f", map[string]string{}, "main")
+			if !reflect.DeepEqual(function.ReturnValues, tc.expectedTypes) {
+				t.Errorf("Expected return values %v, got %v", tc.expectedTypes, function.ReturnValues)
+			}
+			if !reflect.DeepEqual(function.ReturnValueNames, tc.expectedNames) {
+				t.Errorf("Expected return value names %v, got %v", tc.expectedNames, function.ReturnValueNames)
+			}
+			if function.HasNamedReturnValues() != (tc.expectedNames != nil) {
+				t.Errorf("Expected HasNamedReturnValues to be %t", tc.expectedNames != nil)
+			}
+			if !reflect.DeepEqual(function.NamedReturnValues(), tc.expectedRendering) {
+				t.Errorf("Expected named return values %v, got %v", tc.expectedRendering, function.NamedReturnValues())
+			}
+		})
+	}
+}
+
+func TestFunctionString(t *testing.T) {
+	tt := []struct {
+		source   string
+		expected string
+	}{
+		{source: "func()", expected: "f()"},
+		{source: "func(p []byte, s string) int", expected: "f(p []byte, s string) int"},
+		{source: "func(p []byte) (int, error)", expected: "f(p []byte) (int, error)"},
+		{source: "func() (n int)", expected: "f() (n int)"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.source, func(t *testing.T) {
+			expr, err := goparser.ParseExpr(tc.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			function := getFunction(expr.(*ast.FuncType), "f", map[string]string{}, "main")
+			if function.String() != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, function.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// goModule is the module declared in a go.mod file and the directory the file is in
+type goModule struct {
+	path string
+	root string
+}
+
+// findModule returns the module of the nearest go.mod in the given directory or any of its parents, or nil if there is
+// none. The result is cached for every directory visited
+func (p *ClassParser) findModule(directoryPath string) *goModule {
+	dir, err := filepath.Abs(directoryPath)
+	if err != nil {
+		return nil
+	}
+	if p.modulesMutex != nil {
+		p.modulesMutex.Lock()
+		defer p.modulesMutex.Unlock()
+	}
+	var visited []string
+	var module *goModule
+	for {
+		if m, ok := p.modules[dir]; ok {
+			module = m
+			break
+		}
+		visited = append(visited, dir)
+		if content, err := afero.ReadFile(p.fileSystem(), filepath.Join(dir, "go.mod")); err == nil {
+			if modulePath := getModulePath(content); modulePath != "" {
+				module = &goModule{path: modulePath, root: dir}
+				break
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	for _, v := range visited {
+		p.modules[v] = module
+	}
+	return module
+}
+
+// getModulePath returns the path in the module directive of the given go.mod content, or an empty string if there is none
+func getModulePath(content []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 || fields[0] != "module" {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(fields[1]); err == nil {
+			return unquoted
+		}
+		return fields[1]
+	}
+	return ""
+}
+
+// getImportPath returns the elements of the import path of the given directory from the path of the module. It returns
+// false if the directory is not inside the module root
+func (m *goModule) getImportPath(directoryPath string) ([]string, bool) {
+	dir, err := filepath.Abs(directoryPath)
+	if err != nil {
+		return nil, false
+	}
+	rel, err := filepath.Rel(m.root, dir)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return nil, false
+	}
+	base := strings.Split(m.path, "/")
+	if rel != "." {
+		base = append(base, splitPath(rel)...)
+	}
+	return base, true
+}
@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"testing"
+)
+
+func TestGetModulePath(t *testing.T) {
+	tt := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name:     "Module directive",
+			content:  "module github.com/jfeliu007/goplantuml\n\ngo 1.16\n",
+			expected: "github.com/jfeliu007/goplantuml",
+		},
+		{
+			name:     "Quoted module with comment",
+			content:  "// the module\nmodule \"example.com/m\" // comment\n",
+			expected: "example.com/m",
+		},
+		{
+			name:     "No module directive",
+			content:  "go 1.16\n",
+			expected: "",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			result := getModulePath([]byte(tc.content))
+			if result != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestGetPackageBaseFromGoMod(t *testing.T) {
+	parser := getEmptyParser("main")
+	parser.AllImports = make(map[string]string)
+	parser.RenderingOptions.ModuleBase = "parser"
+	parser.modules = make(map[string]*goModule)
+	tt := []struct {
+		directoryPath string
+		expected      string
+	}{
+		{
+			directoryPath: "../testingsupport/subfolder2",
+			expected:      "github.com.jfeliu007.goplantuml.testingsupport",
+		},
+		{
+			directoryPath: "../testingsupport",
+			expected:      "github.com.jfeliu007.goplantuml",
+		},
+		{
+			directoryPath: "..",
+			expected:      "github.com.jfeliu007",
+		},
+	}
+	for _, tc := range tt {
+		result := parser.getPackageBase(tc.directoryPath)
+		if result != tc.expected {
+			t.Errorf("Expected package base of %s to be %s, got %s", tc.directoryPath, tc.expected, result)
+		}
+	}
+	err := parser.parseDirectory("../testingsupport/subfolder2")
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	if parser.getStruct("github.com.jfeliu007.goplantuml.testingsupport.subfolder2.Subfolder2") == nil {
+		t.Errorf("Expected Subfolder2 to be in the namespace of its import path")
+	}
+}
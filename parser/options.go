@@ -0,0 +1,764 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Option sets one of the RenderingOptions of a ClassParser. Options are typed so passing the wrong kind of value is a
+// compile error instead of a panic, see ClassParser.ApplyOptions
+type Option func(*RenderingOptions) error
+
+// WithAggregations sets whether aggregations are rendered
+func WithAggregations(render bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.Aggregations = render
+		return nil
+	}
+}
+
+// WithCompositions sets whether compositions are rendered
+func WithCompositions(render bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.Compositions = render
+		return nil
+	}
+}
+
+// WithImplementations sets whether implementations are rendered
+func WithImplementations(render bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.Implementations = render
+		return nil
+	}
+}
+
+// WithAliases sets whether aliases are rendered
+func WithAliases(render bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.Aliases = render
+		return nil
+	}
+}
+
+// WithFields sets whether fields are rendered
+func WithFields(render bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.Fields = render
+		return nil
+	}
+}
+
+// WithMethods sets whether methods are rendered
+func WithMethods(render bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.Methods = render
+		return nil
+	}
+}
+
+// WithConnectionLabels sets whether the connections are rendered with labels
+func WithConnectionLabels(render bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.ConnectionLabels = render
+		return nil
+	}
+}
+
+// WithTitle sets the title of the diagram. No title is rendered when it is empty
+func WithTitle(title string) Option {
+	return func(ro *RenderingOptions) error {
+		ro.Title = title
+		return nil
+	}
+}
+
+// WithNotes sets the notes rendered in the diagram
+func WithNotes(notes string) Option {
+	return func(ro *RenderingOptions) error {
+		ro.Notes = notes
+		return nil
+	}
+}
+
+// WithAggregatePrivateMembers sets whether aggregations are rendered for private members too
+func WithAggregatePrivateMembers(aggregate bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.AggregatePrivateMembers = aggregate
+		return nil
+	}
+}
+
+// WithPrivateMembers sets whether private fields and methods are rendered
+func WithPrivateMembers(render bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.PrivateMembers = render
+		return nil
+	}
+}
+
+// WithColorSeed sets the seed used to derive the colors of the connections. Random colors are used when it is 0
+func WithColorSeed(seed int) Option {
+	return func(ro *RenderingOptions) error {
+		ro.ColorSeed = seed
+		return nil
+	}
+}
+
+// WithConstants sets whether the constants declared for a named type are rendered as an enumeration
+func WithConstants(render bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.Constants = render
+		return nil
+	}
+}
+
+// WithMemberOrder sets the order in which fields and methods are rendered. It fails for unknown orders
+func WithMemberOrder(order MemberOrder) Option {
+	return func(ro *RenderingOptions) error {
+		switch order {
+		case MemberOrderVisibility, MemberOrderSource, MemberOrderAlphabetical:
+			ro.MemberOrder = order
+			return nil
+		}
+		return fmt.Errorf("Invalid member order %v", order)
+	}
+}
+
+// WithGroupBy sets how the classes rendered side by side are chosen. It fails for unknown groupings
+func WithGroupBy(grouping Grouping) Option {
+	return func(ro *RenderingOptions) error {
+		switch grouping {
+		case "", GroupByNone, GroupByFile:
+			ro.GroupBy = grouping
+			return nil
+		}
+		return fmt.Errorf("Invalid grouping %v", grouping)
+	}
+}
+
+// WithEmbeddedAsComposition sets whether embedded fields are rendered as compositions instead of regular fields
+func WithEmbeddedAsComposition(render bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.EmbeddedAsComposition = render
+		return nil
+	}
+}
+
+// WithMultiplicity sets whether aggregations of slices, arrays and maps are rendered with their multiplicity
+func WithMultiplicity(render bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.Multiplicity = render
+		return nil
+	}
+}
+
+// WithNestedNamespaces sets whether packages are rendered as namespaces nested into the ones of their parent packages
+func WithNestedNamespaces(render bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.NestedNamespaces = render
+		return nil
+	}
+}
+
+// WithIncludePattern renders only the types whose fully qualified name matches the given regular expression. It fails
+// if the expression does not compile. An empty pattern includes every type
+func WithIncludePattern(pattern string) Option {
+	return func(ro *RenderingOptions) error {
+		re, err := compilePattern(pattern)
+		if err != nil {
+			return err
+		}
+		ro.IncludePattern = re
+		return nil
+	}
+}
+
+// WithExcludePattern does not render the types whose fully qualified name matches the given regular expression. It
+// fails if the expression does not compile. An empty pattern excludes no type
+func WithExcludePattern(pattern string) Option {
+	return func(ro *RenderingOptions) error {
+		re, err := compilePattern(pattern)
+		if err != nil {
+			return err
+		}
+		ro.ExcludePattern = re
+		return nil
+	}
+}
+
+// WithDocComments sets whether the doc comments of the types are rendered as notes attached to them
+func WithDocComments(render bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.DocComments = render
+		return nil
+	}
+}
+
+// WithImportGraph sets whether the diagram shows the packages and the imports between them instead of the types
+func WithImportGraph(render bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.ImportGraph = render
+		return nil
+	}
+}
+
+// WithHideEmptyClasses sets whether the types rendered without any member and without any connection are hidden
+func WithHideEmptyClasses(hide bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.HideEmptyClasses = hide
+		return nil
+	}
+}
+
+// WithMermaidNamespaces sets whether the mermaid render wraps the classes of every package in a namespace block
+func WithMermaidNamespaces(render bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.MermaidNamespaces = render
+		return nil
+	}
+}
+
+// WithReceiverKind sets whether the methods declared with a pointer receiver are rendered with a * before their name
+func WithReceiverKind(render bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.ShowReceiverKind = render
+		return nil
+	}
+}
+
+// WithNoColor sets whether the connections are drawn with the default color instead of random ones
+func WithNoColor(noColor bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.NoColor = noColor
+		return nil
+	}
+}
+
+// WithStereotypes sets the stereotypes rendered for the structures of the types used as keys, replacing the default
+// ones. It fails for keys other than "class", "interface", "alias", "type" and "func"
+func WithStereotypes(stereotypes map[string]string) Option {
+	return func(ro *RenderingOptions) error {
+		result := make(map[string]string, len(stereotypes))
+		for structureType, stereotype := range stereotypes {
+			switch structureType {
+			case "class", "interface", "alias", "type", "func":
+				result[structureType] = stereotype
+			default:
+				return fmt.Errorf("Invalid stereotype type %s", structureType)
+			}
+		}
+		ro.Stereotypes = result
+		return nil
+	}
+}
+
+// WithConnectors sets the arrows the plantuml render draws the connections of the kinds used as keys with, replacing
+// the default ones. It fails for keys other than "composition", "extends", "realization", "aggregation", "alias",
+// "derives" and "constraint", and for arrows that are empty or hold spaces or quotes
+func WithConnectors(connectors map[string]string) Option {
+	return func(ro *RenderingOptions) error {
+		result := make(map[string]string, len(connectors))
+		for kind, connector := range connectors {
+			switch kind {
+			case "composition", "extends", "realization", "aggregation", "alias", "derives", "constraint":
+			default:
+				return fmt.Errorf("Invalid connector kind %s", kind)
+			}
+			if connector == "" || strings.ContainsAny(connector, " \t\n\"") {
+				return fmt.Errorf("Invalid connector %q for %s", connector, kind)
+			}
+			result[kind] = connector
+		}
+		ro.Connectors = result
+		return nil
+	}
+}
+
+// WithPackageFunctions sets whether the functions declared without a receiver are rendered in a class of their own for
+// every package
+func WithPackageFunctions(render bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.PackageFunctions = render
+		return nil
+	}
+}
+
+// WithHideEmptyMembers sets whether the plantuml render hides the sections of the classes that have no fields or no
+// methods
+func WithHideEmptyMembers(hide bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.HideEmptyMembers = hide
+		return nil
+	}
+}
+
+// WithHideInterfaceMethods sets whether the methods of the interfaces are hidden
+func WithHideInterfaceMethods(hide bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.HideInterfaceMethods = hide
+		return nil
+	}
+}
+
+// WithHideConstructors sets whether the constructors are hidden from the functions of the packages
+func WithHideConstructors(hide bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.HideConstructors = hide
+		return nil
+	}
+}
+
+// WithSmartRelationships sets whether the aggregations of the types held by value are rendered as compositions
+func WithSmartRelationships(smart bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.SmartRelationships = smart
+		return nil
+	}
+}
+
+// WithMaxSignatureWidth sets the number of characters above which the parameters of the method signatures are wrapped
+// onto continuation lines. Signatures are never wrapped when it is 0 or less
+func WithMaxSignatureWidth(width int) Option {
+	return func(ro *RenderingOptions) error {
+		ro.MaxSignatureWidth = width
+		return nil
+	}
+}
+
+// WithPlainText sets whether the keywords of the types are rendered without markup
+func WithPlainText(plain bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.PlainText = plain
+		return nil
+	}
+}
+
+// WithTheme sets the PlantUML theme the diagram is rendered with. No theme is used when it is empty
+func WithTheme(theme string) Option {
+	return func(ro *RenderingOptions) error {
+		ro.Theme = theme
+		return nil
+	}
+}
+
+// WithFieldComments sets whether the comments of the fields are rendered after them
+func WithFieldComments(render bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.FieldComments = render
+		return nil
+	}
+}
+
+// WithIndent sets the string repeated at the beginning of the rendered lines for every level of depth. It fails when it
+// holds anything but spaces and tabs. Four spaces are used when it is empty
+func WithIndent(indent string) Option {
+	return func(ro *RenderingOptions) error {
+		if strings.Trim(indent, " \t") != "" {
+			return fmt.Errorf("Invalid indentation %q, only spaces and tabs are allowed", indent)
+		}
+		ro.Indent = indent
+		return nil
+	}
+}
+
+// WithFieldNameLabels sets whether the aggregations and compositions are labeled with the names of their fields
+func WithFieldNameLabels(render bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.FieldNameLabels = render
+		return nil
+	}
+}
+
+// WithBuiltinAggregations sets whether the aggregations of builtin types such as string or int are rendered
+func WithBuiltinAggregations(render bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.BuiltinAggregations = render
+		return nil
+	}
+}
+
+// WithFlattenPackages sets whether the classes of every package are rendered at the top level instead of in a namespace
+func WithFlattenPackages(flatten bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.FlattenPackages = flatten
+		return nil
+	}
+}
+
+// WithMarkdownFence sets whether the plantuml and mermaid renders wrap the diagrams in a markdown fenced code block
+func WithMarkdownFence(fence bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.MarkdownFence = fence
+		return nil
+	}
+}
+
+// WithPackageAliases sets the names the package prefixes used as keys are displayed with by the plantuml and mermaid
+// renders. It fails for empty prefixes and for prefixes that end with a dot
+func WithPackageAliases(aliases map[string]string) Option {
+	return func(ro *RenderingOptions) error {
+		result := make(map[string]string, len(aliases))
+		for prefix, alias := range aliases {
+			if prefix == "" || strings.HasSuffix(prefix, ".") {
+				return fmt.Errorf("Invalid package prefix %s", prefix)
+			}
+			result[prefix] = alias
+		}
+		ro.PackageAliases = result
+		return nil
+	}
+}
+
+// WithPackageLegends sets whether the plantuml render draws a note with the number of structs and interfaces of every
+// package in its namespace
+func WithPackageLegends(legends bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.PackageLegends = legends
+		return nil
+	}
+}
+
+// WithConstValues sets whether the constants of the enumerations are rendered along with their value, when it is known
+func WithConstValues(render bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.ConstValues = render
+		return nil
+	}
+}
+
+// WithHideSelfReferences sets whether the compositions and aggregations of a type with itself are hidden
+func WithHideSelfReferences(hide bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.HideSelfReferences = hide
+		return nil
+	}
+}
+
+// WithConstraintEdges sets whether the generic types are connected to the interfaces their type parameters are
+// constrained by
+func WithConstraintEdges(render bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.ConstraintEdges = render
+		return nil
+	}
+}
+
+// WithPackageVars sets whether the package level variables holding a parsed struct are rendered in a class of their own
+// for every package
+func WithPackageVars(render bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.PackageVars = render
+		return nil
+	}
+}
+
+// WithMaxMembers sets the number of fields, and separately of methods, rendered in every class. Every member is rendered
+// when it is 0 or less
+func WithMaxMembers(count int) Option {
+	return func(ro *RenderingOptions) error {
+		ro.MaxMembers = count
+		return nil
+	}
+}
+
+// WithQualifiedMemberTypes sets whether the types of the fields and parameters are rendered with their fully qualified
+// name
+func WithQualifiedMemberTypes(qualified bool) Option {
+	return func(ro *RenderingOptions) error {
+		ro.QualifiedMemberTypes = qualified
+		return nil
+	}
+}
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile(pattern)
+}
+
+// ApplyOptions sets the given options in order. It stops at the first option that fails
+func (p *ClassParser) ApplyOptions(opts ...Option) error {
+	for _, opt := range opts {
+		if err := opt(p.RenderingOptions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getOption returns the Option that sets the given RenderingOption to val. It fails if the option is unknown or if
+// val is not of the type the option expects
+func getOption(option RenderingOption, val interface{}) (Option, error) {
+	switch option {
+	case RenderAggregations:
+		return getBoolOption(option, val, WithAggregations)
+	case RenderAliases:
+		return getBoolOption(option, val, WithAliases)
+	case RenderCompositions:
+		return getBoolOption(option, val, WithCompositions)
+	case RenderFields:
+		return getBoolOption(option, val, WithFields)
+	case RenderImplementations:
+		return getBoolOption(option, val, WithImplementations)
+	case RenderMethods:
+		return getBoolOption(option, val, WithMethods)
+	case RenderConnectionLabels:
+		return getBoolOption(option, val, WithConnectionLabels)
+	case RenderTitle:
+		return getStringOption(option, val, WithTitle)
+	case RenderNotes:
+		return getStringOption(option, val, WithNotes)
+	case AggregatePrivateMembers:
+		return getBoolOption(option, val, WithAggregatePrivateMembers)
+	case RenderPrivateMembers:
+		return getBoolOption(option, val, WithPrivateMembers)
+	case ColorSeed:
+		seed, ok := val.(int)
+		if !ok {
+			return nil, &InvalidOptionValueError{Option: option, Expected: "int", Value: val}
+		}
+		return WithColorSeed(seed), nil
+	case RenderConstants:
+		return getBoolOption(option, val, WithConstants)
+	case RenderEmbeddedAsComposition:
+		return getBoolOption(option, val, WithEmbeddedAsComposition)
+	case RenderMultiplicity:
+		return getBoolOption(option, val, WithMultiplicity)
+	case RenderNestedNamespaces:
+		return getBoolOption(option, val, WithNestedNamespaces)
+	case IncludePattern:
+		return getStringOption(option, val, WithIncludePattern)
+	case ExcludePattern:
+		return getStringOption(option, val, WithExcludePattern)
+	case RenderDocComments:
+		return getBoolOption(option, val, WithDocComments)
+	case ImportGraph:
+		return getBoolOption(option, val, WithImportGraph)
+	case HideEmptyClasses:
+		return getBoolOption(option, val, WithHideEmptyClasses)
+	case MermaidNamespaces:
+		return getBoolOption(option, val, WithMermaidNamespaces)
+	case ShowReceiverKind:
+		return getBoolOption(option, val, WithReceiverKind)
+	case NoColor:
+		return getBoolOption(option, val, WithNoColor)
+	case Stereotypes:
+		stereotypes, ok := val.(map[string]string)
+		if !ok {
+			return nil, &InvalidOptionValueError{Option: option, Expected: "map[string]string", Value: val}
+		}
+		return WithStereotypes(stereotypes), nil
+	case Connectors:
+		connectors, ok := val.(map[string]string)
+		if !ok {
+			return nil, &InvalidOptionValueError{Option: option, Expected: "map[string]string", Value: val}
+		}
+		return WithConnectors(connectors), nil
+	case RenderPackageFunctions:
+		return getBoolOption(option, val, WithPackageFunctions)
+	case HideEmptyMembers:
+		return getBoolOption(option, val, WithHideEmptyMembers)
+	case HideInterfaceMethods:
+		return getBoolOption(option, val, WithHideInterfaceMethods)
+	case HideConstructors:
+		return getBoolOption(option, val, WithHideConstructors)
+	case SmartRelationships:
+		return getBoolOption(option, val, WithSmartRelationships)
+	case MaxSignatureWidth:
+		width, ok := val.(int)
+		if !ok {
+			return nil, &InvalidOptionValueError{Option: option, Expected: "int", Value: val}
+		}
+		return WithMaxSignatureWidth(width), nil
+	case PlainText:
+		return getBoolOption(option, val, WithPlainText)
+	case Theme:
+		return getStringOption(option, val, WithTheme)
+	case RenderFieldComments:
+		return getBoolOption(option, val, WithFieldComments)
+	case Indent:
+		return getStringOption(option, val, WithIndent)
+	case RenderFieldNameLabels:
+		return getBoolOption(option, val, WithFieldNameLabels)
+	case RenderBuiltinAggregations:
+		return getBoolOption(option, val, WithBuiltinAggregations)
+	case FlattenPackages:
+		return getBoolOption(option, val, WithFlattenPackages)
+	case MarkdownFence:
+		return getBoolOption(option, val, WithMarkdownFence)
+	case PackageAliases:
+		aliases, ok := val.(map[string]string)
+		if !ok {
+			return nil, &InvalidOptionValueError{Option: option, Expected: "map[string]string", Value: val}
+		}
+		return WithPackageAliases(aliases), nil
+	case PackageLegends:
+		return getBoolOption(option, val, WithPackageLegends)
+	case ShowConstValues:
+		return getBoolOption(option, val, WithConstValues)
+	case HideSelfReferences:
+		return getBoolOption(option, val, WithHideSelfReferences)
+	case RenderConstraintEdges:
+		return getBoolOption(option, val, WithConstraintEdges)
+	case RenderPackageVars:
+		return getBoolOption(option, val, WithPackageVars)
+	case MaxMembers:
+		count, ok := val.(int)
+		if !ok {
+			return nil, &InvalidOptionValueError{Option: option, Expected: "int", Value: val}
+		}
+		return WithMaxMembers(count), nil
+	case QualifiedMemberTypes:
+		return getBoolOption(option, val, WithQualifiedMemberTypes)
+	case RenderMemberOrder:
+		order, ok := val.(MemberOrder)
+		if !ok {
+			return nil, &InvalidOptionValueError{Option: option, Expected: "MemberOrder", Value: val}
+		}
+		return WithMemberOrder(order), nil
+	case GroupBy:
+		grouping, ok := val.(Grouping)
+		if !ok {
+			return nil, &InvalidOptionValueError{Option: option, Expected: "Grouping", Value: val}
+		}
+		return WithGroupBy(grouping), nil
+	}
+	return nil, fmt.Errorf("Invalid Rendering option %v", option)
+}
+
+func getBoolOption(option RenderingOption, val interface{}, f func(bool) Option) (Option, error) {
+	b, ok := val.(bool)
+	if !ok {
+		return nil, &InvalidOptionValueError{Option: option, Expected: "bool", Value: val}
+	}
+	return f(b), nil
+}
+
+func getStringOption(option RenderingOption, val interface{}, f func(string) Option) (Option, error) {
+	s, ok := val.(string)
+	if !ok {
+		return nil, &InvalidOptionValueError{Option: option, Expected: "string", Value: val}
+	}
+	return f(s), nil
+}
+
+// InvalidOptionValueError is returned by SetRenderingOptions when the value given for an option is not of the type
+// the option expects
+type InvalidOptionValueError struct {
+	Option   RenderingOption
+	Expected string
+	Value    interface{}
+}
+
+func (e *InvalidOptionValueError) Error() string {
+	return fmt.Sprintf("option %s expects %s, got %T", e.Option, e.Expected, e.Value)
+}
+
+// OptionConflictError is returned by RenderingOptions.Validate for an option that has no effect, or contradicts
+// another option, with the other options that are set
+type OptionConflictError struct {
+	Option RenderingOption
+	Reason string
+}
+
+func (e *OptionConflictError) Error() string {
+	return fmt.Sprintf("option %s %s", e.Option, e.Reason)
+}
+
+// Validate returns an OptionConflictError for every option that has no effect, or contradicts another option, with
+// the other options that are set. None of them prevents the diagram from being rendered, but it is probably not the
+// one that was expected
+func (ro *RenderingOptions) Validate() []error {
+	var result []error
+	conflict := func(set bool, option RenderingOption, reason string) {
+		if set {
+			result = append(result, &OptionConflictError{Option: option, Reason: reason})
+		}
+	}
+	conflict(ro.AggregatePrivateMembers && !ro.Aggregations, AggregatePrivateMembers, "has no effect when RenderAggregations is false")
+	conflict(ro.Multiplicity && !ro.Aggregations, RenderMultiplicity, "has no effect when RenderAggregations is false")
+	conflict(ro.SmartRelationships && !ro.Aggregations, SmartRelationships, "has no effect when RenderAggregations is false")
+	conflict(ro.BuiltinAggregations && !ro.Aggregations, RenderBuiltinAggregations, "has no effect when RenderAggregations is false")
+	conflict(ro.ConnectionLabels && !ro.Aggregations && !ro.Compositions && !ro.Implementations && !ro.Aliases,
+		RenderConnectionLabels, "has no effect when no connection is rendered")
+	conflict(ro.FieldNameLabels && !ro.Aggregations && !ro.Compositions,
+		RenderFieldNameLabels, "has no effect when RenderAggregations and RenderCompositions are false")
+	conflict(ro.HideInterfaceMethods && !ro.Methods, HideInterfaceMethods, "has no effect when RenderMethods is false")
+	conflict(ro.ShowReceiverKind && !ro.Methods, ShowReceiverKind, "has no effect when RenderMethods is false")
+	conflict(ro.HideConstructors && !ro.PackageFunctions, HideConstructors, "has no effect when RenderPackageFunctions is false")
+	conflict(ro.NestedNamespaces && ro.FlattenPackages, RenderNestedNamespaces, "has no effect when FlattenPackages is true")
+	conflict(ro.MermaidNamespaces && ro.FlattenPackages, MermaidNamespaces, "has no effect when FlattenPackages is true")
+	conflict(ro.PackageLegends && ro.FlattenPackages, PackageLegends, "has no effect when FlattenPackages is true")
+	conflict(ro.ConstValues && !ro.Constants, ShowConstValues, "has no effect when RenderConstants is false")
+	conflict(ro.HideSelfReferences && !ro.Aggregations && !ro.Compositions,
+		HideSelfReferences, "has no effect when RenderAggregations and RenderCompositions are false")
+	conflict(ro.MaxSignatureWidth < 0, MaxSignatureWidth, "is negative, signatures are never wrapped")
+	conflict(ro.MaxMembers < 0, MaxMembers, "is negative, every member is rendered")
+	conflict(ro.IncludePattern != nil && ro.ExcludePattern != nil && ro.IncludePattern.String() == ro.ExcludePattern.String(),
+		ExcludePattern, "excludes every type matched by IncludePattern, nothing is rendered")
+	return result
+}
+
+var renderingOptionNames = map[RenderingOption]string{
+	RenderAggregations:          "RenderAggregations",
+	RenderCompositions:          "RenderCompositions",
+	RenderImplementations:       "RenderImplementations",
+	RenderAliases:               "RenderAliases",
+	RenderFields:                "RenderFields",
+	RenderMethods:               "RenderMethods",
+	RenderConnectionLabels:      "RenderConnectionLabels",
+	RenderTitle:                 "RenderTitle",
+	RenderNotes:                 "RenderNotes",
+	AggregatePrivateMembers:     "AggregatePrivateMembers",
+	RenderPrivateMembers:        "RenderPrivateMembers",
+	ColorSeed:                   "ColorSeed",
+	RenderConstants:             "RenderConstants",
+	RenderMemberOrder:           "RenderMemberOrder",
+	RenderEmbeddedAsComposition: "RenderEmbeddedAsComposition",
+	RenderMultiplicity:          "RenderMultiplicity",
+	RenderNestedNamespaces:      "RenderNestedNamespaces",
+	IncludePattern:              "IncludePattern",
+	ExcludePattern:              "ExcludePattern",
+	RenderDocComments:           "RenderDocComments",
+	ImportGraph:                 "ImportGraph",
+	HideEmptyClasses:            "HideEmptyClasses",
+	MermaidNamespaces:           "MermaidNamespaces",
+	ShowReceiverKind:            "ShowReceiverKind",
+	NoColor:                     "NoColor",
+	Stereotypes:                 "Stereotypes",
+	RenderPackageFunctions:      "RenderPackageFunctions",
+	HideEmptyMembers:            "HideEmptyMembers",
+	HideInterfaceMethods:        "HideInterfaceMethods",
+	HideConstructors:            "HideConstructors",
+	SmartRelationships:          "SmartRelationships",
+	MaxSignatureWidth:           "MaxSignatureWidth",
+	PlainText:                   "PlainText",
+	Theme:                       "Theme",
+	RenderFieldComments:         "RenderFieldComments",
+	Indent:                      "Indent",
+	RenderFieldNameLabels:       "RenderFieldNameLabels",
+	RenderBuiltinAggregations:   "RenderBuiltinAggregations",
+	FlattenPackages:             "FlattenPackages",
+	GroupBy:                     "GroupBy",
+	Connectors:                  "Connectors",
+	MarkdownFence:               "MarkdownFence",
+	PackageAliases:              "PackageAliases",
+	PackageLegends:              "PackageLegends",
+	ShowConstValues:             "ShowConstValues",
+	HideSelfReferences:          "HideSelfReferences",
+	RenderConstraintEdges:       "RenderConstraintEdges",
+	RenderPackageVars:           "RenderPackageVars",
+	MaxMembers:                  "MaxMembers",
+	QualifiedMemberTypes:        "QualifiedMemberTypes",
+}
+
+// String returns the name of the RenderingOption constant
+func (o RenderingOption) String() string {
+	if name, ok := renderingOptionNames[o]; ok {
+		return name
+	}
+	return fmt.Sprintf("RenderingOption(%d)", int(o))
+}
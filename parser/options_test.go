@@ -0,0 +1,442 @@
+package parser
+
+import (
+	"errors"
+	goparser "go/parser"
+	"go/token"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestApplyOptions(t *testing.T) {
+	parser := getEmptyParser("main")
+	err := parser.ApplyOptions(
+		WithAggregations(true),
+		WithFields(false),
+		WithTitle("Title"),
+		WithColorSeed(42),
+		WithMemberOrder(MemberOrderAlphabetical),
+		WithMultiplicity(true),
+		WithPlainText(true),
+		WithTheme("cerulean"),
+	)
+	if err != nil {
+		t.Fatalf("TestApplyOptions: expected no error, got %s", err.Error())
+	}
+	expected := &RenderingOptions{
+		Aggregations:    true,
+		Fields:          false,
+		Methods:         true,
+		Compositions:    true,
+		Implementations: true,
+		Aliases:         true,
+		PrivateMembers:  true,
+		Title:           "Title",
+		ColorSeed:       42,
+		MemberOrder:     MemberOrderAlphabetical,
+		Multiplicity:    true,
+		PlainText:       true,
+		Theme:           "cerulean",
+	}
+	if !reflect.DeepEqual(parser.RenderingOptions, expected) {
+		t.Errorf("TestApplyOptions: expected RenderingOptions to be %v got %v", expected, parser.RenderingOptions)
+	}
+	err = parser.ApplyOptions(WithMemberOrder("random"), WithTitle("Other"))
+	if err == nil {
+		t.Error("TestApplyOptions: expected error got nil")
+	}
+	if parser.RenderingOptions.Title != "Title" {
+		t.Errorf("TestApplyOptions: expected options after the failing one not to be applied, got title %s", parser.RenderingOptions.Title)
+	}
+}
+
+func TestSetRenderingOptionsInvalidValues(t *testing.T) {
+	tt := []struct {
+		name          string
+		option        RenderingOption
+		value         interface{}
+		expectedError string
+	}{
+		{
+			name:          "String for a bool option",
+			option:        RenderAggregations,
+			value:         "true",
+			expectedError: "option RenderAggregations expects bool, got string",
+		},
+		{
+			name:          "Bool for a string option",
+			option:        RenderTitle,
+			value:         true,
+			expectedError: "option RenderTitle expects string, got bool",
+		},
+		{
+			name:          "String for the color seed",
+			option:        ColorSeed,
+			value:         "42",
+			expectedError: "option ColorSeed expects int, got string",
+		},
+		{
+			name:          "Bool for the maximum signature width",
+			option:        MaxSignatureWidth,
+			value:         true,
+			expectedError: "option MaxSignatureWidth expects int, got bool",
+		},
+		{
+			name:          "String for the maximum number of members",
+			option:        MaxMembers,
+			value:         "10",
+			expectedError: "option MaxMembers expects int, got string",
+		},
+		{
+			name:          "String for the qualified member types",
+			option:        QualifiedMemberTypes,
+			value:         "true",
+			expectedError: "option QualifiedMemberTypes expects bool, got string",
+		},
+		{
+			name:          "String for the member order",
+			option:        RenderMemberOrder,
+			value:         "source",
+			expectedError: "option RenderMemberOrder expects MemberOrder, got string",
+		},
+		{
+			name:          "Map of bools for the stereotypes",
+			option:        Stereotypes,
+			value:         map[string]bool{"class": true},
+			expectedError: "option Stereotypes expects map[string]string, got map[string]bool",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			parser := getEmptyParser("main")
+			err := parser.SetRenderingOptions(map[RenderingOption]interface{}{
+				tc.option: tc.value,
+			})
+			var valueErr *InvalidOptionValueError
+			if !errors.As(err, &valueErr) {
+				t.Fatalf("Expected an InvalidOptionValueError, got %v", err)
+			}
+			if valueErr.Option != tc.option {
+				t.Errorf("Expected the error to identify option %s, got %s", tc.option, valueErr.Option)
+			}
+			if err.Error() != tc.expectedError {
+				t.Errorf("Expected error %q, got %q", tc.expectedError, err.Error())
+			}
+		})
+	}
+}
+
+func TestRenderingOptionString(t *testing.T) {
+	if RenderTitle.String() != "RenderTitle" {
+		t.Errorf("Expected RenderTitle, got %s", RenderTitle.String())
+	}
+	if RenderingOption(-1).String() != "RenderingOption(-1)" {
+		t.Errorf("Expected RenderingOption(-1), got %s", RenderingOption(-1).String())
+	}
+}
+
+func TestIncludeAndExcludePatterns(t *testing.T) {
+	parser := getEmptyParser("main")
+	parser.Structure["main"] = map[string]*Struct{
+		"UserService":  {},
+		"OrderService": {},
+		"Order":        {},
+	}
+	parser.Structure["main.internal"] = map[string]*Struct{
+		"CacheService": {},
+	}
+	err := parser.SetRenderingOptions(map[RenderingOption]interface{}{
+		IncludePattern: "Service$",
+		ExcludePattern: `\.internal\.|Order`,
+	})
+	if err != nil {
+		t.Fatalf("TestIncludeAndExcludePatterns: expected no error, got %s", err.Error())
+	}
+	included := parser.IncludedStructures("main")
+	if len(included) != 1 || included["UserService"] == nil {
+		t.Errorf("TestIncludeAndExcludePatterns: expected only UserService to be included, got %v", included)
+	}
+	if len(parser.IncludedStructures("main.internal")) != 0 {
+		t.Errorf("TestIncludeAndExcludePatterns: expected main.internal to be excluded")
+	}
+	if parser.RenderingOptions.IsIncluded("io.Reader") {
+		t.Errorf("TestIncludeAndExcludePatterns: expected io.Reader not to be included")
+	}
+	err = parser.ApplyOptions(WithIncludePattern(""), WithExcludePattern(""))
+	if err != nil {
+		t.Fatalf("TestIncludeAndExcludePatterns: expected no error, got %s", err.Error())
+	}
+	if len(parser.IncludedStructures("main")) != 3 {
+		t.Errorf("TestIncludeAndExcludePatterns: expected empty patterns to include every structure")
+	}
+	err = parser.ApplyOptions(WithIncludePattern("("))
+	if err == nil {
+		t.Error("TestIncludeAndExcludePatterns: expected error for an invalid pattern, got nil")
+	}
+}
+
+func TestHideEmptyClasses(t *testing.T) {
+	source := `package main
+
+type Empty struct {
+}
+
+type Base struct {
+}
+
+type Derived struct {
+	Base
+	Name string
+}
+
+type Doer interface {
+	Do()
+}
+
+type Impl struct {
+}
+
+func (i *Impl) Do() {
+}
+`
+	f, err := goparser.ParseFile(token.NewFileSet(), "empty.go", source, goparser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser := getEmptyParser("main")
+	parser.AllImports = make(map[string]string)
+	for _, d := range f.Decls {
+		parser.parseFileDeclarations(d)
+	}
+	parser.addImplementations()
+	tt := []struct {
+		name     string
+		options  []Option
+		expected []string
+	}{
+		{
+			name:     "Disabled",
+			options:  []Option{WithHideEmptyClasses(false)},
+			expected: []string{"Base", "Derived", "Doer", "Empty", "Impl"},
+		},
+		{
+			name:     "Composition and implementation targets are kept",
+			options:  []Option{WithHideEmptyClasses(true), WithMethods(false), WithEmbeddedAsComposition(true)},
+			expected: []string{"Base", "Derived", "Doer", "Impl"},
+		},
+		{
+			name:     "Unconnected types are hidden",
+			options:  []Option{WithHideEmptyClasses(true), WithMethods(false), WithCompositions(false), WithImplementations(false)},
+			expected: []string{"Derived"},
+		},
+		{
+			name:     "Types with methods are kept",
+			options:  []Option{WithHideEmptyClasses(true), WithMethods(true), WithCompositions(false), WithImplementations(false)},
+			expected: []string{"Derived", "Doer", "Impl"},
+		},
+		{
+			name:     "Interfaces with hidden methods are hidden",
+			options:  []Option{WithHideEmptyClasses(true), WithMethods(true), WithHideInterfaceMethods(true), WithCompositions(false), WithImplementations(false)},
+			expected: []string{"Derived", "Impl"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := parser.ApplyOptions(tc.options...)
+			if err != nil {
+				t.Fatalf("Expected no error, got %s", err.Error())
+			}
+			included := parser.IncludedStructures("main")
+			var result []string
+			for name := range included {
+				result = append(result, name)
+			}
+			sort.Strings(result)
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("Expected %v to be included, got %v", tc.expected, result)
+			}
+			if all := parser.AllIncludedStructures(); !reflect.DeepEqual(all["main"], included) {
+				t.Errorf("Expected all the included structures of main to be %v, got %v", included, all["main"])
+			}
+		})
+	}
+}
+
+func TestStereotypes(t *testing.T) {
+	parser := getEmptyParser("main")
+	stereotypes := map[string]string{
+		"class":     "<< (C,Gold) >>",
+		"interface": "<< (I,Orchid) >>",
+	}
+	err := parser.SetRenderingOptions(map[RenderingOption]interface{}{
+		Stereotypes: stereotypes,
+	})
+	if err != nil {
+		t.Fatalf("TestStereotypes: expected no error, got %s", err.Error())
+	}
+	if !reflect.DeepEqual(parser.RenderingOptions.Stereotypes, stereotypes) {
+		t.Errorf("TestStereotypes: expected stereotypes to be %v, got %v", stereotypes, parser.RenderingOptions.Stereotypes)
+	}
+	stereotypes["alias"] = "<< (A,Gray) >>"
+	if _, ok := parser.RenderingOptions.Stereotypes["alias"]; ok {
+		t.Error("TestStereotypes: expected the stereotypes to be copied")
+	}
+	err = parser.ApplyOptions(WithStereotypes(map[string]string{"struct": "<< (S,Gold) >>"}))
+	if err == nil {
+		t.Error("TestStereotypes: expected error for an unknown type, got nil")
+	}
+}
+
+func TestConnectors(t *testing.T) {
+	parser := getEmptyParser("main")
+	connectors := map[string]string{
+		"extends": "<|..",
+		"alias":   "..",
+	}
+	err := parser.SetRenderingOptions(map[RenderingOption]interface{}{
+		Connectors: connectors,
+	})
+	if err != nil {
+		t.Fatalf("TestConnectors: expected no error, got %s", err.Error())
+	}
+	if !reflect.DeepEqual(parser.RenderingOptions.Connectors, connectors) {
+		t.Errorf("TestConnectors: expected connectors to be %v, got %v", connectors, parser.RenderingOptions.Connectors)
+	}
+	err = parser.ApplyOptions(WithConnectors(map[string]string{"realization": "<|.."}))
+	if err != nil {
+		t.Fatalf("TestConnectors: expected no error, got %s", err.Error())
+	}
+	if parser.RenderingOptions.Connectors["realization"] != "<|.." {
+		t.Errorf("TestConnectors: expected the realization connector to be <|.., got %v", parser.RenderingOptions.Connectors)
+	}
+	err = parser.ApplyOptions(WithConnectors(map[string]string{"inheritance": "<|.."}))
+	if err == nil {
+		t.Error("TestConnectors: expected error for an unknown kind, got nil")
+	}
+	err = parser.ApplyOptions(WithConnectors(map[string]string{"extends": "<| .."}))
+	if err == nil {
+		t.Error("TestConnectors: expected error for an arrow with a space, got nil")
+	}
+}
+
+func TestRenderedPackageFunctions(t *testing.T) {
+	parser := getEmptyParser("main")
+	parser.PackageFunctions = map[string][]*Function{
+		"main": {{Name: "New"}, {Name: "NewReader"}, {Name: "Newest"}, {Name: "newFoo"}, {Name: "Do"}},
+	}
+	tt := []struct {
+		name     string
+		options  []Option
+		expected []string
+	}{
+		{
+			name:     "Not rendered",
+			options:  []Option{WithPackageFunctions(false)},
+			expected: nil,
+		},
+		{
+			name:     "Rendered",
+			options:  []Option{WithPackageFunctions(true), WithHideConstructors(false)},
+			expected: []string{"New", "NewReader", "Newest", "newFoo", "Do"},
+		},
+		{
+			name:     "Constructors are hidden",
+			options:  []Option{WithPackageFunctions(true), WithHideConstructors(true)},
+			expected: []string{"Newest", "newFoo", "Do"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := parser.ApplyOptions(tc.options...)
+			if err != nil {
+				t.Fatalf("Expected no error, got %s", err.Error())
+			}
+			var result []string
+			for _, function := range parser.RenderedPackageFunctions("main") {
+				result = append(result, function.Name)
+			}
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("Expected %v to be rendered, got %v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestValidateRenderingOptions(t *testing.T) {
+	tt := []struct {
+		name     string
+		options  []Option
+		expected []RenderingOption
+	}{
+		{
+			name:     "Default options",
+			options:  nil,
+			expected: nil,
+		},
+		{
+			name:     "Aggregation options without aggregations",
+			options:  []Option{WithAggregations(false), WithAggregatePrivateMembers(true), WithMultiplicity(true), WithSmartRelationships(true)},
+			expected: []RenderingOption{AggregatePrivateMembers, RenderMultiplicity, SmartRelationships},
+		},
+		{
+			name:     "Aggregation options with aggregations",
+			options:  []Option{WithAggregations(true), WithAggregatePrivateMembers(true), WithMultiplicity(true), WithSmartRelationships(true)},
+			expected: nil,
+		},
+		{
+			name:     "Labels without connections",
+			options:  []Option{WithConnectionLabels(true), WithCompositions(false), WithImplementations(false), WithAliases(false)},
+			expected: []RenderingOption{RenderConnectionLabels},
+		},
+		{
+			name:     "Method options without methods",
+			options:  []Option{WithMethods(false), WithHideInterfaceMethods(true), WithReceiverKind(true)},
+			expected: []RenderingOption{HideInterfaceMethods, ShowReceiverKind},
+		},
+		{
+			name:     "Constructors without package functions",
+			options:  []Option{WithHideConstructors(true)},
+			expected: []RenderingOption{HideConstructors},
+		},
+		{
+			name:     "Negative signature width",
+			options:  []Option{WithMaxSignatureWidth(-1)},
+			expected: []RenderingOption{MaxSignatureWidth},
+		},
+		{
+			name:     "Negative number of members",
+			options:  []Option{WithMaxMembers(-1)},
+			expected: []RenderingOption{MaxMembers},
+		},
+		{
+			name:     "Same include and exclude patterns",
+			options:  []Option{WithIncludePattern("^main"), WithExcludePattern("^main")},
+			expected: []RenderingOption{ExcludePattern},
+		},
+		{
+			name:     "Self references without aggregations nor compositions",
+			options:  []Option{WithAggregations(false), WithCompositions(false), WithHideSelfReferences(true)},
+			expected: []RenderingOption{HideSelfReferences},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			parser := getEmptyParser("main")
+			if err := parser.ApplyOptions(tc.options...); err != nil {
+				t.Fatalf("Expected no error, got %s", err.Error())
+			}
+			var result []RenderingOption
+			for _, err := range parser.RenderingOptions.Validate() {
+				var conflict *OptionConflictError
+				if !errors.As(err, &conflict) {
+					t.Fatalf("Expected an OptionConflictError, got %v", err)
+				}
+				result = append(result, conflict.Option)
+			}
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("Expected conflicts for %v, got %v", tc.expected, result)
+			}
+		})
+	}
+}
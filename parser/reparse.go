@@ -0,0 +1,220 @@
+package parser
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// ReparseFile parses the file with the given path again, so a file watcher can keep the diagram up to date without
+// parsing the whole tree every time a file changes. The types, methods, constants and package functions parsed from
+// the file before are removed and the ones it declares now are added. A file that does not exist anymore is only
+// removed. The implementations are only matched again for the structs and interfaces declared in the file, for the
+// structs that have methods in it and for the structs embedding any of them. The path must be given the way the file
+// was found when the tree was parsed, that is joined to the directory it was parsed from. Imports are never removed
+// since the other files of the package can share them
+func (p *ClassParser) ReparseFile(path string) error {
+	path = filepath.Clean(path)
+	f, err := p.parseChangedFile(path)
+	if err != nil {
+		return err
+	}
+	oldStructs, oldInterfaces := p.declarationsIn(path)
+	p.removeFile(path)
+	for s := range p.AllStructs {
+		if st := p.getStruct(s); st != nil {
+			for i := range oldInterfaces {
+				delete(st.Extends, i)
+			}
+		}
+	}
+	if f != nil {
+		directoryPath := filepath.Dir(path)
+		p.parsePackage(&ast.Package{
+			Name:  f.Name.Name,
+			Files: map[string]*ast.File{path: f},
+		}, p.getPackageBase(directoryPath))
+		if !strings.HasSuffix(p.CurrentPackageName, "_test") {
+			p.addPackagePath(directoryPath, p.CurrentPackageName)
+		}
+	}
+	newStructs, newInterfaces := p.declarationsIn(path)
+	for s := range newStructs {
+		oldStructs[s] = struct{}{}
+	}
+	// The structs that changed, and the ones embedding them, match every interface again, while the structs that did
+	// not only need to match the interfaces declared in the file
+	changed := map[string]struct{}{}
+	for s := range p.embedders(oldStructs) {
+		if _, ok := p.AllStructs[s]; !ok {
+			continue
+		}
+		if st := p.getStruct(s); st != nil {
+			st.Extends = make(map[string]struct{})
+			changed[s] = struct{}{}
+		}
+	}
+	p.addImplementationsOf(changed, p.allInterfaces())
+	p.addImplementationsOf(p.AllStructs, p.interfaces(newInterfaces))
+	return nil
+}
+
+// parseChangedFile returns the syntax tree of the file with the given path, or nil when the file does not exist or is
+// left out of the diagram, because it is a test file or because it does not match the build constraints
+func (p *ClassParser) parseChangedFile(path string) (*ast.File, error) {
+	if _, err := p.fileSystem().Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if !p.includeTests && strings.HasSuffix(path, "_test.go") {
+		return nil, nil
+	}
+	if p.buildContext != nil {
+		match, err := p.buildContext.MatchFile(filepath.Dir(path), filepath.Base(path))
+		if err != nil {
+			return nil, err
+		}
+		if !match {
+			return nil, nil
+		}
+	}
+	src, err := afero.ReadFile(p.fileSystem(), path)
+	if err != nil {
+		return nil, err
+	}
+	return parser.ParseFile(token.NewFileSet(), path, src, parser.ParseComments)
+}
+
+// declarationsIn returns the full names of the structures that are not interfaces and were declared or have methods in
+// the file with the given path, along with the full names of the interfaces declared in it
+func (p *ClassParser) declarationsIn(path string) (map[string]struct{}, map[string]struct{}) {
+	structs := map[string]struct{}{}
+	interfaces := map[string]struct{}{}
+	for pack, structures := range p.Structure {
+		for name, st := range structures {
+			fullName := qualifiedName(pack, name)
+			if st.Type == "interface" {
+				if st.DefinedIn == path {
+					interfaces[fullName] = struct{}{}
+				}
+				continue
+			}
+			if st.DefinedIn == path {
+				structs[fullName] = struct{}{}
+				continue
+			}
+			for _, function := range st.Functions {
+				if function.DefinedIn == path {
+					structs[fullName] = struct{}{}
+					break
+				}
+			}
+		}
+	}
+	return structs, interfaces
+}
+
+// removeFile removes everything that was parsed from the file with the given path. The structures declared in the file
+// keep the methods and constants declared in other files, as if they had been parsed without their declaration, and
+// the structures and packages left empty are removed
+func (p *ClassParser) removeFile(path string) {
+	for pack, structures := range p.Structure {
+		removed := false
+		for name, st := range structures {
+			functions := make([]*Function, 0, len(st.Functions))
+			for _, function := range st.Functions {
+				if function.DefinedIn != path {
+					functions = append(functions, function)
+				}
+			}
+			var constants []*Field
+			for _, constant := range st.Constants {
+				if constant.DefinedIn != path {
+					constants = append(constants, constant)
+				}
+			}
+			removed = removed || len(functions) != len(st.Functions) || len(constants) != len(st.Constants)
+			st.Functions = functions
+			st.Constants = constants
+			if st.DefinedIn == path {
+				p.removeDeclaration(pack, name, st)
+				removed = true
+			}
+			if st.DefinedIn == "" && len(st.Functions) == 0 && len(st.Constants) == 0 {
+				delete(structures, name)
+				delete(p.AllStructs, qualifiedName(pack, name))
+			}
+		}
+		functions := make([]*Function, 0, len(p.PackageFunctions[pack]))
+		for _, function := range p.PackageFunctions[pack] {
+			if function.DefinedIn != path {
+				functions = append(functions, function)
+			}
+		}
+		if len(functions) != len(p.PackageFunctions[pack]) {
+			p.PackageFunctions[pack] = functions
+			removed = true
+		}
+		variables := make([]*Variable, 0, len(p.PackageVars[pack]))
+		for _, variable := range p.PackageVars[pack] {
+			if variable.DefinedIn != path {
+				variables = append(variables, variable)
+			}
+		}
+		if len(variables) != len(p.PackageVars[pack]) {
+			p.PackageVars[pack] = variables
+			removed = true
+		}
+		if removed && len(structures) == 0 && len(functions) == 0 && len(variables) == 0 {
+			delete(p.Structure, pack)
+			delete(p.PackageFunctions, pack)
+			delete(p.PackageVars, pack)
+		}
+	}
+}
+
+// removeDeclaration removes the type declaration of the given structure, which is left with the methods and the
+// constants declared in other files
+func (p *ClassParser) removeDeclaration(pack string, name string, st *Struct) {
+	fullName := qualifiedName(pack, name)
+	delete(p.AllStructs, fullName)
+	delete(p.AllInterfaces, fullName)
+	if alias, ok := p.AllAliases[name]; ok && alias.PackageName == pack {
+		delete(p.AllAliases, name)
+		p.removeRenamedStruct(alias)
+	}
+	*st = Struct{
+		PackageName:         st.PackageName,
+		Functions:           st.Functions,
+		Fields:              make([]*Field, 0),
+		Constants:           st.Constants,
+		Composition:         make(map[string]struct{}),
+		Extends:             make(map[string]struct{}),
+		Aggregations:        make(map[string]struct{}),
+		PrivateAggregations: make(map[string]struct{}),
+	}
+	if len(st.Functions) > 0 {
+		// The methods still create the class, as they do when they are parsed before the declaration
+		st.Type = "class"
+	}
+}
+
+// removeRenamedStruct removes the renamed struct recorded for the given alias, unless another alias still refers to it
+func (p *ClassParser) removeRenamedStruct(alias *Alias) {
+	if strings.Count(alias.Name, ".") <= 1 {
+		return
+	}
+	for _, other := range p.AllAliases {
+		if other.Name == alias.Name {
+			return
+		}
+	}
+	pack := strings.SplitN(alias.Name, ".", 2)
+	delete(p.AllRenamedStructs[pack[0]], GenerateRenamedStructName(pack[1]))
+}
@@ -0,0 +1,137 @@
+package parser
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReparseFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reparse")
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+	write := func(name string, src string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatalf("Expected no errors, got %s", err.Error())
+		}
+	}
+	write("shape.go", `package shapes
+
+type Shape interface {
+	Area() float64
+}
+
+type Square struct {
+	Side float64
+}
+`)
+	write("square.go", `package shapes
+
+func (s *Square) Area() float64 {
+	return s.Side * s.Side
+}
+
+type Color int
+
+const (
+	Red Color = iota
+	Green
+)
+`)
+	parser := getEmptyParser("main")
+	parser.AllImports = make(map[string]string)
+	parser.RenderingOptions.ModuleBase = filepath.Base(dir)
+	if err := parser.parseDirectory(dir); err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	parser.addImplementations()
+	pack := parser.CurrentPackageName
+	square := parser.Structure[pack]["Square"]
+	if square.DefinedIn != filepath.Join(dir, "shape.go") {
+		t.Errorf("Expected Square to be defined in shape.go, got %s", square.DefinedIn)
+	}
+	if _, ok := square.Extends[pack+".Shape"]; !ok {
+		t.Fatalf("Expected Square to implement Shape before the file changed")
+	}
+
+	// The method moves to the declaration and the constants are removed
+	write("square.go", `package shapes
+
+type Circle struct {
+	Radius float64
+}
+
+func (c Circle) Area() float64 {
+	return 3 * c.Radius * c.Radius
+}
+`)
+	if err := parser.ReparseFile(filepath.Join(dir, "square.go")); err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	if len(square.Functions) != 0 {
+		t.Errorf("Expected the methods of Square to be removed, got %v", square.MethodNames())
+	}
+	if _, ok := square.Extends[pack+".Shape"]; ok {
+		t.Errorf("Expected Square to stop implementing Shape")
+	}
+	circle, ok := parser.Structure[pack]["Circle"]
+	if !ok {
+		t.Fatalf("Expected Circle to be added")
+	}
+	if _, ok := circle.Extends[pack+".Shape"]; !ok {
+		t.Errorf("Expected Circle to implement Shape")
+	}
+	if _, ok := parser.Structure[pack][pack+".Color"]; ok {
+		t.Errorf("Expected Color to be removed")
+	}
+	if _, ok := parser.AllAliases[pack+".Color"]; ok {
+		t.Errorf("Expected the alias of Color to be removed")
+	}
+
+	// The interface is removed while Square keeps its fields
+	write("shape.go", `package shapes
+
+type Square struct {
+	Side float64
+}
+`)
+	if err := parser.ReparseFile(filepath.Join(dir, "shape.go")); err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	if _, ok := parser.AllInterfaces[pack+".Shape"]; ok {
+		t.Errorf("Expected Shape to be removed")
+	}
+	if _, ok := circle.Extends[pack+".Shape"]; ok {
+		t.Errorf("Expected Circle to stop implementing the removed Shape")
+	}
+	square = parser.Structure[pack]["Square"]
+	if len(square.Fields) != 1 || square.Type != "class" {
+		t.Errorf("Expected Square to be parsed again, got %s", square)
+	}
+
+	// Removing a file removes what it declared
+	if err := os.Remove(filepath.Join(dir, "square.go")); err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	if err := parser.ReparseFile(filepath.Join(dir, "square.go")); err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	if _, ok := parser.Structure[pack]["Circle"]; ok {
+		t.Errorf("Expected Circle to be removed with its file")
+	}
+	if _, ok := parser.AllStructs[pack+".Circle"]; ok {
+		t.Errorf("Expected Circle to be removed from the structs")
+	}
+
+	// A file that cannot be parsed leaves the diagram as it is
+	write("shape.go", "package shapes\n\ntype Square struct {")
+	if err := parser.ReparseFile(filepath.Join(dir, "shape.go")); err == nil {
+		t.Errorf("Expected an error for a file that cannot be parsed")
+	}
+	if _, ok := parser.Structure[pack]["Square"]; !ok {
+		t.Errorf("Expected Square to be kept when the file cannot be parsed")
+	}
+}
@@ -0,0 +1,63 @@
+package parser
+
+// Stats holds structural metrics of the parsed structures, such as the ones computed by ClassParser.Stats
+type Stats struct {
+	// TypesPerPackage holds the number of structures parsed in each package
+	TypesPerPackage map[string]int
+	// Structs is the number of structures of Type "class"
+	Structs int
+	// Interfaces is the number of structures of Type "interface"
+	Interfaces int
+	// AverageMethodsPerStruct is the number of methods of the structures of Type "class" divided by Structs. It is 0
+	// when no struct was parsed
+	AverageMethodsPerStruct float64
+	// FanIn holds the number of parsed structures aggregating each parsed structure, private aggregations included,
+	// keyed by its fully qualified name
+	FanIn map[string]int
+	// FanOut holds the number of parsed structures each parsed structure aggregates, private aggregations included,
+	// keyed by its fully qualified name
+	FanOut map[string]int
+}
+
+// Stats returns the structural metrics of the parsed structures. The aggregations of the types that were not parsed
+// are left out of FanIn and FanOut, which hold every parsed structure even when it is not connected to any other
+func (p *ClassParser) Stats() Stats {
+	stats := Stats{
+		TypesPerPackage: make(map[string]int, len(p.Structure)),
+		FanIn:           make(map[string]int),
+		FanOut:          make(map[string]int),
+	}
+	methods := 0
+	for pack, structures := range p.Structure {
+		stats.TypesPerPackage[pack] = len(structures)
+		for name, st := range structures {
+			fullName := qualifiedName(pack, name)
+			switch st.Type {
+			case "class":
+				stats.Structs++
+				methods += len(st.Functions)
+			case "interface":
+				stats.Interfaces++
+			}
+			if _, ok := stats.FanIn[fullName]; !ok {
+				stats.FanIn[fullName] = 0
+			}
+			aggregated := map[string]struct{}{}
+			for _, targets := range []map[string]struct{}{st.Aggregations, st.PrivateAggregations} {
+				for target := range targets {
+					if resolved, ok := p.ResolveType(target, st); ok && p.Lookup(resolved) != nil {
+						aggregated[resolved] = struct{}{}
+					}
+				}
+			}
+			stats.FanOut[fullName] = len(aggregated)
+			for target := range aggregated {
+				stats.FanIn[target]++
+			}
+		}
+	}
+	if stats.Structs > 0 {
+		stats.AverageMethodsPerStruct = float64(methods) / float64(stats.Structs)
+	}
+	return stats
+}
@@ -0,0 +1,56 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	parser := getEmptyParser("main")
+	parser.AllImports = make(map[string]string)
+	err := parser.ParseSource("shop/shop.go", []byte(`package shop
+
+type Service interface {
+	Place(o *Order)
+}
+
+type OrderService struct {
+	repository *Repository
+	audit      *Repository
+}
+
+func (s *OrderService) Place(o *Order) {}
+
+func (s *OrderService) Cancel(o *Order) {}
+
+type Repository struct {
+	Orders []*Order
+}
+
+type Order struct {
+	Owner string
+}
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	pack := parser.CurrentPackageName
+	stats := parser.Stats()
+	if stats.TypesPerPackage[pack] != 4 {
+		t.Errorf("Expected 4 types in %s, got %d", pack, stats.TypesPerPackage[pack])
+	}
+	if stats.Structs != 3 || stats.Interfaces != 1 {
+		t.Errorf("Expected 3 structs and 1 interface, got %d structs and %d interfaces", stats.Structs, stats.Interfaces)
+	}
+	if stats.AverageMethodsPerStruct != 2.0/3.0 {
+		t.Errorf("Expected AverageMethodsPerStruct to be %f, got %f", 2.0/3.0, stats.AverageMethodsPerStruct)
+	}
+	expectedFanIn := map[string]int{pack + ".Service": 0, pack + ".OrderService": 0, pack + ".Repository": 1, pack + ".Order": 1}
+	if !reflect.DeepEqual(stats.FanIn, expectedFanIn) {
+		t.Errorf("Expected FanIn to be %v, got %v", expectedFanIn, stats.FanIn)
+	}
+	expectedFanOut := map[string]int{pack + ".Service": 0, pack + ".OrderService": 1, pack + ".Repository": 1, pack + ".Order": 0}
+	if !reflect.DeepEqual(stats.FanOut, expectedFanOut) {
+		t.Errorf("Expected FanOut to be %v, got %v", expectedFanOut, stats.FanOut)
+	}
+}
@@ -0,0 +1,72 @@
+package parser
+
+import (
+	"fmt"
+	"go/ast"
+	goparser "go/parser"
+	"sort"
+)
+
+// stdInterfaceSources holds the well-known interfaces of the standard library that can be matched against the parsed
+// structs, declared as they are in their packages. Their methods only use builtin types, so they can be compared with
+// the methods of any struct
+var stdInterfaceSources = map[string]string{
+	"error":                    "interface{ Error() string }",
+	"fmt.Stringer":             "interface{ String() string }",
+	"fmt.GoStringer":           "interface{ GoString() string }",
+	"io.Reader":                "interface{ Read(p []byte) (n int, err error) }",
+	"io.Writer":                "interface{ Write(p []byte) (n int, err error) }",
+	"io.Closer":                "interface{ Close() error }",
+	"io.ReadWriter":            "interface{ Read(p []byte) (n int, err error); Write(p []byte) (n int, err error) }",
+	"io.ReadCloser":            "interface{ Read(p []byte) (n int, err error); Close() error }",
+	"io.WriteCloser":           "interface{ Write(p []byte) (n int, err error); Close() error }",
+	"io.ByteReader":            "interface{ ReadByte() (byte, error) }",
+	"io.ByteWriter":            "interface{ WriteByte(c byte) error }",
+	"io.StringWriter":          "interface{ WriteString(s string) (n int, err error) }",
+	"sort.Interface":           "interface{ Len() int; Less(i, j int) bool; Swap(i, j int) }",
+	"encoding.TextMarshaler":   "interface{ MarshalText() (text []byte, err error) }",
+	"encoding.TextUnmarshaler": "interface{ UnmarshalText(text []byte) error }",
+	"json.Marshaler":           "interface{ MarshalJSON() ([]byte, error) }",
+	"json.Unmarshaler":         "interface{ UnmarshalJSON([]byte) error }",
+}
+
+// StdInterfaceNames returns the sorted names of the standard interfaces that can be given in
+// ClassDiagramOptions.StdInterfaces
+func StdInterfaceNames() []string {
+	names := make([]string, 0, len(stdInterfaceSources))
+	for name := range stdInterfaceSources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getStdInterfaces returns the standard interfaces with the given names, keyed by the name they are connected with.
+// error belongs to the builtin package. It fails for the names that are not in StdInterfaceNames
+func getStdInterfaces(names []string) (map[string]*Struct, error) {
+	result := make(map[string]*Struct, len(names))
+	for _, name := range names {
+		source, ok := stdInterfaceSources[name]
+		if !ok {
+			return nil, fmt.Errorf("Unknown standard interface %s", name)
+		}
+		expr, err := goparser.ParseExpr(source)
+		if err != nil {
+			return nil, err
+		}
+		inter := &Struct{
+			Type:        "interface",
+			Functions:   make([]*Function, 0),
+			Composition: make(map[string]struct{}),
+			Extends:     make(map[string]struct{}),
+		}
+		for _, method := range expr.(*ast.InterfaceType).Methods.List {
+			inter.AddMethod(method, map[string]string{})
+		}
+		if name == "error" {
+			name = fmt.Sprintf("%s.%s", BuiltinPackageName, name)
+		}
+		result[name] = inter
+	}
+	return result, nil
+}
@@ -1,8 +1,11 @@
 package parser
 
 import (
+	"fmt"
 	"go/ast"
-	"unicode"
+	"go/token"
+	"sort"
+	"strings"
 )
 
 //Struct represent a struct in golang, it can be of Type "class" or "interface" and can be associated
@@ -11,11 +14,56 @@ type Struct struct {
 	PackageName         string
 	Functions           []*Function
 	Fields              []*Field
+	TypeParameters      []*Field
+	Constants           []*Field
 	Type                string
 	Composition         map[string]struct{}
 	Extends             map[string]struct{}
 	Aggregations        map[string]struct{}
 	PrivateAggregations map[string]struct{}
+	// Multiplicities holds the multiplicity of the aggregated types that are held in a slice, an array or a map
+	Multiplicities map[string]string
+	// ValueAggregations holds the aggregated types that at least one field holds by value, instead of through a pointer
+	// or a channel
+	ValueAggregations map[string]struct{}
+	// Doc is the text of the doc comment of the type declaration
+	Doc string
+	// Note is the text of the //plantuml:note directive of the type declaration, rendered as a note attached to it
+	Note string
+	// Signature is the function type of the structures of Type "func", such as func(http.ResponseWriter, *http.Request)
+	// for type HandlerFunc func(http.ResponseWriter, *http.Request). It is named func
+	Signature *Function
+	// TypeSet holds the type set elements of a constraint interface, such as ~int | ~string, in the order they are
+	// declared
+	TypeSet []string
+	// DefinedIn is the path of the file the type was declared in. It is empty for the types whose methods were parsed
+	// without their declaration
+	DefinedIn string
+	// AggregationFields holds the names of the fields each type of Aggregations and PrivateAggregations is aggregated
+	// by, in the order they are declared
+	AggregationFields map[string][]string
+	// CompositionFields holds the names of the embedded fields each type of Composition is composed by
+	CompositionFields map[string][]string
+	// BuiltinAggregations holds the names of the fields each builtin type, such as string or int, is aggregated by. They
+	// are kept apart from Aggregations since they are only rendered with the BuiltinAggregations option
+	BuiltinAggregations map[string][]string
+}
+
+// The relationships of a structure with the entries of its Extends
+const (
+	// Generalization is the relationship of an interface with the interfaces it embeds
+	Generalization = "generalization"
+	// Realization is the relationship of a struct, or of any other type with methods, with the interfaces it implements
+	Realization = "realization"
+)
+
+// ExtendsRelationship returns the relationship of the structure with every entry of its Extends. Interfaces only extend
+// the interfaces they embed, while the other structures extend the interfaces they implement
+func (st *Struct) ExtendsRelationship() string {
+	if st.Type == "interface" {
+		return Generalization
+	}
+	return Realization
 }
 
 // ImplementsInterface returns true if the struct st conforms ot the given interface
@@ -69,6 +117,92 @@ func (st *Struct) AddToAggregation(fType string) {
 	st.Aggregations[fType] = struct{}{}
 }
 
+//addBuiltinAggregation records the name of a field that aggregates the given builtin type
+func (st *Struct) addBuiltinAggregation(fType string, name string) {
+	if st.BuiltinAggregations == nil {
+		st.BuiltinAggregations = make(map[string][]string)
+	}
+	st.BuiltinAggregations[fType] = append(st.BuiltinAggregations[fType], name)
+}
+
+// AggregatedBuiltinTypes returns the builtin types aggregated by the fields of the struct, sorted by name. The types
+// that are only aggregated by unexported fields are left out unless private is true
+func (st *Struct) AggregatedBuiltinTypes(private bool) []string {
+	var result []string
+	for t := range st.BuiltinAggregations {
+		if len(st.AggregatingFields(t, private)) > 0 {
+			result = append(result, t)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+//addMultiplicity records the multiplicity of an aggregated type. The first multiplicity recorded for a type is kept
+func (st *Struct) addMultiplicity(fType string, multiplicity string) {
+	if multiplicity == "" {
+		return
+	}
+	if st.Multiplicities == nil {
+		st.Multiplicities = make(map[string]string)
+	}
+	if _, ok := st.Multiplicities[fType]; !ok {
+		st.Multiplicities[fType] = multiplicity
+	}
+}
+
+//addAggregationField records the name of a field that aggregates the given type
+func (st *Struct) addAggregationField(fType string, name string) {
+	if st.AggregationFields == nil {
+		st.AggregationFields = make(map[string][]string)
+	}
+	st.AggregationFields[fType] = append(st.AggregationFields[fType], name)
+}
+
+//addCompositionField records the name of an embedded field that composes the given type. The type is stored the same
+//way AddToComposition stores it
+func (st *Struct) addCompositionField(fType string, name string) {
+	if len(fType) == 0 {
+		return
+	}
+	if fType[0] == "*"[0] {
+		fType = fType[1:]
+	}
+	if st.CompositionFields == nil {
+		st.CompositionFields = make(map[string][]string)
+	}
+	st.CompositionFields[fType] = append(st.CompositionFields[fType], name)
+}
+
+// AggregatingFields returns the names of the fields that aggregate the given type, in the order they are declared. The
+// unexported fields are left out unless private is true
+func (st *Struct) AggregatingFields(aggregated string, private bool) []string {
+	var result []string
+	for _, fields := range [][]string{st.AggregationFields[aggregated], st.BuiltinAggregations[aggregated]} {
+		for _, name := range fields {
+			if private || token.IsExported(name) {
+				result = append(result, name)
+			}
+		}
+	}
+	return result
+}
+
+//addValueAggregation records that an aggregated type is held by value
+func (st *Struct) addValueAggregation(fType string) {
+	if st.ValueAggregations == nil {
+		st.ValueAggregations = make(map[string]struct{})
+	}
+	st.ValueAggregations[fType] = struct{}{}
+}
+
+// HoldsByValue returns true when at least one field of the struct holds the given aggregated type by value, instead of
+// through a pointer or a channel
+func (st *Struct) HoldsByValue(aggregated string) bool {
+	_, ok := st.ValueAggregations[aggregated]
+	return ok
+}
+
 //addToPrivateAggregation adds an aggregation type to the list of aggregations for private members
 func (st *Struct) addToPrivateAggregation(fType string) {
 	st.PrivateAggregations[fType] = struct{}{}
@@ -78,37 +212,138 @@ func (st *Struct) addToPrivateAggregation(fType string) {
 //needed information
 func (st *Struct) AddField(field *ast.Field, aliases map[string]string, packageName string) {
 	theType, fundamentalTypes := getFieldType(field.Type, aliases, packageName)
+	typeParameters := getTypeParameterNames(st.TypeParameters)
+	theType = replaceTypeParameters(theType, typeParameters)
+	fullType := replacePackageConstant(theType, st.PackageName)
 	theType = replacePackageConstant(theType, "")
 	if field.Names != nil {
-		theType = replacePackageConstant(theType, "")
 		newField := &Field{
-			Name: field.Names[0].Name,
-			Type: theType,
+			Name:     field.Names[0].Name,
+			Type:     theType,
+			FullType: fullType,
+			Pos:      field.Names[0].Pos(),
+			Comment:  getFieldComment(field),
 		}
 		st.Fields = append(st.Fields, newField)
-		if unicode.IsUpper(rune(newField.Name[0])) {
-			for _, t := range fundamentalTypes {
-				st.AddToAggregation(replacePackageConstant(t, st.PackageName))
+		multiplicity := getMultiplicity(field.Type)
+		valueTypes := map[string]struct{}{}
+		for _, t := range getValueTypes(field.Type, aliases, packageName) {
+			valueTypes[t] = struct{}{}
+		}
+		for _, t := range fundamentalTypes {
+			if replaceTypeParameters(t, typeParameters) != t {
+				// Type parameters are not real types, so there is nothing to aggregate
+				continue
 			}
-		} else {
-			for _, t := range fundamentalTypes {
-				st.addToPrivateAggregation(replacePackageConstant(t, st.PackageName))
+			aggregated := replacePackageConstant(t, st.PackageName)
+			if newField.IsExported() {
+				st.AddToAggregation(aggregated)
+			} else {
+				st.addToPrivateAggregation(aggregated)
+			}
+			st.addMultiplicity(aggregated, multiplicity)
+			st.addAggregationField(aggregated, newField.Name)
+			if _, ok := valueTypes[t]; ok {
+				st.addValueAggregation(aggregated)
 			}
 		}
-	} else if field.Type != nil {
-		if theType[0] == "*"[0] {
-			theType = theType[1:]
+		for _, t := range getBuiltinTypes(field.Type) {
+			st.addBuiltinAggregation(t, newField.Name)
 		}
-		st.AddToComposition(theType)
+	} else if field.Type != nil {
+		// Embedded fields are kept as fields as well, so they can be rendered either as fields or as compositions
+		st.Fields = append(st.Fields, &Field{
+			Name:     getEmbeddedFieldName(theType),
+			Type:     theType,
+			FullType: fullType,
+			Pos:      field.Type.Pos(),
+			Embedded: true,
+			Comment:  getFieldComment(field),
+		})
+		st.AddToComposition(fullType)
+		st.addCompositionField(fullType, getEmbeddedFieldName(theType))
 	}
 }
 
+//AddConstant adds a constant of the given type to this Structure. Constants are rendered as the values of an enumeration
+func (st *Struct) AddConstant(name string, constType string) {
+	st.Constants = append(st.Constants, &Field{
+		Name: name,
+		Type: constType,
+	})
+}
+
 //AddMethod Parse the Field and if it is an ast.FuncType, then add the methods into the Structure
 func (st *Struct) AddMethod(method *ast.Field, aliases map[string]string) {
+	st.addMethod(method, aliases, getTypeParameterNames(st.TypeParameters))
+}
+
+//addMethod works like AddMethod but uses the given type parameter names. Methods declared outside of the type
+//can name the type parameters of their receiver differently from the type declaration. It returns the added method,
+//or nil if the field is not a function
+func (st *Struct) addMethod(method *ast.Field, aliases map[string]string, typeParameters []string) *Function {
 	f, ok := method.Type.(*ast.FuncType)
 	if !ok {
-		return
+		return nil
 	}
-	function := getFunction(f, method.Names[0].Name, aliases, st.PackageName)
+	function := getFunctionWithTypeParameters(f, method.Names[0].Name, aliases, st.PackageName, typeParameters)
+	function.Pos = method.Names[0].Pos()
 	st.Functions = append(st.Functions, function)
+	return function
+}
+
+//SortedFields returns the fields of this Structure in the given order. The fields are returned in the order they were
+//parsed for MemberOrderVisibility, since grouping them by visibility is up to the renderer
+func (st *Struct) SortedFields(order MemberOrder) []*Field {
+	fields := append([]*Field{}, st.Fields...)
+	switch order {
+	case MemberOrderSource:
+		sort.SliceStable(fields, func(i, j int) bool { return fields[i].Pos < fields[j].Pos })
+	case MemberOrderAlphabetical:
+		sort.SliceStable(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+	}
+	return fields
+}
+
+//SortedFunctions returns the methods of this Structure in the given order. The methods are returned in the order they
+//were parsed for MemberOrderVisibility, since grouping them by visibility is up to the renderer
+func (st *Struct) SortedFunctions(order MemberOrder) []*Function {
+	functions := append([]*Function{}, st.Functions...)
+	switch order {
+	case MemberOrderSource:
+		sort.SliceStable(functions, func(i, j int) bool { return functions[i].Pos < functions[j].Pos })
+	case MemberOrderAlphabetical:
+		sort.SliceStable(functions, func(i, j int) bool { return functions[i].Name < functions[j].Name })
+	}
+	return functions
+}
+
+//FieldNames returns the names of the fields of this Structure in the order they were parsed
+func (st *Struct) FieldNames() []string {
+	names := make([]string, 0, len(st.Fields))
+	for _, f := range st.Fields {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+//MethodNames returns the names of the methods of this Structure in the order they were parsed
+func (st *Struct) MethodNames() []string {
+	names := make([]string, 0, len(st.Functions))
+	for _, f := range st.Functions {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+//String returns the type of this Structure followed by its fields and methods, as in class{Name string; Greet() string}
+func (st *Struct) String() string {
+	members := make([]string, 0, len(st.Fields)+len(st.Functions))
+	for _, f := range st.Fields {
+		members = append(members, f.String())
+	}
+	for _, f := range st.Functions {
+		members = append(members, f.String())
+	}
+	return fmt.Sprintf("%s{%s}", st.Type, strings.Join(members, "; "))
 }
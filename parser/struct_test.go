@@ -2,6 +2,8 @@ package parser
 
 import (
 	"go/ast"
+	goparser "go/parser"
+	"go/token"
 	"reflect"
 	"testing"
 )
@@ -363,13 +365,14 @@ func TestAddField(t *testing.T) {
 		Type: &ast.Ident{
 			Name: "int",
 		},
-	}, make(map[string]string))
+	}, make(map[string]string), "main")
 	if len(st.Fields) != 1 {
 		t.Errorf("TestAddField: Expected st.Fields to have exactly one element but it has %d elements", len(st.Fields))
 	}
 	testField := &Field{
-		Name: "foo",
-		Type: "int",
+		Name:     "foo",
+		Type:     "int",
+		FullType: "int",
 	}
 	if !reflect.DeepEqual(st.Fields[0], testField) {
 		t.Errorf("TestAddField: Expected st.Fields[0] to have %v, got %v", testField, st.Fields[0])
@@ -381,9 +384,9 @@ func TestAddField(t *testing.T) {
 				Name: "FooComposed",
 			},
 		},
-	}, make(map[string]string))
+	}, make(map[string]string), "main")
 
-	if !arrayContains(st.Composition, "FooComposed") {
+	if !arrayContains(st.Composition, "main.FooComposed") {
 		t.Errorf("TestAddField: Expecting FooComposed to be part of the compositions ,but the array had %v", st.Composition)
 	}
 	st.AddField(&ast.Field{
@@ -397,7 +400,7 @@ func TestAddField(t *testing.T) {
 				Name: "FooComposed",
 			},
 		},
-	}, make(map[string]string))
+	}, make(map[string]string), "main")
 	if !arrayContains(st.Aggregations, "main.FooComposed") {
 		t.Errorf("TestAddField: Expecting main.FooComposed to be part of the aggregations ,but the array had %v", st.Aggregations)
 	}
@@ -477,3 +480,423 @@ func TestAddMethod(t *testing.T) {
 		t.Errorf("TestAddMethod: Expected st.Function[0] to have %v, got %v", testFunction, st.Functions[0])
 	}
 }
+
+func TestSortedFields(t *testing.T) {
+	st := &Struct{
+		Fields: []*Field{
+			{Name: "b", Pos: 30},
+			{Name: "C", Pos: 10},
+			{Name: "a", Pos: 20},
+		},
+	}
+	tt := []struct {
+		order         MemberOrder
+		expectedNames []string
+	}{
+		{order: MemberOrderVisibility, expectedNames: []string{"b", "C", "a"}},
+		{order: MemberOrderSource, expectedNames: []string{"C", "a", "b"}},
+		{order: MemberOrderAlphabetical, expectedNames: []string{"C", "a", "b"}},
+	}
+	for _, tc := range tt {
+		t.Run(string(tc.order), func(t *testing.T) {
+			names := []string{}
+			for _, f := range st.SortedFields(tc.order) {
+				names = append(names, f.Name)
+			}
+			if !reflect.DeepEqual(names, tc.expectedNames) {
+				t.Errorf("Expected fields to be %v, got %v", tc.expectedNames, names)
+			}
+		})
+	}
+	if st.Fields[0].Name != "b" {
+		t.Errorf("TestSortedFields: Expected st.Fields to keep its order, got %v", st.Fields)
+	}
+}
+
+func TestSortedFunctions(t *testing.T) {
+	st := &Struct{
+		Functions: []*Function{
+			{Name: "Zoo", Pos: 10},
+			{Name: "bar", Pos: 30},
+			{Name: "Foo", Pos: 20},
+		},
+	}
+	tt := []struct {
+		order         MemberOrder
+		expectedNames []string
+	}{
+		{order: MemberOrderVisibility, expectedNames: []string{"Zoo", "bar", "Foo"}},
+		{order: MemberOrderSource, expectedNames: []string{"Zoo", "Foo", "bar"}},
+		{order: MemberOrderAlphabetical, expectedNames: []string{"Foo", "Zoo", "bar"}},
+	}
+	for _, tc := range tt {
+		t.Run(string(tc.order), func(t *testing.T) {
+			names := []string{}
+			for _, f := range st.SortedFunctions(tc.order) {
+				names = append(names, f.Name)
+			}
+			if !reflect.DeepEqual(names, tc.expectedNames) {
+				t.Errorf("Expected methods to be %v, got %v", tc.expectedNames, names)
+			}
+		})
+	}
+}
+
+func TestAddEmbeddedField(t *testing.T) {
+	st := &Struct{
+		PackageName:  "main",
+		Fields:       make([]*Field, 0),
+		Composition:  make(map[string]struct{}),
+		Aggregations: make(map[string]struct{}),
+	}
+	st.AddField(&ast.Field{
+		Type: &ast.SelectorExpr{
+			X:   &ast.Ident{Name: "io"},
+			Sel: &ast.Ident{Name: "Reader"},
+		},
+	}, map[string]string{"io": "io"}, "main")
+	st.AddField(&ast.Field{
+		Type: &ast.StarExpr{
+			X: &ast.Ident{Name: "Base"},
+		},
+	}, map[string]string{}, "main")
+	expectedFields := []*Field{
+		{Name: "Reader", Type: "io.Reader", FullType: "io.Reader", Embedded: true},
+		{Name: "Base", Type: "*.Base", FullType: "*main.Base", Embedded: true},
+	}
+	if !reflect.DeepEqual(st.Fields, expectedFields) {
+		t.Errorf("TestAddEmbeddedField: Expected fields to be %v, got %v", expectedFields, st.Fields)
+	}
+	expectedComposition := map[string]struct{}{"io.Reader": {}, "main.Base": {}}
+	if !reflect.DeepEqual(st.Composition, expectedComposition) {
+		t.Errorf("TestAddEmbeddedField: Expected compositions to be %v, got %v", expectedComposition, st.Composition)
+	}
+}
+
+func TestAddFieldMultiplicity(t *testing.T) {
+	st := &Struct{
+		PackageName:         "main",
+		Fields:              make([]*Field, 0),
+		Composition:         make(map[string]struct{}),
+		Aggregations:        make(map[string]struct{}),
+		PrivateAggregations: make(map[string]struct{}),
+	}
+	fields := []*ast.Field{
+		{
+			Names: []*ast.Ident{{Name: "Items"}},
+			Type:  &ast.ArrayType{Elt: &ast.Ident{Name: "Order"}},
+		},
+		{
+			Names: []*ast.Ident{{Name: "Corners"}},
+			Type:  &ast.ArrayType{Len: &ast.BasicLit{Value: "4"}, Elt: &ast.Ident{Name: "Point"}},
+		},
+		{
+			Names: []*ast.Ident{{Name: "index"}},
+			Type:  &ast.MapType{Key: &ast.Ident{Name: "string"}, Value: &ast.StarExpr{X: &ast.Ident{Name: "Customer"}}},
+		},
+		{
+			Names: []*ast.Ident{{Name: "Owner"}},
+			Type:  &ast.StarExpr{X: &ast.Ident{Name: "User"}},
+		},
+	}
+	for _, f := range fields {
+		st.AddField(f, map[string]string{}, "main")
+	}
+	expected := map[string]string{
+		"main.Order":    "0..*",
+		"main.Point":    "4",
+		"main.Customer": "0..*",
+	}
+	if !reflect.DeepEqual(st.Multiplicities, expected) {
+		t.Errorf("TestAddFieldMultiplicity: Expected multiplicities to be %v, got %v", expected, st.Multiplicities)
+	}
+}
+
+func TestAddFieldValueAggregations(t *testing.T) {
+	st := &Struct{
+		PackageName:         "main",
+		Fields:              make([]*Field, 0),
+		Composition:         make(map[string]struct{}),
+		Aggregations:        make(map[string]struct{}),
+		PrivateAggregations: make(map[string]struct{}),
+	}
+	fields := []*ast.Field{
+		{
+			Names: []*ast.Ident{{Name: "Address"}},
+			Type:  &ast.Ident{Name: "Address"},
+		},
+		{
+			Names: []*ast.Ident{{Name: "Items"}},
+			Type:  &ast.ArrayType{Elt: &ast.Ident{Name: "Order"}},
+		},
+		{
+			Names: []*ast.Ident{{Name: "index"}},
+			Type:  &ast.MapType{Key: &ast.Ident{Name: "Key"}, Value: &ast.StarExpr{X: &ast.Ident{Name: "Customer"}}},
+		},
+		{
+			Names: []*ast.Ident{{Name: "Owner"}},
+			Type:  &ast.StarExpr{X: &ast.Ident{Name: "User"}},
+		},
+		{
+			Names: []*ast.Ident{{Name: "Events"}},
+			Type:  &ast.ChanType{Value: &ast.Ident{Name: "Event"}},
+		},
+	}
+	for _, f := range fields {
+		st.AddField(f, map[string]string{}, "main")
+	}
+	expected := map[string]struct{}{
+		"main.Address": {},
+		"main.Order":   {},
+		"main.Key":     {},
+	}
+	if !reflect.DeepEqual(st.ValueAggregations, expected) {
+		t.Errorf("TestAddFieldValueAggregations: Expected value aggregations to be %v, got %v", expected, st.ValueAggregations)
+	}
+	if !st.HoldsByValue("main.Address") || st.HoldsByValue("main.User") {
+		t.Errorf("TestAddFieldValueAggregations: Expected Address to be held by value and User through a pointer")
+	}
+}
+
+func TestAddFieldMapAggregations(t *testing.T) {
+	st := &Struct{
+		PackageName:         "main",
+		Fields:              make([]*Field, 0),
+		Composition:         make(map[string]struct{}),
+		Aggregations:        make(map[string]struct{}),
+		PrivateAggregations: make(map[string]struct{}),
+	}
+	fields := []*ast.Field{
+		{
+			Names: []*ast.Ident{{Name: "Users"}},
+			Type:  &ast.MapType{Key: &ast.Ident{Name: "UserID"}, Value: &ast.StarExpr{X: &ast.Ident{Name: "User"}}},
+		},
+		{
+			Names: []*ast.Ident{{Name: "Accounts"}},
+			Type: &ast.MapType{
+				Key:   &ast.SelectorExpr{X: &ast.Ident{Name: "bank"}, Sel: &ast.Ident{Name: "IBAN"}},
+				Value: &ast.ArrayType{Elt: &ast.SelectorExpr{X: &ast.Ident{Name: "bank"}, Sel: &ast.Ident{Name: "Account"}}},
+			},
+		},
+	}
+	for _, f := range fields {
+		st.AddField(f, map[string]string{"bank": "github.com.example.bank"}, "main")
+	}
+	// Both the key and the value of a map are aggregated
+	for _, aggregated := range []string{"main.UserID", "main.User", "github.com.example.bank.IBAN", "github.com.example.bank.Account"} {
+		if _, ok := st.Aggregations[aggregated]; !ok {
+			t.Errorf("TestAddFieldMapAggregations: Expected %s to be aggregated, got %v", aggregated, st.Aggregations)
+		}
+		if st.Multiplicities[aggregated] != "0..*" {
+			t.Errorf("TestAddFieldMapAggregations: Expected the multiplicity of %s to be 0..*, got %q", aggregated, st.Multiplicities[aggregated])
+		}
+	}
+}
+
+func TestAddFieldAggregationFields(t *testing.T) {
+	st := &Struct{
+		PackageName:         "main",
+		Fields:              make([]*Field, 0),
+		Composition:         make(map[string]struct{}),
+		Aggregations:        make(map[string]struct{}),
+		PrivateAggregations: make(map[string]struct{}),
+	}
+	fields := []*ast.Field{
+		{
+			Names: []*ast.Ident{{Name: "Billing"}},
+			Type:  &ast.Ident{Name: "Address"},
+		},
+		{
+			Names: []*ast.Ident{{Name: "shipping"}},
+			Type:  &ast.StarExpr{X: &ast.Ident{Name: "Address"}},
+		},
+		{
+			Names: []*ast.Ident{{Name: "Items"}},
+			Type:  &ast.ArrayType{Elt: &ast.Ident{Name: "Order"}},
+		},
+		{
+			Type: &ast.StarExpr{X: &ast.SelectorExpr{X: &ast.Ident{Name: "sync"}, Sel: &ast.Ident{Name: "Mutex"}}},
+		},
+	}
+	for _, f := range fields {
+		st.AddField(f, map[string]string{}, "main")
+	}
+	expected := map[string][]string{
+		"main.Address": {"Billing", "shipping"},
+		"main.Order":   {"Items"},
+	}
+	if !reflect.DeepEqual(st.AggregationFields, expected) {
+		t.Errorf("TestAddFieldAggregationFields: Expected aggregation fields to be %v, got %v", expected, st.AggregationFields)
+	}
+	if result := st.AggregatingFields("main.Address", false); !reflect.DeepEqual(result, []string{"Billing"}) {
+		t.Errorf("TestAddFieldAggregationFields: Expected only the exported fields of Address, got %v", result)
+	}
+	if result := st.AggregatingFields("main.Address", true); !reflect.DeepEqual(result, []string{"Billing", "shipping"}) {
+		t.Errorf("TestAddFieldAggregationFields: Expected every field of Address, got %v", result)
+	}
+	expectedCompositions := map[string][]string{
+		"sync.Mutex": {"Mutex"},
+	}
+	if !reflect.DeepEqual(st.CompositionFields, expectedCompositions) {
+		t.Errorf("TestAddFieldAggregationFields: Expected composition fields to be %v, got %v", expectedCompositions, st.CompositionFields)
+	}
+}
+
+func TestFieldIsExported(t *testing.T) {
+	tt := []struct {
+		name     string
+		field    *Field
+		expected bool
+	}{
+		{name: "Exported", field: &Field{Name: "Name", Type: "string"}, expected: true},
+		{name: "Unexported", field: &Field{Name: "name", Type: "string"}, expected: false},
+		{name: "Underscore prefixed", field: &Field{Name: "_Name", Type: "string"}, expected: false},
+		{name: "Non ASCII upper case", field: &Field{Name: "Ñame", Type: "string"}, expected: true},
+		{name: "Embedded exported", field: &Field{Name: "Base", Type: "*base.Base", Embedded: true}, expected: true},
+		{name: "Embedded unexported", field: &Field{Name: "node", Type: "node[T]", Embedded: true}, expected: false},
+		{name: "Embedded without name", field: &Field{Type: "*base.Base", Embedded: true}, expected: true},
+		{name: "Empty name", field: &Field{Type: "string"}, expected: false},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := tc.field.IsExported(); result != tc.expected {
+				t.Errorf("Expected IsExported to be %t, got %t", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestAddFieldUnderscoreAggregation(t *testing.T) {
+	st := &Struct{
+		PackageName:         "main",
+		Fields:              make([]*Field, 0),
+		Composition:         make(map[string]struct{}),
+		Aggregations:        make(map[string]struct{}),
+		PrivateAggregations: make(map[string]struct{}),
+	}
+	st.AddField(&ast.Field{
+		Names: []*ast.Ident{{Name: "_Order"}},
+		Type:  &ast.Ident{Name: "Order"},
+	}, map[string]string{}, "main")
+	st.AddField(&ast.Field{
+		Type: &ast.StarExpr{X: &ast.SelectorExpr{X: &ast.Ident{Name: "base"}, Sel: &ast.Ident{Name: "Base"}}},
+	}, map[string]string{}, "main")
+	if _, ok := st.PrivateAggregations["main.Order"]; !ok || len(st.Aggregations) != 0 {
+		t.Errorf("TestAddFieldUnderscoreAggregation: Expected Order to be a private aggregation, got %v and %v", st.Aggregations, st.PrivateAggregations)
+	}
+	if len(st.Fields) != 2 || !st.Fields[1].Embedded || !st.Fields[1].IsExported() {
+		t.Errorf("TestAddFieldUnderscoreAggregation: Expected the embedded base.Base field to be exported, got %v", st.Fields)
+	}
+}
+
+func TestAddFieldComments(t *testing.T) {
+	source := `package main
+
+type User struct {
+	// Name is the display name
+	// of the user
+	Name string // user's display name
+	// Email is where the
+	//   notifications are sent
+	Email string
+	Age   int
+	Base  // embedded
+}
+`
+	f, err := goparser.ParseFile(token.NewFileSet(), "comments.go", source, goparser.ParseComments)
+	if err != nil {
+		t.Fatalf("TestAddFieldComments: expected no errors, got %s", err.Error())
+	}
+	st := &Struct{
+		PackageName:         "main",
+		Fields:              make([]*Field, 0),
+		Composition:         make(map[string]struct{}),
+		Aggregations:        make(map[string]struct{}),
+		PrivateAggregations: make(map[string]struct{}),
+	}
+	structType := f.Decls[0].(*ast.GenDecl).Specs[0].(*ast.TypeSpec).Type.(*ast.StructType)
+	for _, field := range structType.Fields.List {
+		st.AddField(field, map[string]string{}, "main")
+	}
+	expected := map[string]string{
+		"Name":  "user's display name",
+		"Email": "Email is where the notifications are sent",
+		"Age":   "",
+		"Base":  "embedded",
+	}
+	if len(st.Fields) != len(expected) {
+		t.Fatalf("TestAddFieldComments: expected %d fields, got %d", len(expected), len(st.Fields))
+	}
+	for _, field := range st.Fields {
+		if field.Comment != expected[field.Name] {
+			t.Errorf("TestAddFieldComments: expected the comment of %s to be %q, got %q", field.Name, expected[field.Name], field.Comment)
+		}
+	}
+}
+
+func TestStructInspectionHelpers(t *testing.T) {
+	st := &Struct{
+		Type: "class",
+		Fields: []*Field{
+			{Name: "Name", Type: "string"},
+			{Name: "Base", Type: "*Base", Embedded: true},
+		},
+		Functions: []*Function{
+			{Name: "Greet", Parameters: []*Field{{Name: "to", Type: "string"}}, ReturnValues: []string{"string"}},
+			{Name: "Close", ReturnValues: []string{"error"}, ReturnValueNames: []string{"err"}},
+		},
+	}
+	if names := st.FieldNames(); !reflect.DeepEqual(names, []string{"Name", "Base"}) {
+		t.Errorf("TestStructInspectionHelpers: expected field names [Name Base], got %v", names)
+	}
+	if names := st.MethodNames(); !reflect.DeepEqual(names, []string{"Greet", "Close"}) {
+		t.Errorf("TestStructInspectionHelpers: expected method names [Greet Close], got %v", names)
+	}
+	expected := "class{Name string; *Base; Greet(to string) string; Close() (err error)}"
+	if st.String() != expected {
+		t.Errorf("TestStructInspectionHelpers: expected %q, got %q", expected, st.String())
+	}
+	empty := &Struct{Type: "interface"}
+	if len(empty.FieldNames()) != 0 || len(empty.MethodNames()) != 0 || empty.String() != "interface{}" {
+		t.Errorf("TestStructInspectionHelpers: expected an empty interface, got %q", empty.String())
+	}
+}
+
+func TestAddFieldBuiltinAggregations(t *testing.T) {
+	st := &Struct{
+		PackageName:         "main",
+		Fields:              make([]*Field, 0),
+		Composition:         make(map[string]struct{}),
+		Aggregations:        make(map[string]struct{}),
+		PrivateAggregations: make(map[string]struct{}),
+	}
+	fields := []*ast.Field{
+		{
+			Names: []*ast.Ident{{Name: "Scores"}},
+			Type:  &ast.MapType{Key: &ast.Ident{Name: "string"}, Value: &ast.ArrayType{Elt: &ast.Ident{Name: "int"}}},
+		},
+		{
+			Names: []*ast.Ident{{Name: "count"}},
+			Type:  &ast.StarExpr{X: &ast.Ident{Name: "uint"}},
+		},
+		{
+			Names: []*ast.Ident{{Name: "Started"}},
+			Type:  &ast.SelectorExpr{X: &ast.Ident{Name: "time"}, Sel: &ast.Ident{Name: "Time"}},
+		},
+	}
+	for _, f := range fields {
+		st.AddField(f, map[string]string{}, "main")
+	}
+	if len(st.Aggregations) != 1 || len(st.PrivateAggregations) != 0 {
+		t.Errorf("TestAddFieldBuiltinAggregations: Expected the builtin types to be kept out of the aggregations, got %v %v", st.Aggregations, st.PrivateAggregations)
+	}
+	if result := st.AggregatedBuiltinTypes(false); !reflect.DeepEqual(result, []string{"int", "string"}) {
+		t.Errorf("TestAddFieldBuiltinAggregations: Expected the builtin types of the exported fields, got %v", result)
+	}
+	if result := st.AggregatedBuiltinTypes(true); !reflect.DeepEqual(result, []string{"int", "string", "uint"}) {
+		t.Errorf("TestAddFieldBuiltinAggregations: Expected the builtin types of every field, got %v", result)
+	}
+	if result := st.AggregatingFields("string", false); !reflect.DeepEqual(result, []string{"Scores"}) {
+		t.Errorf("TestAddFieldBuiltinAggregations: Expected string to be aggregated by Scores, got %v", result)
+	}
+}
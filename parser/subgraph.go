@@ -0,0 +1,119 @@
+package parser
+
+// Subgraph returns a new ClassParser holding only the structures with the given fully qualified names, such as
+// github.com.foo.bar.OrderService, along with the structures they reach within maxHops compositions, aggregations,
+// private ones included, or implementations. Roots that were not parsed are ignored, 0 keeps only the roots and
+// negative values do not limit the hops. The structures are copied without their connections to the parsed structures
+// left out, so the renderers draw the subgraph as if nothing else had been parsed. The types that were not parsed are
+// kept, as they are in the whole diagram. The rendering options are copied too
+func (p *ClassParser) Subgraph(roots []string, maxHops int) *ClassParser {
+	names := map[string][2]string{}
+	for pack, structures := range p.Structure {
+		for name := range structures {
+			names[qualifiedName(pack, name)] = [2]string{pack, name}
+		}
+	}
+	included := map[string]struct{}{}
+	var current []string
+	for _, root := range roots {
+		if _, ok := names[root]; ok {
+			if _, ok := included[root]; !ok {
+				included[root] = struct{}{}
+				current = append(current, root)
+			}
+		}
+	}
+	for hops := 0; len(current) > 0 && (maxHops < 0 || hops < maxHops); hops++ {
+		var next []string
+		for _, fullName := range current {
+			st := p.Structure[names[fullName][0]][names[fullName][1]]
+			for _, target := range p.references(st) {
+				if _, ok := included[target]; !ok {
+					included[target] = struct{}{}
+					next = append(next, target)
+				}
+			}
+		}
+		current = next
+	}
+
+	ro := *p.RenderingOptions
+	result := &ClassParser{
+		RenderingOptions:   &ro,
+		Structure:          make(map[string]map[string]*Struct),
+		CurrentPackageName: p.CurrentPackageName,
+		AllInterfaces:      make(map[string]struct{}),
+		AllStructs:         make(map[string]struct{}),
+		AllImports:         p.AllImports,
+		AllAliases:         make(map[string]*Alias),
+		AllRenamedStructs:  make(map[string]map[string]string),
+		PackageImports:     make(map[string]map[string]struct{}),
+		stdInterfaces:      p.stdInterfaces,
+		packagePaths:       p.packagePaths,
+	}
+	for fullName := range included {
+		pack, name := names[fullName][0], names[fullName][1]
+		st := *p.Structure[pack][name]
+		st.Composition = p.keepIncluded(&st, st.Composition, included)
+		st.Extends = p.keepIncluded(&st, st.Extends, included)
+		st.Aggregations = p.keepIncluded(&st, st.Aggregations, included)
+		st.PrivateAggregations = p.keepIncluded(&st, st.PrivateAggregations, included)
+		if _, ok := result.Structure[pack]; !ok {
+			result.Structure[pack] = make(map[string]*Struct)
+			if renamed, ok := p.AllRenamedStructs[pack]; ok {
+				result.AllRenamedStructs[pack] = renamed
+			}
+			if imports, ok := p.PackageImports[pack]; ok {
+				result.PackageImports[pack] = imports
+			}
+		}
+		result.Structure[pack][name] = &st
+		if _, ok := p.AllInterfaces[fullName]; ok {
+			result.AllInterfaces[fullName] = struct{}{}
+		}
+		if _, ok := p.AllStructs[fullName]; ok {
+			result.AllStructs[fullName] = struct{}{}
+		}
+	}
+	for key, alias := range p.AllAliases {
+		if _, ok := included[alias.Name]; !ok {
+			continue
+		}
+		if _, ok := names[alias.AliasOf]; ok {
+			if _, ok := included[alias.AliasOf]; !ok {
+				continue
+			}
+		}
+		result.AllAliases[key] = alias
+	}
+	return result
+}
+
+// references returns the fully qualified names of the parsed structures the given structure is composed of,
+// aggregates or implements
+func (p *ClassParser) references(st *Struct) []string {
+	var result []string
+	for _, targets := range []map[string]struct{}{st.Composition, st.Aggregations, st.PrivateAggregations, st.Extends} {
+		for target := range targets {
+			if resolved, ok := p.ResolveType(target, st); ok && p.Lookup(resolved) != nil {
+				result = append(result, resolved)
+			}
+		}
+	}
+	return result
+}
+
+// keepIncluded returns the given connections of st without the ones to the parsed structures that are not included
+func (p *ClassParser) keepIncluded(st *Struct, targets map[string]struct{}, included map[string]struct{}) map[string]struct{} {
+	result := make(map[string]struct{}, len(targets))
+	for target := range targets {
+		resolved, ok := p.ResolveType(target, st)
+		if ok && p.Lookup(resolved) != nil {
+			if _, ok := included[resolved]; !ok {
+				continue
+			}
+		}
+		result[target] = struct{}{}
+	}
+	return result
+}
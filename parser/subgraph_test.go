@@ -0,0 +1,93 @@
+package parser
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestSubgraph(t *testing.T) {
+	parser := getEmptyParser("main")
+	parser.AllImports = make(map[string]string)
+	err := parser.ParseSource("shop/shop.go", []byte(`package shop
+
+type Service interface {
+	Place(o *Order)
+}
+
+type OrderService struct {
+	repository *Repository
+}
+
+func (s *OrderService) Place(o *Order) {}
+
+type Repository struct {
+	Orders []*Order
+}
+
+type Order struct {
+	Lines []Line
+}
+
+type Line struct {
+	Product string
+}
+
+type Unrelated struct {
+	Order *Order
+}
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	pack := parser.CurrentPackageName
+	tt := []struct {
+		name     string
+		roots    []string
+		maxHops  int
+		expected []string
+	}{
+		{
+			name:     "RootOnly",
+			roots:    []string{pack + ".OrderService"},
+			expected: []string{"OrderService"},
+		},
+		{
+			name:     "OneHop",
+			roots:    []string{pack + ".OrderService"},
+			maxHops:  1,
+			expected: []string{"OrderService", "Repository", "Service"},
+		},
+		{
+			name:     "Unlimited",
+			roots:    []string{pack + ".OrderService", pack + ".Missing"},
+			maxHops:  -1,
+			expected: []string{"Line", "Order", "OrderService", "Repository", "Service"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			subgraph := parser.Subgraph(tc.roots, tc.maxHops)
+			var names []string
+			for name := range subgraph.Structure[pack] {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, tc.expected) {
+				t.Errorf("Expected the subgraph to hold %v, got %v", tc.expected, names)
+			}
+		})
+	}
+
+	subgraph := parser.Subgraph([]string{pack + ".OrderService"}, 1)
+	repository := subgraph.Structure[pack]["Repository"]
+	if len(repository.Aggregations) != 0 {
+		t.Errorf("Expected the aggregation of Order to be left out, got %v", repository.Aggregations)
+	}
+	if len(parser.Structure[pack]["Repository"].Aggregations) != 1 {
+		t.Errorf("Expected the parsed Repository to keep its aggregations, got %v", parser.Structure[pack]["Repository"].Aggregations)
+	}
+	if _, ok := subgraph.Structure[pack]["OrderService"].Extends[pack+".Service"]; !ok {
+		t.Errorf("Expected OrderService to implement Service in the subgraph")
+	}
+}
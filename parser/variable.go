@@ -0,0 +1,87 @@
+package parser
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// Variable holds a variable declared at the package level, such as var DefaultClient = &Client{}
+type Variable struct {
+	Field
+	// Types holds the fully qualified names of the types held by the variable, such as the struct its pointer points to
+	Types []string
+}
+
+// handleVarDecl adds the variables declared in the given var block to the variables of the current package. Their type
+// is the one they are declared with or, when it is omitted, the one of the composite literal they are initialized
+// with, as in &Client{}. The variables whose type cannot be told without type checking, such as the ones initialized
+// with the result of a function, are left out
+func (p *ClassParser) handleVarDecl(decl *ast.GenDecl) {
+	for _, spec := range decl.Specs {
+		v, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+		for i, name := range v.Names {
+			varType := v.Type
+			if varType == nil && i < len(v.Values) {
+				varType = getLiteralType(v.Values[i])
+			}
+			if varType == nil || name.Name == "_" {
+				continue
+			}
+			theType, fundamentalTypes := getFieldType(varType, p.AllImports, p.CurrentPackageName)
+			types := make([]string, 0, len(fundamentalTypes))
+			for _, t := range fundamentalTypes {
+				types = append(types, replacePackageConstant(t, p.CurrentPackageName))
+			}
+			if p.PackageVars == nil {
+				p.PackageVars = make(map[string][]*Variable)
+			}
+			p.PackageVars[p.CurrentPackageName] = append(p.PackageVars[p.CurrentPackageName], &Variable{
+				Field: Field{
+					Name:      name.Name,
+					Type:      replacePackageConstant(theType, ""),
+					FullType:  replacePackageConstant(theType, p.CurrentPackageName),
+					Pos:       name.Pos(),
+					DefinedIn: p.currentFile,
+				},
+				Types: types,
+			})
+		}
+	}
+}
+
+// getLiteralType returns the type of the given composite literal, or of the pointer to it when its address is taken as
+// in &Client{}. It returns nil for any other expression
+func getLiteralType(value ast.Expr) ast.Expr {
+	switch v := value.(type) {
+	case *ast.CompositeLit:
+		return v.Type
+	case *ast.UnaryExpr:
+		if literal, ok := v.X.(*ast.CompositeLit); ok && v.Op == token.AND && literal.Type != nil {
+			return &ast.StarExpr{X: literal.Type}
+		}
+	case *ast.ParenExpr:
+		return getLiteralType(v.X)
+	}
+	return nil
+}
+
+// RenderedPackageVars returns the variables declared in the given package that are rendered with the current rendering
+// options. There are none unless PackageVars is set, and only the ones holding a parsed struct are rendered
+func (p *ClassParser) RenderedPackageVars(pack string) []*Variable {
+	if !p.RenderingOptions.PackageVars {
+		return nil
+	}
+	result := make([]*Variable, 0, len(p.PackageVars[pack]))
+	for _, variable := range p.PackageVars[pack] {
+		for _, t := range variable.Types {
+			if _, ok := p.AllStructs[t]; ok {
+				result = append(result, variable)
+				break
+			}
+		}
+	}
+	return result
+}
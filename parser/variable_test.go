@@ -0,0 +1,57 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPackageVars(t *testing.T) {
+	parser, err := NewClassDiagramWithOptions(&ClassDiagramOptions{
+		Options: []Option{WithPackageVars(true)},
+	})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = parser.ParseSource("client.go", []byte(`package client
+
+import "net/http"
+
+type Client struct {
+	HTTP *http.Client
+}
+
+var DefaultClient = &Client{}
+
+var (
+	fallback       Client
+	clients        = map[string]*Client{}
+	Timeout        = 30
+	transport      = http.DefaultTransport
+	custom, plain  = Client{}, NewClient()
+)
+
+func NewClient() *Client {
+	return &Client{}
+}
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	var names, types []string
+	for _, variable := range parser.PackageVars[".client"] {
+		names = append(names, variable.Name)
+		types = append(types, variable.Type)
+	}
+	// The types of Timeout, transport and plain cannot be told without type checking
+	expectedNames := []string{"DefaultClient", "fallback", "clients", "custom"}
+	if !reflect.DeepEqual(names, expectedNames) {
+		t.Errorf("Expected the variables %v, got %v", expectedNames, names)
+	}
+	expectedTypes := []string{"*.Client", ".Client", "map[string]*.Client", ".Client"}
+	if !reflect.DeepEqual(types, expectedTypes) {
+		t.Errorf("Expected the types %v, got %v", expectedTypes, types)
+	}
+	if rendered := parser.RenderedPackageVars(".client"); len(rendered) != 4 {
+		t.Errorf("Expected every variable to be rendered, got %d", len(rendered))
+	}
+}
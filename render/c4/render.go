@@ -0,0 +1,100 @@
+package c4
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/jfeliu007/goplantuml/parser"
+	"github.com/jfeliu007/goplantuml/render"
+)
+
+const componentInclude = "!include https://raw.githubusercontent.com/plantuml-stdlib/C4-PlantUML/master/C4_Component.puml"
+const technology = "Go package"
+const uses = "uses"
+
+type renderer struct {
+}
+
+var _ render.Renderer = (*renderer)(nil)
+
+func init() {
+	render.Register("c4", func() render.Renderer { return NewRender() })
+}
+
+// NewRender returns a renderer that draws every package as a C4 component, connected to the packages whose types
+// its types are composed of or aggregate. The types themselves are not rendered.
+func NewRender() *renderer {
+	return &renderer{}
+}
+
+func (r *renderer) Render(p *parser.ClassParser) string {
+	str := &strings.Builder{}
+	// Writing into a strings.Builder never fails
+	_ = r.RenderTo(str, p)
+	return str.String()
+}
+
+func (r *renderer) RenderTo(w io.Writer, p *parser.ClassParser) error {
+	str := parser.NewLineWriterWithIndent(w, p.RenderingOptions.Indent)
+	str.WriteLineWithDepth(0, "@startuml")
+	str.WriteLineWithDepth(0, componentInclude)
+	if p.RenderingOptions.Title != "" {
+		str.WriteLineWithDepth(0, fmt.Sprintf(`title %s`, p.RenderingOptions.Title))
+	}
+
+	included := p.AllIncludedStructures()
+	var packages []string
+	for pack, structures := range included {
+		if len(structures) > 0 {
+			packages = append(packages, pack)
+		}
+	}
+	sort.Strings(packages)
+	for _, pack := range packages {
+		description := fmt.Sprintf("%d types", len(included[pack]))
+		if len(included[pack]) == 1 {
+			description = "1 type"
+		}
+		str.WriteLineWithDepth(0, fmt.Sprintf(`Component(%s, "%s", "%s", "%s")`, render.NodeID(pack), pack, technology, description))
+	}
+	for _, pack := range packages {
+		for _, dependency := range r.dependencies(p, pack, included) {
+			str.WriteLineWithDepth(0, fmt.Sprintf(`Rel(%s, %s, "%s")`, render.NodeID(pack), render.NodeID(dependency), uses))
+		}
+	}
+	str.WriteLineWithDepth(0, "@enduml")
+	return str.Err()
+}
+
+// dependencies returns the sorted list of the other parsed packages that the types of the given package are
+// composed of or aggregate, given the included structures of every package
+func (r *renderer) dependencies(p *parser.ClassParser, pack string, included map[string]map[string]*parser.Struct) []string {
+	found := map[string]struct{}{}
+	for _, structure := range included[pack] {
+		targets := []map[string]struct{}{structure.Composition, structure.Aggregations}
+		if p.RenderingOptions.AggregatePrivateMembers {
+			targets = append(targets, structure.PrivateAggregations)
+		}
+		for _, target := range targets {
+			for t := range target {
+				split := strings.LastIndex(t, ".")
+				if split < 0 || !p.RenderingOptions.IsIncluded(t) {
+					continue
+				}
+				dependency := t[:split]
+				if dependency == pack || len(included[dependency]) == 0 {
+					continue
+				}
+				found[dependency] = struct{}{}
+			}
+		}
+	}
+	result := make([]string, 0, len(found))
+	for dependency := range found {
+		result = append(result, dependency)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,113 @@
+package c4
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jfeliu007/goplantuml/parser"
+)
+
+const users = `package users
+
+type User struct {
+	Name string
+}
+`
+
+const header = `@startuml
+!include https://raw.githubusercontent.com/plantuml-stdlib/C4-PlantUML/master/C4_Component.puml
+Component(example__com__shop__orders, "example.com.shop.orders", "Go package", "1 type")
+Component(example__com__shop__users, "example.com.shop.users", "Go package", "1 type")
+`
+
+func TestRender(t *testing.T) {
+	tt := []struct {
+		name     string
+		orders   string
+		options  []parser.Option
+		expected string
+	}{
+		{
+			name:     "Composition",
+			orders:   "type Customer struct {\n\tusers.User\n}\n",
+			expected: header + `Rel(example__com__shop__orders, example__com__shop__users, "uses")` + "\n@enduml\n",
+		},
+		{
+			name:     "Aggregation",
+			orders:   "type Order struct {\n\tBuyer *users.User\n}\n",
+			expected: header + `Rel(example__com__shop__orders, example__com__shop__users, "uses")` + "\n@enduml\n",
+		},
+		{
+			name:     "PrivateAggregation",
+			orders:   "type History struct {\n\towner *users.User\n}\n",
+			expected: header + "@enduml\n",
+		},
+		{
+			name:     "AggregatePrivateMembers",
+			orders:   "type History struct {\n\towner *users.User\n}\n",
+			options:  []parser.Option{parser.WithAggregatePrivateMembers(true)},
+			expected: header + `Rel(example__com__shop__orders, example__com__shop__users, "uses")` + "\n@enduml\n",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			shop := t.TempDir()
+			files := map[string]string{
+				"go.mod":          "module example.com/shop\n",
+				"users/user.go":   users,
+				"orders/order.go": "package orders\n\nimport \"example.com/shop/users\"\n\n" + tc.orders,
+			}
+			for name, content := range files {
+				name = filepath.Join(shop, name)
+				if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+					t.Fatalf("Expected no errors, got %s", err.Error())
+				}
+				if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+					t.Fatalf("Expected no errors, got %s", err.Error())
+				}
+			}
+			directories := []string{filepath.Join(shop, "orders"), filepath.Join(shop, "users")}
+			p, err := parser.NewClassDiagramFromDirectories(directories, tc.options...)
+			if err != nil {
+				t.Fatalf("Expected no errors, got %s", err.Error())
+			}
+			if result := NewRender().Render(p); result != tc.expected {
+				t.Errorf("Expected\n%s\ngot\n%s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestRenderDistinctAliases(t *testing.T) {
+	m := t.TempDir()
+	files := map[string]string{
+		"go.mod":     "module example.com/m\n",
+		"a/b/b.go":   "package b\n\ntype T struct{}\n",
+		"a_b/a_b.go": "package a_b\n\ntype T struct{}\n",
+	}
+	for name, content := range files {
+		name = filepath.Join(m, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatalf("Expected no errors, got %s", err.Error())
+		}
+		if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatalf("Expected no errors, got %s", err.Error())
+		}
+	}
+	p, err := parser.NewClassDiagramFromDirectories([]string{filepath.Join(m, "a", "b"), filepath.Join(m, "a_b")})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	result := NewRender().Render(p)
+	for _, expected := range []string{
+		`Component(example__com__m__a__b, "example.com.m.a.b", "Go package", "1 type")`,
+		`Component(example__com__m__a_ub, "example.com.m.a_b", "Go package", "1 type")`,
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected the diagram to contain %q, got\n%s", expected, result)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package graphml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/jfeliu007/goplantuml/parser"
+	"github.com/jfeliu007/goplantuml/render"
+)
+
+const header = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>`
+const graphmlStart = `<graphml xmlns="http://graphml.graphdrawing.org/xmlns" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:y="http://www.yworks.com/xml/graphml" xsi:schemaLocation="http://graphml.graphdrawing.org/xmlns http://www.yworks.com/xml/schema/graphml/1.1/ygraphml.xsd">`
+
+// The relationship data of the edges
+const (
+	composition    = "composition"
+	generalization = "generalization"
+	realization    = "realization"
+	aggregation    = "aggregation"
+	alias          = "alias"
+	derivation     = "derivation"
+)
+
+// keys are the data attributes declared for the nodes and edges, in the order they are declared
+var keys = []struct {
+	id         string
+	domain     string
+	name       string
+	yfilesType string
+}{
+	{id: "name", domain: "node", name: "name"},
+	{id: "package", domain: "node", name: "package"},
+	{id: "type", domain: "node", name: "type"},
+	{id: "fields", domain: "node", name: "fields"},
+	{id: "methods", domain: "node", name: "methods"},
+	{id: "graphics", domain: "node", yfilesType: "nodegraphics"},
+	{id: "relationship", domain: "edge", name: "relationship"},
+}
+
+type renderer struct {
+}
+
+var _ render.Renderer = (*renderer)(nil)
+
+func init() {
+	render.Register("graphml", func() render.Renderer { return NewRender() })
+}
+
+// NewRender returns a renderer that writes GraphML, as read by yEd. Every type is a node holding its name, package,
+// type, fields and methods as data, and drawn as a UML class by yEd. Every connection is an edge holding the kind of
+// relationship it is as data. The types of other packages that are connected to the rendered ones are added as nodes
+// with no members.
+func NewRender() *renderer {
+	return &renderer{}
+}
+
+func (r *renderer) Render(p *parser.ClassParser) string {
+	str := &strings.Builder{}
+	// Writing into a strings.Builder never fails
+	_ = r.RenderTo(str, p)
+	return str.String()
+}
+
+// edge is a connection between the nodes with the given ids
+type edge struct {
+	source       string
+	target       string
+	relationship string
+}
+
+func (r *renderer) RenderTo(w io.Writer, p *parser.ClassParser) error {
+	str := parser.NewLineWriterWithIndent(w, p.RenderingOptions.Indent)
+	str.WriteLineWithDepth(0, header)
+	str.WriteLineWithDepth(0, graphmlStart)
+	for _, key := range keys {
+		if key.yfilesType != "" {
+			str.WriteLineWithDepth(1, fmt.Sprintf(`<key id="%s" for="%s" yfiles.type="%s"/>`, key.id, key.domain, key.yfilesType))
+			continue
+		}
+		str.WriteLineWithDepth(1, fmt.Sprintf(`<key id="%s" for="%s" attr.name="%s" attr.type="string"/>`, key.id, key.domain, key.name))
+	}
+	str.WriteLineWithDepth(1, `<graph id="G" edgedefault="directed">`)
+
+	declared := map[string]struct{}{}
+	var edges []edge
+	var packages []string
+	for pack := range p.Structure {
+		packages = append(packages, pack)
+	}
+	sort.Strings(packages)
+	included := p.AllIncludedStructures()
+	for _, pack := range packages {
+		structures := included[pack]
+		var names []string
+		for name := range structures {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			structure := structures[name]
+			id := r.fullName(pack, name)
+			declared[id] = struct{}{}
+			r.renderNode(p, id, pack, structure, str)
+			edges = append(edges, r.edges(p, id, structure)...)
+		}
+	}
+	if p.RenderingOptions.Aliases {
+		edges = append(edges, r.aliasEdges(p)...)
+	}
+
+	// The ends of every edge must be declared, so the types that are not rendered get a node of their own
+	var external []string
+	for _, e := range edges {
+		for _, id := range []string{e.source, e.target} {
+			if _, ok := declared[id]; !ok {
+				declared[id] = struct{}{}
+				external = append(external, id)
+			}
+		}
+	}
+	sort.Strings(external)
+	for _, id := range external {
+		r.renderExternalNode(id, str)
+	}
+	for i, e := range edges {
+		str.WriteLineWithDepth(2, fmt.Sprintf(`<edge id="e%d" source="%s" target="%s">`, i, escape(e.source), escape(e.target)))
+		str.WriteLineWithDepth(3, fmt.Sprintf(`<data key="relationship">%s</data>`, e.relationship))
+		str.WriteLineWithDepth(2, `</edge>`)
+	}
+	str.WriteLineWithDepth(1, `</graph>`)
+	str.WriteLineWithDepth(0, `</graphml>`)
+	return str.Err()
+}
+
+// renderNode renders the node of the given structure. Its members are rendered as data, one per line, and as the
+// attributes and methods of the UML class drawn by yEd
+func (r *renderer) renderNode(p *parser.ClassParser, id string, pack string, structure *parser.Struct, str *parser.LineWriter) {
+	name := id[strings.LastIndex(id, ".")+1:]
+	fields := r.fields(p, structure)
+	methods := r.methods(p, structure)
+	str.WriteLineWithDepth(2, fmt.Sprintf(`<node id="%s">`, escape(id)))
+	str.WriteLineWithDepth(3, fmt.Sprintf(`<data key="name">%s</data>`, escape(name)))
+	str.WriteLineWithDepth(3, fmt.Sprintf(`<data key="package">%s</data>`, escape(pack)))
+	str.WriteLineWithDepth(3, fmt.Sprintf(`<data key="type">%s</data>`, escape(structure.Type)))
+	str.WriteLineWithDepth(3, fmt.Sprintf(`<data key="fields">%s</data>`, escape(strings.Join(fields, "\n"))))
+	str.WriteLineWithDepth(3, fmt.Sprintf(`<data key="methods">%s</data>`, escape(strings.Join(methods, "\n"))))
+	str.WriteLineWithDepth(3, `<data key="graphics">`)
+	str.WriteLineWithDepth(4, `<y:UMLClassNode>`)
+	str.WriteLineWithDepth(5, fmt.Sprintf(`<y:NodeLabel>%s</y:NodeLabel>`, escape(name)))
+	str.WriteLineWithDepth(5, fmt.Sprintf(`<y:UML stereotype="%s">`, escape(structure.Type)))
+	str.WriteLineWithDepth(6, fmt.Sprintf(`<y:AttributeLabel>%s</y:AttributeLabel>`, escape(strings.Join(fields, "\n"))))
+	str.WriteLineWithDepth(6, fmt.Sprintf(`<y:MethodLabel>%s</y:MethodLabel>`, escape(strings.Join(methods, "\n"))))
+	str.WriteLineWithDepth(5, `</y:UML>`)
+	str.WriteLineWithDepth(4, `</y:UMLClassNode>`)
+	str.WriteLineWithDepth(3, `</data>`)
+	str.WriteLineWithDepth(2, `</node>`)
+}
+
+// renderExternalNode renders the node of a type that is connected to the rendered ones but is not rendered itself
+func (r *renderer) renderExternalNode(id string, str *parser.LineWriter) {
+	split := strings.LastIndex(id, ".")
+	str.WriteLineWithDepth(2, fmt.Sprintf(`<node id="%s">`, escape(id)))
+	str.WriteLineWithDepth(3, fmt.Sprintf(`<data key="name">%s</data>`, escape(id[split+1:])))
+	if split > 0 {
+		str.WriteLineWithDepth(3, fmt.Sprintf(`<data key="package">%s</data>`, escape(id[:split])))
+	}
+	str.WriteLineWithDepth(3, `<data key="graphics">`)
+	str.WriteLineWithDepth(4, `<y:UMLClassNode>`)
+	str.WriteLineWithDepth(5, fmt.Sprintf(`<y:NodeLabel>%s</y:NodeLabel>`, escape(id)))
+	str.WriteLineWithDepth(4, `</y:UMLClassNode>`)
+	str.WriteLineWithDepth(3, `</data>`)
+	str.WriteLineWithDepth(2, `</node>`)
+}
+
+// fields returns the fields of the structure that are rendered with the current options
+func (r *renderer) fields(p *parser.ClassParser, structure *parser.Struct) []string {
+	result := []string{}
+	if !p.RenderingOptions.Fields {
+		return result
+	}
+	result = append(result, structure.TypeSet...)
+	for _, field := range structure.SortedFields(p.RenderingOptions.MemberOrder) {
+		if p.RenderingOptions.PrivateMembers || field.IsExported() {
+			result = append(result, field.String())
+		}
+	}
+	return result
+}
+
+// methods returns the methods of the structure that are rendered with the current options
+func (r *renderer) methods(p *parser.ClassParser, structure *parser.Struct) []string {
+	result := []string{}
+	if !p.RenderingOptions.Methods || (structure.Type == "interface" && p.RenderingOptions.HideInterfaceMethods) {
+		return result
+	}
+	if structure.Signature != nil {
+		result = append(result, structure.Signature.String())
+	}
+	for _, method := range structure.SortedFunctions(p.RenderingOptions.MemberOrder) {
+		if p.RenderingOptions.PrivateMembers || method.IsExported() {
+			result = append(result, method.String())
+		}
+	}
+	return result
+}
+
+// edges returns the compositions, realizations and aggregations of the given structure that are rendered with the
+// current options, sorted by relationship and target
+func (r *renderer) edges(p *parser.ClassParser, id string, structure *parser.Struct) []edge {
+	ro := p.RenderingOptions
+	var result []edge
+	add := func(targets map[string]struct{}, relationship string) {
+		var sorted []string
+		for target := range targets {
+			if !ro.BuiltinAggregations && p.GetPackageName(target, structure) == parser.BuiltinPackageName {
+				continue
+			}
+			// The targets that were not parsed get a node of their own in RenderTo
+			target, _ = p.ResolveType(target, structure)
+			if !ro.IsIncluded(target) || ro.IsSelfReference(id, target) {
+				continue
+			}
+			sorted = append(sorted, target)
+		}
+		sort.Strings(sorted)
+		for _, target := range sorted {
+			result = append(result, edge{source: id, target: target, relationship: relationship})
+		}
+	}
+	if ro.Compositions {
+		add(structure.Composition, composition)
+	}
+	if ro.Implementations {
+		relationship := realization
+		if structure.ExtendsRelationship() == parser.Generalization {
+			relationship = generalization
+		}
+		add(structure.Extends, relationship)
+	}
+	if ro.Aggregations {
+		aggregations := make(map[string]struct{}, len(structure.Aggregations))
+		for target := range structure.Aggregations {
+			aggregations[target] = struct{}{}
+		}
+		if ro.AggregatePrivateMembers {
+			for target := range structure.PrivateAggregations {
+				aggregations[target] = struct{}{}
+			}
+		}
+		if ro.BuiltinAggregations {
+			for _, target := range structure.AggregatedBuiltinTypes(ro.AggregatePrivateMembers) {
+				aggregations[target] = struct{}{}
+			}
+		}
+		// With SmartRelationships the types held by value are owned by the structure, so they are composed instead
+		values := map[string]struct{}{}
+		if ro.SmartRelationships {
+			for target := range aggregations {
+				if structure.HoldsByValue(target) {
+					values[target] = struct{}{}
+					delete(aggregations, target)
+				}
+			}
+		}
+		add(aggregations, aggregation)
+		add(values, composition)
+	}
+	return result
+}
+
+// aliasEdges returns an edge from every alias to the type it is an alias of, and from every defined type to the type
+// it is defined from
+func (r *renderer) aliasEdges(p *parser.ClassParser) []edge {
+	orderedAliases := parser.AliasSlice{}
+	for _, a := range p.AllAliases {
+		orderedAliases = append(orderedAliases, *a)
+	}
+	sort.Sort(orderedAliases)
+	var result []edge
+	for _, a := range orderedAliases {
+		if !p.RenderingOptions.IsIncluded(a.Name) || !p.RenderingOptions.IsIncluded(a.AliasOf) {
+			continue
+		}
+		if a.DefinedType {
+			result = append(result, edge{source: a.AliasOf, target: a.Name, relationship: derivation})
+		} else {
+			result = append(result, edge{source: a.AliasOf, target: a.Name, relationship: alias})
+		}
+	}
+	return result
+}
+
+// fullName returns the fully qualified name of the structure with the given name in the given package. Defined types
+// are already stored with their package prefix
+func (r *renderer) fullName(pack string, name string) string {
+	if strings.HasPrefix(name, pack+".") {
+		return name
+	}
+	return pack + "." + name
+}
+
+// escape returns the given text with the characters that are not allowed in XML text and attributes escaped
+func escape(text string) string {
+	str := &strings.Builder{}
+	// Writing into a strings.Builder never fails
+	_ = xml.EscapeText(str, []byte(text))
+	return str.String()
+}
@@ -0,0 +1,105 @@
+package graphml
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/jfeliu007/goplantuml/parser"
+)
+
+const source = `package queue
+
+type Queue[T any] struct {
+	In    chan<- T
+	Out   <-chan T
+	Items []*Item[T]
+}
+
+type Item[T any] struct {
+	Value T
+	Next  *Item[T]
+}
+
+type Sink = chan<- int
+`
+
+// document holds the nodes and the edges of a GraphML document
+type document struct {
+	Nodes []struct {
+		ID   string `xml:"id,attr"`
+		Data []struct {
+			Key   string `xml:"key,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"data"`
+	} `xml:"graph>node"`
+	Edges []struct {
+		Source string `xml:"source,attr"`
+		Target string `xml:"target,attr"`
+		Data   string `xml:"data"`
+	} `xml:"graph>edge"`
+}
+
+func TestRender(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{
+		Options: []parser.Option{parser.WithAggregations(true), parser.WithAliases(true)},
+	})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	if err = p.ParseSource("queue.go", []byte(source)); err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	// & and > are not found in the names of Go types, so a structure holding them is added by hand
+	p.Structure[".queue"]["Fish&Chips<T>"] = &parser.Struct{PackageName: ".queue", Type: "class"}
+	result := NewRender().Render(p)
+	doc := &document{}
+	if err = xml.Unmarshal([]byte(result), doc); err != nil {
+		t.Fatalf("Expected the document to be valid XML, got %s\n%s", err.Error(), result)
+	}
+	nodes := map[string]map[string]string{}
+	for _, node := range doc.Nodes {
+		data := map[string]string{}
+		for _, d := range node.Data {
+			data[d.Key] = strings.TrimSpace(d.Value)
+		}
+		nodes[node.ID] = data
+	}
+	tt := []struct {
+		name     string
+		node     string
+		key      string
+		expected string
+	}{
+		{name: "Name", node: ".queue.Fish&Chips<T>", key: "name", expected: "Fish&Chips<T>"},
+		{name: "Fields", node: ".queue.Queue", key: "fields", expected: "In chan<- T\nOut <-chan T\nItems []*.Item[T]"},
+		{name: "External", node: "builtin.chan<- int", key: "name", expected: "chan<- int"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			data, ok := nodes[tc.node]
+			if !ok {
+				t.Fatalf("Expected the node %s to exist, got %v", tc.node, nodes)
+			}
+			if data[tc.key] != tc.expected {
+				t.Errorf("Expected the %s of %s to be %q, got %q", tc.key, tc.node, tc.expected, data[tc.key])
+			}
+		})
+	}
+	if len(doc.Edges) != 3 {
+		t.Errorf("Expected 3 edges, got %v", doc.Edges)
+	}
+	for _, e := range doc.Edges {
+		for _, id := range []string{e.Source, e.Target} {
+			if _, ok := nodes[id]; !ok {
+				t.Errorf("Expected the end %s of the %s edge to be a node, got %v", id, e.Data, nodes)
+			}
+		}
+	}
+}
+
+func TestEscape(t *testing.T) {
+	if result, expected := escape(`Box<T> & "x"`), "Box&lt;T&gt; &amp; &#34;x&#34;"; result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
@@ -1,7 +1,13 @@
 package render
 
-import "github.com/jfeliu007/goplantuml/parser"
+import (
+	"io"
+
+	"github.com/jfeliu007/goplantuml/parser"
+)
 
 type Renderer interface {
 	Render(parser *parser.ClassParser) string
+	// RenderTo writes the diagram into w as it is rendered instead of building it in memory
+	RenderTo(w io.Writer, parser *parser.ClassParser) error
 }
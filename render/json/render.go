@@ -0,0 +1,220 @@
+package json
+
+import (
+	encjson "encoding/json"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/jfeliu007/goplantuml/parser"
+	"github.com/jfeliu007/goplantuml/render"
+)
+
+// SchemaVersion is the version of the JSON document written by this renderer. It is increased every time a change
+// to the schema could break its consumers
+const SchemaVersion = 1
+
+// Model is the root of the JSON document. Packages, structs, aliases and relations are sorted by name so the same
+// code always produces the same document
+type Model struct {
+	Version  int       `json:"version"`
+	Packages []Package `json:"packages"`
+	Aliases  []Alias   `json:"aliases"`
+}
+
+// Package holds the structs and the functions without a receiver declared in a package
+type Package struct {
+	Name      string   `json:"name"`
+	Structs   []Struct `json:"structs"`
+	Functions []Method `json:"functions"`
+}
+
+// Struct is a class, interface, alias, defined type or function type declared in a package. The signature is only set
+// for function types
+type Struct struct {
+	Name                string            `json:"name"`
+	Type                string            `json:"type"`
+	TypeParameters      []Field           `json:"typeParameters"`
+	Fields              []Field           `json:"fields"`
+	Methods             []Method          `json:"methods"`
+	Constants           []Field           `json:"constants"`
+	Compositions        []string          `json:"compositions"`
+	Extends             []string          `json:"extends"`
+	Aggregations        []string          `json:"aggregations"`
+	PrivateAggregations []string          `json:"privateAggregations"`
+	Multiplicities      map[string]string `json:"multiplicities"`
+	Signature           *Method           `json:"signature,omitempty"`
+	TypeSet             []string          `json:"typeSet,omitempty"`
+}
+
+// Field is a field, a parameter, a type parameter or a constant
+type Field struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	FullType string `json:"fullType"`
+	Embedded bool   `json:"embedded"`
+	Comment  string `json:"comment,omitempty"`
+	Value    string `json:"value,omitempty"`
+}
+
+// Method is a method of a struct or interface, or a function declared without a receiver
+type Method struct {
+	Name             string   `json:"name"`
+	Parameters       []Field  `json:"parameters"`
+	ReturnValues     []string `json:"returnValues"`
+	ReturnValueNames []string `json:"returnValueNames"`
+	PointerReceiver  bool     `json:"pointerReceiver"`
+}
+
+// Alias relates a type to the type it is an alias of, or is defined from when DefinedType is true
+type Alias struct {
+	Name          string   `json:"name"`
+	PackageName   string   `json:"packageName"`
+	AliasOf       string   `json:"aliasOf"`
+	DefinedType   bool     `json:"definedType"`
+	TypeArguments []string `json:"typeArguments,omitempty"`
+}
+
+type renderer struct {
+}
+
+var _ render.Renderer = (*renderer)(nil)
+
+func init() {
+	render.Register("json", func() render.Renderer { return NewRender() })
+}
+
+func NewRender() *renderer {
+	return &renderer{}
+}
+
+func (r *renderer) Render(p *parser.ClassParser) string {
+	str := &strings.Builder{}
+	// Writing into a strings.Builder never fails
+	_ = r.RenderTo(str, p)
+	return str.String()
+}
+
+// RenderTo writes the whole parsed model as JSON. Rendering options are ignored, every parsed element is written
+func (r *renderer) RenderTo(w io.Writer, p *parser.ClassParser) error {
+	encoder := encjson.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(NewModel(p))
+}
+
+// NewModel builds the model that is serialized by the renderer from the given parser
+func NewModel(p *parser.ClassParser) *Model {
+	model := &Model{
+		Version:  SchemaVersion,
+		Packages: []Package{},
+		Aliases:  []Alias{},
+	}
+	var packages []string
+	for pack := range p.Structure {
+		packages = append(packages, pack)
+	}
+	sort.Strings(packages)
+	for _, pack := range packages {
+		structures := p.Structure[pack]
+		var names []string
+		for name := range structures {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		result := Package{
+			Name:      pack,
+			Structs:   make([]Struct, 0, len(names)),
+			Functions: make([]Method, 0, len(p.PackageFunctions[pack])),
+		}
+		for _, name := range names {
+			result.Structs = append(result.Structs, newStruct(name, structures[name]))
+		}
+		for _, f := range p.PackageFunctions[pack] {
+			result.Functions = append(result.Functions, newMethod(f))
+		}
+		model.Packages = append(model.Packages, result)
+	}
+	aliases := parser.AliasSlice{}
+	for _, alias := range p.AllAliases {
+		aliases = append(aliases, *alias)
+	}
+	sort.Sort(aliases)
+	for _, alias := range aliases {
+		model.Aliases = append(model.Aliases, Alias{
+			Name:          alias.Name,
+			PackageName:   alias.PackageName,
+			AliasOf:       alias.AliasOf,
+			DefinedType:   alias.DefinedType,
+			TypeArguments: alias.TypeArguments,
+		})
+	}
+	return model
+}
+
+func newStruct(name string, structure *parser.Struct) Struct {
+	result := Struct{
+		Name:                name,
+		Type:                structure.Type,
+		TypeParameters:      newFields(structure.TypeParameters),
+		Fields:              newFields(structure.Fields),
+		Methods:             make([]Method, 0, len(structure.Functions)),
+		Constants:           newFields(structure.Constants),
+		Compositions:        sortedKeys(structure.Composition),
+		Extends:             sortedKeys(structure.Extends),
+		Aggregations:        sortedKeys(structure.Aggregations),
+		PrivateAggregations: sortedKeys(structure.PrivateAggregations),
+		Multiplicities:      map[string]string{},
+	}
+	for _, f := range structure.Functions {
+		result.Methods = append(result.Methods, newMethod(f))
+	}
+	for t, multiplicity := range structure.Multiplicities {
+		result.Multiplicities[t] = multiplicity
+	}
+	if len(structure.TypeSet) > 0 {
+		result.TypeSet = append([]string{}, structure.TypeSet...)
+	}
+	if structure.Signature != nil {
+		signature := newMethod(structure.Signature)
+		result.Signature = &signature
+	}
+	return result
+}
+
+func newMethod(f *parser.Function) Method {
+	returnValues := f.ReturnValues
+	if returnValues == nil {
+		returnValues = []string{}
+	}
+	return Method{
+		Name:             f.Name,
+		Parameters:       newFields(f.Parameters),
+		ReturnValues:     returnValues,
+		ReturnValueNames: append([]string{}, f.ReturnValueNames...),
+		PointerReceiver:  f.PointerReceiver,
+	}
+}
+
+func newFields(fields []*parser.Field) []Field {
+	result := make([]Field, 0, len(fields))
+	for _, f := range fields {
+		result = append(result, Field{
+			Name:     f.Name,
+			Type:     f.Type,
+			FullType: f.FullType,
+			Embedded: f.Embedded,
+			Comment:  f.Comment,
+			Value:    f.Value,
+		})
+	}
+	return result
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,137 @@
+package json
+
+import (
+	encjson "encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/jfeliu007/goplantuml/parser"
+)
+
+var sources = map[string]string{
+	"shapes.go": `package shapes
+
+type Shape interface {
+	Area() float64
+}
+
+type Square struct {
+	Side   float64
+	Points []*Point
+	points map[string]*Point
+}
+
+func (s *Square) Area() float64 {
+	return s.Side * s.Side
+}
+
+type Point struct {
+	X, Y int
+}
+
+type Color int
+
+const (
+	Red Color = iota
+	Green
+)
+
+type Size = Square
+
+func NewSquare(side float64) *Square {
+	return &Square{Side: side}
+}
+`,
+	"colors.go": `package colors
+
+type RGB struct {
+	R, G, B uint8
+}
+
+type Palette []RGB
+`,
+}
+
+// parseSources returns a parser holding the packages of every source, parsed in the given order
+func parseSources(t *testing.T, order []string) *parser.ClassParser {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	for _, name := range order {
+		if err = p.ParseSource(name, []byte(sources[name])); err != nil {
+			t.Fatalf("Expected no errors, got %s", err.Error())
+		}
+	}
+	return p
+}
+
+func TestRenderVersion(t *testing.T) {
+	result := NewRender().Render(parseSources(t, []string{"shapes.go"}))
+	if !strings.HasPrefix(result, "{\n  \"version\": 1,\n") {
+		t.Errorf("Expected the document to start with its version, got\n%s", result)
+	}
+	model := &Model{}
+	if err := encjson.Unmarshal([]byte(result), model); err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	if model.Version != SchemaVersion {
+		t.Errorf("Expected the version to be %d, got %d", SchemaVersion, model.Version)
+	}
+}
+
+func TestRenderIsSorted(t *testing.T) {
+	result := NewRender().Render(parseSources(t, []string{"shapes.go", "colors.go"}))
+	for i := 0; i < 10; i++ {
+		// The structures are kept in maps, which are iterated in a different order every time
+		if other := NewRender().Render(parseSources(t, []string{"colors.go", "shapes.go"})); other != result {
+			t.Fatalf("Expected the same document for the same code, got\n%s\nand\n%s", result, other)
+		}
+	}
+	model := &Model{}
+	if err := encjson.Unmarshal([]byte(result), model); err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	var packages []string
+	for _, pack := range model.Packages {
+		packages = append(packages, pack.Name)
+		var structs []string
+		for _, st := range pack.Structs {
+			structs = append(structs, st.Name)
+		}
+		if !sort.StringsAreSorted(structs) {
+			t.Errorf("Expected the structs of %s to be sorted, got %v", pack.Name, structs)
+		}
+	}
+	if expected := []string{".colors", ".shapes"}; !reflect.DeepEqual(packages, expected) {
+		t.Errorf("Expected the packages to be %v, got %v", expected, packages)
+	}
+}
+
+func TestRenderRoundTrip(t *testing.T) {
+	p := parseSources(t, []string{"shapes.go", "colors.go"})
+	model := &Model{}
+	if err := encjson.Unmarshal([]byte(NewRender().Render(p)), model); err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	if expected := NewModel(p); !reflect.DeepEqual(model, expected) {
+		t.Errorf("Expected the document to hold the model\n%+v\ngot\n%+v", expected, model)
+	}
+	var square *Struct
+	for i, st := range model.Packages[1].Structs {
+		if st.Name == "Square" {
+			square = &model.Packages[1].Structs[i]
+		}
+	}
+	if square == nil {
+		t.Fatalf("Expected Square to be in the document, got %+v", model.Packages[1].Structs)
+	}
+	if expected := []string{".shapes.Point"}; !reflect.DeepEqual(square.Aggregations, expected) {
+		t.Errorf("Expected the aggregations of Square to be %v, got %v", expected, square.Aggregations)
+	}
+	if expected := map[string]string{".shapes.Point": "0..*"}; !reflect.DeepEqual(square.Multiplicities, expected) {
+		t.Errorf("Expected the multiplicities of Square to be %v, got %v", expected, square.Multiplicities)
+	}
+}
@@ -2,9 +2,9 @@ package mermaid
 
 import (
 	"fmt"
+	"io"
 	"sort"
 	"strings"
-	"unicode"
 
 	"github.com/jfeliu007/goplantuml/parser"
 	"github.com/jfeliu007/goplantuml/render"
@@ -14,42 +14,110 @@ const extends = `Inheritance`
 const implements = `Realization`
 const aggregates = `Aggregation`
 const aliasOf = `Alias`
+const derivesFrom = `DerivesFrom`
+const constrainedBy = `ConstrainedBy`
+
+const docCommentWidth = 80
+
+var docCommentEscaper = strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;")
+
+// fieldCommentEscaper escapes the comments of the fields, which are written inside the class blocks
+var fieldCommentEscaper = strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;", "{", "#123;", "}", "#125;", "~", "#126;")
+
+// typeEscaper writes the types as they can be written in the members of a mermaid class. Names are underscored like the
+// class names, except for the arrow of directional channels and the ... of variadic parameters. Empty braces, as in interface{}, are removed and the other
+// braces would close the class body, the parentheses of function types would make mermaid read the fields as methods
+// and the ~ of the type set elements would be read as the delimiter of generic types, so they are written as entity
+// codes
+var typeEscaper = strings.NewReplacer("<-", "<-", "...", "...", ".", "_", "-", "_", "{}", "", "{", "#123;", "}", "#125;", "(", "#40;", ")", "#41;", "~", "#126;")
 
 type renderer struct {
 }
 
 var _ render.Renderer = (*renderer)(nil)
 
+func init() {
+	render.Register("mermaid", func() render.Renderer { return NewRender() })
+}
+
 func NewRender() *renderer {
 	return &renderer{}
 }
 
 func (r *renderer) Render(p *parser.ClassParser) string {
-	str := &parser.LineStringBuilder{}
-	str.WriteLineWithDepth(0, "classDiagram")
+	str := &strings.Builder{}
+	// Writing into a strings.Builder never fails
+	_ = r.RenderTo(str, p)
+	return str.String()
+}
 
+func (r *renderer) RenderTo(w io.Writer, p *parser.ClassParser) error {
+	str := parser.NewLineWriterWithIndent(w, p.RenderingOptions.Indent)
+	if p.RenderingOptions.MarkdownFence {
+		str.WriteLineWithDepth(0, "```mermaid")
+	}
+	r.renderDiagram(p, str)
+	if p.RenderingOptions.MarkdownFence {
+		str.WriteLineWithDepth(0, "```")
+	}
+	return str.Err()
+}
+
+// renderDiagram renders either the class diagram or the import graph of the parsed packages
+func (r *renderer) renderDiagram(p *parser.ClassParser, str *parser.LineWriter) {
 	var packages []string
 	for pack := range p.Structure {
 		packages = append(packages, pack)
 	}
 	sort.Strings(packages)
-	for _, pack := range packages {
-		structures := p.Structure[pack]
-		r.renderStructures(p, pack, structures, str)
+	if p.RenderingOptions.ImportGraph {
+		r.renderImportGraph(p, packages, str)
+		return
+	}
+	str.WriteLineWithDepth(0, "classDiagram")
 
+	nodes := newNodeLabels()
+	nodes.aliases = p.RenderingOptions.PackageAliases
+	included := p.AllIncludedStructures()
+	if p.RenderingOptions.FlattenPackages {
+		nodes.flat = render.FlatNames(included)
+	}
+	for _, pack := range packages {
+		r.renderStructures(p, pack, included[pack], str, nodes)
 	}
 	if p.RenderingOptions.Aliases {
-		r.renderAliases(p, str)
+		r.renderAliases(p, str, nodes)
+	}
+	if p.RenderingOptions.ConstraintEdges {
+		r.renderConstraints(p, packages, included, str, nodes)
+	}
+	r.renderReferencedLabels(nodes, str)
+}
+
+// renderImportGraph renders a flowchart with every package as a node connected to the other parsed packages it
+// imports. Import cycles are drawn like any other import so they can be spotted
+func (r *renderer) renderImportGraph(p *parser.ClassParser, packages []string, str *parser.LineWriter) {
+	str.WriteLineWithDepth(0, "flowchart LR")
+	for _, pack := range packages {
+		str.WriteLineWithDepth(1, fmt.Sprintf(`%s["%s"]`, render.NodeID(pack), render.DisplayPackage(p.RenderingOptions.PackageAliases, pack)))
+	}
+	for _, pack := range packages {
+		for _, imported := range p.ImportedPackages(pack) {
+			str.WriteLineWithDepth(1, fmt.Sprintf(`%s --> %s`, render.NodeID(pack), render.NodeID(imported)))
+		}
 	}
-	return str.String()
 }
 
-func (r *renderer) renderStructures(p *parser.ClassParser, pack string, structures map[string]*parser.Struct, str *parser.LineStringBuilder) {
-	if len(structures) > 0 {
-		composition := &parser.LineStringBuilder{}
-		extends := &parser.LineStringBuilder{}
-		aggregations := &parser.LineStringBuilder{}
-		//str.WriteLineWithDepth(0, fmt.Sprintf(`namespace %s {`, pack))
+func (r *renderer) renderStructures(p *parser.ClassParser, pack string, structures map[string]*parser.Struct, str *parser.LineWriter, nodes *nodeLabels) {
+	functions := p.RenderedPackageFunctions(pack)
+	if len(structures) > 0 || len(functions) > 0 {
+		composition := parser.NewLineStringBuilder(p.RenderingOptions.Indent)
+		extends := parser.NewLineStringBuilder(p.RenderingOptions.Indent)
+		aggregations := parser.NewLineStringBuilder(p.RenderingOptions.Indent)
+		namespace := p.RenderingOptions.MermaidNamespaces && !p.RenderingOptions.FlattenPackages
+		if namespace {
+			str.WriteLineWithDepth(0, fmt.Sprintf(`namespace %s {`, render.NodeID(render.DisplayPackage(p.RenderingOptions.PackageAliases, pack))))
+		}
 
 		var names []string
 		for name := range structures {
@@ -60,10 +128,24 @@ func (r *renderer) renderStructures(p *parser.ClassParser, pack string, structur
 
 		for _, name := range names {
 			structure := structures[name]
-			r.renderStructure(p, structure, pack, name, str, composition, extends, aggregations)
+			r.renderStructure(p, structure, pack, name, str, composition, extends, aggregations, nodes)
+		}
+		if len(functions) > 0 {
+			r.renderPackageFunctions(p, pack, functions, str)
 		}
 
-		//str.WriteLineWithDepth(0, fmt.Sprintf(`}`))
+		if namespace {
+			str.WriteLineWithDepth(0, `}`)
+		}
+		// Notes are not allowed inside namespaces, they are rendered once the classes of the package are
+		for _, name := range names {
+			if p.RenderingOptions.DocComments && strings.TrimSpace(structures[name].Doc) != "" {
+				r.renderDocComment(structures[name], render.NodeID(r.fullName(pack, name)), str)
+			}
+			if structures[name].Note != "" {
+				str.WriteLineWithDepth(1, fmt.Sprintf(`note for %s "%s"`, render.NodeID(r.fullName(pack, name)), docCommentEscaper.Replace(structures[name].Note)))
+			}
+		}
 		if p.RenderingOptions.Compositions {
 			str.WriteLineWithDepth(0, composition.String())
 		}
@@ -76,11 +158,11 @@ func (r *renderer) renderStructures(p *parser.ClassParser, pack string, structur
 	}
 }
 
-func (r *renderer) renderStructure(p *parser.ClassParser, structure *parser.Struct, pack string, name string, str *parser.LineStringBuilder, composition *parser.LineStringBuilder, extends *parser.LineStringBuilder, aggregations *parser.LineStringBuilder) {
-	privateFields := &parser.LineStringBuilder{}
-	publicFields := &parser.LineStringBuilder{}
-	privateMethods := &parser.LineStringBuilder{}
-	publicMethods := &parser.LineStringBuilder{}
+func (r *renderer) renderStructure(p *parser.ClassParser, structure *parser.Struct, pack string, name string, str *parser.LineWriter, composition *parser.LineStringBuilder, extends *parser.LineStringBuilder, aggregations *parser.LineStringBuilder, nodes *nodeLabels) {
+	privateFields := parser.NewLineStringBuilder(p.RenderingOptions.Indent)
+	publicFields := parser.NewLineStringBuilder(p.RenderingOptions.Indent)
+	privateMethods := parser.NewLineStringBuilder(p.RenderingOptions.Indent)
+	publicMethods := parser.NewLineStringBuilder(p.RenderingOptions.Indent)
 	sType := ""
 	renderStructureType := structure.Type
 	switch structure.Type {
@@ -89,17 +171,41 @@ func (r *renderer) renderStructure(p *parser.ClassParser, structure *parser.Stru
 		renderStructureType = "class"
 	case "class":
 		sType = "<<class>>"
-	case "alias":
-		sType = "<<alias>> "
+	case "func":
+		sType = "<<func>>"
 		renderStructureType = "class"
+	case "alias", "type":
+		sType = fmt.Sprintf("<<%s>>", structure.Type)
+		renderStructureType = "class"
+		if p.RenderingOptions.Constants && len(structure.Constants) > 0 {
+			sType = "<<enumeration>>"
+		}
 
 	}
-	str.WriteLineWithDepth(1, fmt.Sprintf(`%s %s { %s`, renderStructureType, r.underscore(pack+"_"+name), sType))
+	if stereotype, ok := p.RenderingOptions.Stereotypes[structure.Type]; ok && sType != "<<enumeration>>" {
+		sType = stereotype
+	}
+	fullName := r.fullName(pack, name)
+	nodes.declare(fullName)
+	renderName := render.NodeID(fullName)
+	if len(structure.TypeParameters) > 0 {
+		renderName = fmt.Sprintf("%s~%s~", renderName, r.renderTypeParameters(structure))
+	}
+	str.WriteLineWithDepth(1, fmt.Sprintf(`%s %s["%s"] {`, renderStructureType, renderName, nodes.label(fullName)))
+	// Some versions of mermaid only read the annotation when it is on a line of its own
+	if sType != "" {
+		str.WriteLineWithDepth(2, sType)
+	}
+	if sType == "<<enumeration>>" {
+		r.renderConstants(p, structure, str)
+	}
+	r.renderTypeSet(p, structure, publicFields)
 	r.renderStructFields(p, structure, privateFields, publicFields)
+	r.renderSignature(p, structure, publicMethods)
 	r.renderStructMethods(p, structure, privateMethods, publicMethods)
-	r.renderCompositions(p, structure, name, composition)
-	r.renderExtends(p, structure, name, extends)
-	r.renderAggregations(p, structure, name, aggregations)
+	r.renderCompositions(p, structure, name, composition, nodes)
+	r.renderExtends(p, structure, name, extends, nodes)
+	r.renderAggregations(p, structure, name, aggregations, nodes)
 	if privateFields.Len() > 0 {
 		str.WriteLineWithDepth(0, privateFields.String())
 	}
@@ -115,12 +221,104 @@ func (r *renderer) renderStructure(p *parser.ClassParser, structure *parser.Stru
 	str.WriteLineWithDepth(1, fmt.Sprintf(`}`))
 }
 
-func (r *renderer) renderAggregations(p *parser.ClassParser, structure *parser.Struct, name string, aggregations *parser.LineStringBuilder) {
-	aggregationMap := structure.Aggregations
+// renderPackageFunctions renders the given functions as the methods of a class with the functions stereotype. Its
+// identifier never matches the one of a type, since nodeID does not write an underscore followed by an f
+func (r *renderer) renderPackageFunctions(p *parser.ClassParser, pack string, functions []*parser.Function, str *parser.LineWriter) {
+	privateMethods := parser.NewLineStringBuilder(p.RenderingOptions.Indent)
+	publicMethods := parser.NewLineStringBuilder(p.RenderingOptions.Indent)
+	r.renderStructMethods(p, &parser.Struct{PackageName: pack, Functions: functions}, privateMethods, publicMethods)
+	str.WriteLineWithDepth(1, fmt.Sprintf(`class %s_functions["%s functions"] {`, render.NodeID(pack), render.DisplayPackage(p.RenderingOptions.PackageAliases, pack)))
+	str.WriteLineWithDepth(2, "<<functions>>")
+	if privateMethods.Len() > 0 {
+		str.WriteLineWithDepth(0, privateMethods.String())
+	}
+	if publicMethods.Len() > 0 {
+		str.WriteLineWithDepth(0, publicMethods.String())
+	}
+	str.WriteLineWithDepth(1, `}`)
+}
+
+// renderConstraints renders a dashed edge from every generic type, and from the class holding the functions of its
+// package, to the parsed interfaces their type parameters are constrained by. The included structures are keyed by
+// package
+func (r *renderer) renderConstraints(
+	p *parser.ClassParser,
+	packages []string,
+	included map[string]map[string]*parser.Struct,
+	str *parser.LineWriter,
+	nodes *nodeLabels,
+) {
+	label := ""
+	if p.RenderingOptions.ConnectionLabels {
+		label = constrainedBy
+	}
+	for _, pack := range packages {
+		structures := included[pack]
+		var names []string
+		for name := range structures {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			r.renderConstraintEdges(p, render.NodeID(r.fullName(pack, name)), structures[name].TypeParameters, label, str, nodes)
+		}
+		typeParameters := make([]*parser.Field, 0)
+		for _, function := range p.RenderedPackageFunctions(pack) {
+			typeParameters = append(typeParameters, function.TypeParameters...)
+		}
+		r.renderConstraintEdges(p, render.NodeID(pack)+"_functions", typeParameters, label, str, nodes)
+	}
+}
+
+// renderConstraintEdges renders a dashed edge from the node with the given identifier to every parsed interface the
+// given type parameters are constrained by
+func (r *renderer) renderConstraintEdges(p *parser.ClassParser, source string, typeParameters []*parser.Field, label string, str *parser.LineWriter, nodes *nodeLabels) {
+	for _, constraint := range p.ConstraintInterfaces(typeParameters) {
+		if !p.RenderingOptions.IsIncluded(constraint) {
+			continue
+		}
+		nodes.reference(constraint)
+		str.WriteLineWithDepth(0, fmt.Sprintf(`%s ..> %s : %s`, source, render.NodeID(constraint), label))
+	}
+}
+
+// renderDocComment renders the doc comment of the structure as a note for it. Quotes would end the note and are
+// written as entity codes instead
+func (r *renderer) renderDocComment(structure *parser.Struct, name string, str *parser.LineWriter) {
+	lines := render.WrapText(structure.Doc, docCommentWidth)
+	text := docCommentEscaper.Replace(strings.Join(lines, `\n`))
+	str.WriteLineWithDepth(1, fmt.Sprintf(`note for %s "%s"`, name, text))
+}
+
+func (r *renderer) renderConstants(p *parser.ClassParser, structure *parser.Struct, str *parser.LineWriter) {
+	for _, constant := range structure.Constants {
+		str.WriteLineWithDepth(2, render.ConstantString(p.RenderingOptions, constant))
+	}
+}
+
+func (r *renderer) renderTypeParameters(structure *parser.Struct) string {
+	typeParameters := make([]string, 0)
+	for _, tp := range structure.TypeParameters {
+		typeParameters = append(typeParameters, fmt.Sprintf("%s %s", tp.Name, r.formatType(tp.Type)))
+	}
+	return strings.Join(typeParameters, ", ")
+}
+
+func (r *renderer) renderAggregations(p *parser.ClassParser, structure *parser.Struct, name string, aggregations *parser.LineStringBuilder, nodes *nodeLabels) {
+	// The aggregations are copied so the private ones are not added to the parsed structure
+	aggregationMap := make(map[string]struct{}, len(structure.Aggregations))
+	for agg := range structure.Aggregations {
+		aggregationMap[agg] = struct{}{}
+	}
 	if p.RenderingOptions.AggregatePrivateMembers {
 		r.updatePrivateAggregations(structure, aggregationMap)
 	}
-	r.renderAggregationMap(p, aggregationMap, structure, aggregations, name)
+	if p.RenderingOptions.BuiltinAggregations {
+		for _, agg := range structure.AggregatedBuiltinTypes(p.RenderingOptions.AggregatePrivateMembers) {
+			aggregationMap[agg] = struct{}{}
+		}
+	}
+	r.renderAggregationMap(p, aggregationMap, structure, aggregations, name, nodes)
 }
 
 func (r *renderer) updatePrivateAggregations(structure *parser.Struct, aggregationsMap map[string]struct{}) {
@@ -129,18 +327,26 @@ func (r *renderer) updatePrivateAggregations(structure *parser.Struct, aggregati
 	}
 }
 
-func (r *renderer) renderCompositions(p *parser.ClassParser, structure *parser.Struct, name string, composition *parser.LineStringBuilder) {
+func (r *renderer) renderCompositions(p *parser.ClassParser, structure *parser.Struct, name string, composition *parser.LineStringBuilder, nodes *nodeLabels) {
+	if structure.Type == "class" && !p.RenderingOptions.EmbeddedAsComposition {
+		// The embedded fields are rendered as fields instead
+		return
+	}
 	var orderedCompositions []string
 
 	for c := range structure.Composition {
-		if !strings.Contains(c, ".") {
-			c = fmt.Sprintf("%s.%s", p.GetPackageName(c, structure), c)
+		fields := structure.CompositionFields[c]
+		// The types that were not parsed are declared as nodes of their own by renderReferencedLabels
+		c, _ = p.ResolveType(c, structure)
+		if !p.RenderingOptions.IsIncluded(c) || p.RenderingOptions.IsSelfReference(r.fullName(structure.PackageName, name), c) {
+			continue
 		}
 		composedString := ""
 		if p.RenderingOptions.ConnectionLabels {
 			composedString = extends
 		}
-		c = fmt.Sprintf(`%s --|> %s_%s : %s`, r.underscore(c), r.underscore(structure.PackageName), name, composedString)
+		nodes.reference(c)
+		c = fmt.Sprintf(`%s --|> %s : %s`, render.NodeID(c), render.NodeID(r.fullName(structure.PackageName, name)), r.label(p, composedString, fields))
 		orderedCompositions = append(orderedCompositions, c)
 	}
 	sort.Strings(orderedCompositions)
@@ -149,11 +355,29 @@ func (r *renderer) renderCompositions(p *parser.ClassParser, structure *parser.S
 	}
 }
 
-func (r *renderer) underscore(val string) string {
-	return strings.NewReplacer(".", "_", "-", "_").Replace(val)
+// fullName returns the fully qualified name of the structure with the given name in the given package. Defined types
+// are already stored with their package prefix
+func (r *renderer) fullName(pack string, name string) string {
+	if strings.HasPrefix(name, pack+".") {
+		return name
+	}
+	return pack + "." + name
+}
+
+// renderReferencedLabels renders the labels of the nodes that are connected to the rendered classes but are not
+// rendered themselves, such as the types of other packages, so they are not shown with their escaped identifier
+func (r *renderer) renderReferencedLabels(nodes *nodeLabels, str *parser.LineWriter) {
+	for _, name := range nodes.undeclared() {
+		str.WriteLineWithDepth(1, fmt.Sprintf(`class %s["%s"]`, render.NodeID(name), nodes.label(name)))
+	}
+}
+
+// formatType returns the given type as it can be written in the members of a mermaid class (see typeEscaper)
+func (r *renderer) formatType(t string) string {
+	return typeEscaper.Replace(t)
 }
 
-func (r *renderer) renderAggregationMap(p *parser.ClassParser, aggregationMap map[string]struct{}, structure *parser.Struct, aggregations *parser.LineStringBuilder, name string) {
+func (r *renderer) renderAggregationMap(p *parser.ClassParser, aggregationMap map[string]struct{}, structure *parser.Struct, aggregations *parser.LineStringBuilder, name string, nodes *nodeLabels) {
 	var orderedAggregations []string
 	for a := range aggregationMap {
 		orderedAggregations = append(orderedAggregations, a)
@@ -162,100 +386,201 @@ func (r *renderer) renderAggregationMap(p *parser.ClassParser, aggregationMap ma
 	sort.Strings(orderedAggregations)
 
 	for _, a := range orderedAggregations {
+		original := a
+		multiplicityString := ""
+		if multiplicity, ok := structure.Multiplicities[a]; ok && p.RenderingOptions.Multiplicity {
+			multiplicityString = fmt.Sprintf(` "%s"`, multiplicity)
+		}
 		if !strings.Contains(a, ".") {
 			a = fmt.Sprintf("%s.%s", p.GetPackageName(a, structure), a)
 		}
+		if !p.RenderingOptions.IsIncluded(a) || p.RenderingOptions.IsSelfReference(r.fullName(structure.PackageName, name), a) {
+			continue
+		}
 		aggregationString := ""
 		if p.RenderingOptions.ConnectionLabels {
 			aggregationString = aggregates
 		}
-		if p.GetPackageName(a, structure) != parser.BuiltinPackageName {
-			aggregations.WriteLineWithDepth(0, fmt.Sprintf(`%s_%s --o %s : %s`, r.underscore(structure.PackageName), name, r.underscore(a), aggregationString))
+		head := "o"
+		if p.RenderingOptions.SmartRelationships && structure.HoldsByValue(original) {
+			head = "*"
 		}
+		if p.RenderingOptions.BuiltinAggregations || p.GetPackageName(original, structure) != parser.BuiltinPackageName {
+			nodes.reference(a)
+			fields := structure.AggregatingFields(original, p.RenderingOptions.AggregatePrivateMembers)
+			aggregations.WriteLineWithDepth(0, fmt.Sprintf(`%s --%s%s %s : %s`, render.NodeID(r.fullName(structure.PackageName, name)), head, multiplicityString, render.NodeID(a), r.label(p, aggregationString, fields)))
+		}
+	}
+}
+
+// label returns the label of a connection, which is made of the given word for its type, when ConnectionLabels is set,
+// followed by the names of the fields it was created by, when FieldNameLabels is set
+func (r *renderer) label(p *parser.ClassParser, connectionLabel string, fields []string) string {
+	if !p.RenderingOptions.FieldNameLabels || len(fields) == 0 {
+		return connectionLabel
 	}
+	return strings.TrimSpace(fmt.Sprintf("%s %s", connectionLabel, strings.Join(fields, ", ")))
 }
 
-func (r *renderer) renderExtends(p *parser.ClassParser, structure *parser.Struct, name string, extends *parser.LineStringBuilder) {
+func (r *renderer) renderExtends(p *parser.ClassParser, structure *parser.Struct, name string, extensions *parser.LineStringBuilder, nodes *nodeLabels) {
 	var orderedExtends []string
 	for c := range structure.Extends {
 		if !strings.Contains(c, ".") {
 			c = fmt.Sprintf("%s.%s", structure.PackageName, c)
 		}
-		implementString := ""
-		if p.RenderingOptions.ConnectionLabels {
-			implementString = implements
+		if !p.RenderingOptions.IsIncluded(c) {
+			continue
+		}
+		// Interfaces are generalized by the ones they embed and realized by the types that implement them
+		arrow, implementString := "<|..", implements
+		if structure.ExtendsRelationship() == parser.Generalization {
+			arrow, implementString = "<|--", extends
+		}
+		if !p.RenderingOptions.ConnectionLabels {
+			implementString = ""
 		}
-		c = fmt.Sprintf(`%s <|.. %s_%s : %s`, r.underscore(c), r.underscore(structure.PackageName), name, implementString)
+		nodes.reference(c)
+		c = fmt.Sprintf(`%s %s %s : %s`, render.NodeID(c), arrow, render.NodeID(r.fullName(structure.PackageName, name)), implementString)
 		orderedExtends = append(orderedExtends, c)
 	}
 	sort.Strings(orderedExtends)
 	for _, c := range orderedExtends {
-		extends.WriteLineWithDepth(0, c)
+		extensions.WriteLineWithDepth(0, c)
 	}
 }
 
-func (r *renderer) renderStructMethods(p *parser.ClassParser, structure *parser.Struct, privateMethods *parser.LineStringBuilder, publicMethods *parser.LineStringBuilder) {
+// renderTypeSet renders the type set elements of a constraint interface before its fields
+func (r *renderer) renderTypeSet(p *parser.ClassParser, structure *parser.Struct, fields *parser.LineStringBuilder) {
+	if !p.RenderingOptions.Fields {
+		return
+	}
+	for _, element := range structure.TypeSet {
+		fields.WriteLineWithDepth(2, r.formatType(element))
+	}
+}
 
-	for _, method := range structure.Functions {
+// renderSignature renders the signature of a function type before its methods
+func (r *renderer) renderSignature(p *parser.ClassParser, structure *parser.Struct, methods *parser.LineStringBuilder) {
+	if structure.Signature == nil || !p.RenderingOptions.Methods {
+		return
+	}
+	methods.WriteLineWithDepth(2, r.formatType(structure.Signature.String()))
+}
+
+func (r *renderer) renderStructMethods(p *parser.ClassParser, structure *parser.Struct, privateMethods *parser.LineStringBuilder, publicMethods *parser.LineStringBuilder) {
+	if structure.Type == "interface" && p.RenderingOptions.HideInterfaceMethods {
+		return
+	}
+	groupByVisibility := p.RenderingOptions.MemberOrder.GroupsByVisibility()
+	rendered, hidden := 0, 0
+	for _, method := range structure.SortedFunctions(p.RenderingOptions.MemberOrder) {
 		accessModifier := "+"
-		if unicode.IsLower(rune(method.Name[0])) {
+		if !method.IsExported() {
 			if !p.RenderingOptions.PrivateMembers {
 				continue
 			}
 
 			accessModifier = "-"
 		}
+		if max := p.RenderingOptions.MaxMembers; max > 0 && rendered >= max {
+			hidden++
+			continue
+		}
+		rendered++
 		parameterList := make([]string, 0)
-		for _, p := range method.Parameters {
-			parameterList = append(parameterList, fmt.Sprintf("%s %s", p.Name, r.underscore(p.Type)))
+		for _, parameter := range method.Parameters {
+			parameterList = append(parameterList, r.formatType(p.RenderingOptions.MemberString(parameter)))
 		}
 		returnValues := ""
 		if len(method.ReturnValues) > 0 {
-			if len(method.ReturnValues) == 1 {
-				returnValues = r.underscore(method.ReturnValues[0])
+			if len(method.ReturnValues) == 1 && !method.HasNamedReturnValues() {
+				returnValues = r.formatType(method.ReturnValues[0])
 			} else {
-				returnValues = fmt.Sprintf("(%s)", r.underscore(strings.Join(method.ReturnValues, ", ")))
+				returnValues = fmt.Sprintf("(%s)", r.formatType(strings.Join(method.NamedReturnValues(), ", ")))
 			}
 		}
-		if accessModifier == "-" {
-			privateMethods.WriteLineWithDepth(2, fmt.Sprintf(`%s%s(%s) %s`, accessModifier, method.Name, strings.Join(parameterList, ", "), returnValues))
+		methodName := method.Name
+		if method.PointerReceiver && p.RenderingOptions.ShowReceiverKind {
+			methodName = "*" + methodName
+		}
+		if accessModifier == "-" && groupByVisibility {
+			privateMethods.WriteLineWithDepth(2, fmt.Sprintf(`%s%s(%s) %s`, accessModifier, methodName, strings.Join(parameterList, ", "), returnValues))
 		} else {
-			publicMethods.WriteLineWithDepth(2, fmt.Sprintf(`%s%s(%s) %s`, accessModifier, method.Name, strings.Join(parameterList, ", "), returnValues))
+			publicMethods.WriteLineWithDepth(2, fmt.Sprintf(`%s%s(%s) %s`, accessModifier, methodName, strings.Join(parameterList, ", "), returnValues))
 		}
 	}
+	if hidden > 0 {
+		publicMethods.WriteLineWithDepth(2, render.MoreMembers(hidden))
+	}
 }
 
 func (r *renderer) renderStructFields(p *parser.ClassParser, structure *parser.Struct, privateFields, publicFields *parser.LineStringBuilder) {
-	for _, field := range structure.Fields {
+	groupByVisibility := p.RenderingOptions.MemberOrder.GroupsByVisibility()
+	rendered, hidden := 0, 0
+	for _, field := range structure.SortedFields(p.RenderingOptions.MemberOrder) {
+		if field.Embedded && p.RenderingOptions.EmbeddedAsComposition {
+			continue
+		}
 		accessModifier := "+"
-		if unicode.IsLower(rune(field.Name[0])) {
+		if !field.IsExported() {
 			if !p.RenderingOptions.PrivateMembers {
 				continue
 			}
 
 			accessModifier = "-"
 		}
-		if accessModifier == "-" {
-			privateFields.WriteLineWithDepth(2, fmt.Sprintf(`%s%s %s`, accessModifier, field.Name, strings.ReplaceAll(r.underscore(field.Type), "{}", "")))
+		if max := p.RenderingOptions.MaxMembers; max > 0 && rendered >= max {
+			hidden++
+			continue
+		}
+		rendered++
+		line := fmt.Sprintf(`%s%s %s%s`, accessModifier, field.Name, r.formatType(p.RenderingOptions.MemberType(field)), r.fieldComment(p, field))
+		if accessModifier == "-" && groupByVisibility {
+			privateFields.WriteLineWithDepth(2, line)
 		} else {
-			publicFields.WriteLineWithDepth(2, fmt.Sprintf(`%s%s %s`, accessModifier, field.Name, strings.ReplaceAll(r.underscore(field.Type), "{}", "")))
+			publicFields.WriteLineWithDepth(2, line)
 		}
 	}
+	if hidden > 0 {
+		// The parentheses of the line are escaped so mermaid does not read it as a method
+		publicFields.WriteLineWithDepth(2, r.formatType(render.MoreMembers(hidden)))
+	}
+}
+
+// fieldComment returns the comment rendered after the given field, with the characters that would end the class or be
+// read as markup escaped. It is empty unless FieldComments is set
+func (r *renderer) fieldComment(p *parser.ClassParser, field *parser.Field) string {
+	if !p.RenderingOptions.FieldComments || field.Comment == "" {
+		return ""
+	}
+	return " : " + fieldCommentEscaper.Replace(field.Comment)
 }
 
-func (r *renderer) renderAliases(p *parser.ClassParser, str *parser.LineStringBuilder) {
+func (r *renderer) renderAliases(p *parser.ClassParser, str *parser.LineWriter, nodes *nodeLabels) {
 	aliasString := ""
+	derivesFromString := ""
 	if p.RenderingOptions.ConnectionLabels {
 		aliasString = aliasOf
+		derivesFromString = derivesFrom
 	}
 	orderedAliases := parser.AliasSlice{}
 	for _, alias := range p.AllAliases {
 		orderedAliases = append(orderedAliases, *alias)
 	}
 	sort.Sort(orderedAliases)
+	cycles := render.AliasCycles(p)
 	for _, alias := range orderedAliases {
+		if !p.RenderingOptions.IsIncluded(alias.Name) || !p.RenderingOptions.IsIncluded(alias.AliasOf) {
+			continue
+		}
 		aliasName := alias.Name
-		if strings.Count(alias.Name, ".") > 1 {
+		if cycle, ok := cycles[alias.AliasOf]; ok {
+			// A cycle is drawn with the connection of its first type alone, see renderAliasCycles
+			if alias.AliasOf != cycle[0] {
+				continue
+			}
+			aliasName = cycle[1%len(cycle)]
+		} else if strings.Count(alias.Name, ".") > 1 {
 			split := strings.SplitN(alias.Name, ".", 2)
 			if aliasRename, ok := p.AllRenamedStructs[split[0]]; ok {
 				renamed := parser.GenerateRenamedStructName(split[1])
@@ -264,6 +589,86 @@ func (r *renderer) renderAliases(p *parser.ClassParser, str *parser.LineStringBu
 				}
 			}
 		}
-		str.WriteLineWithDepth(0, fmt.Sprintf(`%s .. %s : %s`, r.underscore(aliasName), r.underscore(alias.AliasOf), aliasString))
+		nodes.reference(aliasName)
+		nodes.reference(alias.AliasOf)
+		if alias.DefinedType {
+			str.WriteLineWithDepth(0, fmt.Sprintf(`%s --> %s : %s`, render.NodeID(alias.AliasOf), render.NodeID(aliasName), r.typeArgumentsLabel(derivesFromString, alias)))
+		} else {
+			str.WriteLineWithDepth(0, fmt.Sprintf(`%s .. %s : %s`, render.NodeID(aliasName), render.NodeID(alias.AliasOf), r.typeArgumentsLabel(aliasString, alias)))
+		}
+	}
+	r.renderAliasCycles(p, cycles, str, nodes)
+}
+
+// renderAliasCycles renders a note for the first type of every cycle of aliases, which lists the types of the cycle
+func (r *renderer) renderAliasCycles(p *parser.ClassParser, cycles map[string][]string, str *parser.LineWriter, nodes *nodeLabels) {
+	var names []string
+	for name, cycle := range cycles {
+		if name == cycle[0] && p.RenderingOptions.IsIncluded(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		nodes.reference(name)
+		str.WriteLineWithDepth(1, fmt.Sprintf(`note for %s "%s"`, render.NodeID(name), docCommentEscaper.Replace(render.AliasCycleNote(p.RenderingOptions.PackageAliases, cycles[name]))))
+	}
+}
+
+// typeArgumentsLabel returns the label of the connection of the given alias, followed by its type arguments when it is
+// an instantiation of a generic type
+func (r *renderer) typeArgumentsLabel(connectionLabel string, alias parser.Alias) string {
+	if len(alias.TypeArguments) == 0 {
+		return connectionLabel
+	}
+	return strings.TrimSpace(fmt.Sprintf("%s [%s]", connectionLabel, r.formatType(strings.Join(alias.TypeArguments, ", "))))
+}
+
+// nodeLabels keeps track of the nodes rendered in a diagram, so the ones that are only referenced by a connection can
+// be given a label too
+type nodeLabels struct {
+	declared   map[string]struct{}
+	referenced map[string]struct{}
+	// flat holds the labels of the classes keyed by their fully qualified name when the packages are flattened
+	flat map[string]string
+	// aliases holds the PackageAliases the labels are displayed with
+	aliases map[string]string
+}
+
+func newNodeLabels() *nodeLabels {
+	return &nodeLabels{
+		declared:   map[string]struct{}{},
+		referenced: map[string]struct{}{},
+	}
+}
+
+// declare records a node rendered as a class, which already has its label
+func (n *nodeLabels) declare(name string) {
+	n.declared[name] = struct{}{}
+}
+
+// label returns the label of the class with the given fully qualified name, which is the name itself, with its package
+// displayed with the aliases, unless the packages are flattened
+func (n *nodeLabels) label(name string) string {
+	if label, ok := n.flat[name]; ok {
+		name = label
+	}
+	return render.DisplayName(n.aliases, name)
+}
+
+// reference records a node used by a connection
+func (n *nodeLabels) reference(name string) {
+	n.referenced[name] = struct{}{}
+}
+
+// undeclared returns the sorted names of the nodes referenced by a connection that were not rendered as a class
+func (n *nodeLabels) undeclared() []string {
+	result := make([]string, 0)
+	for name := range n.referenced {
+		if _, ok := n.declared[name]; !ok {
+			result = append(result, name)
+		}
 	}
+	sort.Strings(result)
+	return result
 }
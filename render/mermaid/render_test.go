@@ -0,0 +1,425 @@
+package mermaid
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jfeliu007/goplantuml/parser"
+)
+
+func TestRenderEscapesMembers(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = p.ParseSource("escaped.go", []byte(`package escaped
+
+type Box[T interface{ ~int | ~string }] struct {
+	Value   T
+	Lookup  map[string]interface{}
+	Handler func(int) (string, error)
+	Anon    struct{ A int }
+	Log     func(...any)
+	Wrap    func(func(...string), ...func(...int))
+}
+
+func (b *Box[T]) Get(f func() error) func() error {
+	return f
+}
+
+func (b *Box[T]) Each(fns ...func(...any)) {}
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	result := NewRender().Render(p)
+	tt := []struct {
+		name     string
+		expected string
+	}{
+		{name: "Generic", expected: "~T interface#123;#126;int | #126;string#125;~"},
+		{name: "Map", expected: "+Lookup map[string]interface\n"},
+		{name: "Function", expected: "+Handler func#40;int#41; #40;string, error#41;"},
+		{name: "Struct", expected: "+Anon struct#123;int#125;"},
+		{name: "Method", expected: "+Get(f func#40;#41; error) func#40;#41; error"},
+		{name: "Variadic", expected: "+Log func#40;...any#41;"},
+		{name: "NestedVariadic", expected: "+Wrap func#40;func#40;...string#41; , ...func#40;...int#41; #41;"},
+		{name: "VariadicMethod", expected: "+Each(fns ...func#40;...any#41; )"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if !strings.Contains(result, tc.expected) {
+				t.Errorf("Expected the diagram to contain %q, got\n%s", tc.expected, result)
+			}
+		})
+	}
+}
+
+var update = flag.Bool("update", false, "updates the golden files with the rendered diagrams")
+
+func TestRenderInterfaceGolden(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{
+		Options: []parser.Option{parser.WithImplementations(true)},
+	})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = p.ParseSource("shapes.go", []byte(`package shapes
+
+type Shape interface {
+	Area() float64
+	Perimeter() float64
+}
+
+type Square struct {
+	Side float64
+}
+
+func (s Square) Area() float64 {
+	return s.Side * s.Side
+}
+
+func (s Square) Perimeter() float64 {
+	return 4 * s.Side
+}
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	result := NewRender().Render(p)
+	golden := filepath.Join("testdata", "interface.golden")
+	if *update {
+		if err := ioutil.WriteFile(golden, []byte(result), 0644); err != nil {
+			t.Fatalf("Expected no errors, got %s", err.Error())
+		}
+	}
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	if result != string(expected) {
+		t.Errorf("Expected the diagram to match %s, got\n%s", golden, result)
+	}
+}
+
+func TestRenderMarkdownFence(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{
+		Options: []parser.Option{parser.WithMarkdownFence(true)},
+	})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = p.ParseSource("shapes.go", []byte(`package shapes
+
+type Square struct {
+	Side float64
+}
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	result := NewRender().Render(p)
+	if !strings.HasPrefix(result, "```mermaid\nclassDiagram\n") || !strings.HasSuffix(result, "\n```\n") {
+		t.Errorf("Expected the diagram to be fenced, got\n%s", result)
+	}
+}
+
+func TestRenderUnnamedParameters(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = p.ParseSource("doer.go", []byte(`package doer
+
+import "context"
+
+type Doer interface {
+	Do(context.Context, []byte) error
+	DoNamed(ctx context.Context, data []byte) error
+}
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	result := NewRender().Render(p)
+	for _, expected := range []string{
+		"+Do(context_Context, []byte) error\n",
+		"+DoNamed(ctx context_Context, data []byte) error\n",
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected the diagram to contain %q, got\n%s", expected, result)
+		}
+	}
+}
+
+func TestRenderConstraintEdges(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{
+		Options: []parser.Option{parser.WithConstraintEdges(true), parser.WithConnectionLabels(true)},
+	})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = p.ParseSource("shapes.go", []byte(`package shapes
+
+type Shape interface {
+	Area() float64
+}
+
+type Box[T Shape] struct {
+	Items []T
+}
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	result := NewRender().Render(p)
+	if expected := "__shapes__Box ..> __shapes__Shape : ConstrainedBy"; !strings.Contains(result, expected) {
+		t.Errorf("Expected the diagram to contain %q, got\n%s", expected, result)
+	}
+}
+
+func TestRenderMaxMembers(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{
+		Options: []parser.Option{parser.WithMemberOrder(parser.MemberOrderSource), parser.WithMaxMembers(1)},
+	})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = p.ParseSource("big.go", []byte(`package big
+
+type Big struct {
+	A int
+	B int
+	C int
+}
+
+func (b *Big) One() {}
+func (b *Big) Two() {}
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	result := NewRender().Render(p)
+	for _, expected := range []string{"+A int\n", "... #40;+2 more#41;\n", "+One() \n", "... (+1 more)\n"} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected the diagram to contain %q, got\n%s", expected, result)
+		}
+	}
+	if strings.Contains(result, "+B int") || strings.Contains(result, "+Two()") {
+		t.Errorf("Expected the members past the limit not to be rendered, got\n%s", result)
+	}
+}
+
+func TestRenderQualifiedMemberTypes(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{
+		Options: []parser.Option{parser.WithQualifiedMemberTypes(true)},
+	})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = p.ParseSource("shop.go", []byte(`package shop
+
+type Order struct {
+	Total int
+}
+
+type Cart struct {
+	Orders []*Order
+}
+
+func (c *Cart) Add(o Order) {}
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	result := NewRender().Render(p)
+	for _, expected := range []string{"+Orders []*_shop_Order\n", "+Add(o _shop_Order) "} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected the diagram to contain %q, got\n%s", expected, result)
+		}
+	}
+}
+
+// writeFiles writes the given files, keyed by their path relative to a new temporary directory, and returns that
+// directory
+func writeFiles(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, content := range files {
+		name = filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatalf("Expected no errors, got %s", err.Error())
+		}
+		if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatalf("Expected no errors, got %s", err.Error())
+		}
+	}
+	return root
+}
+
+func TestRenderDistinctNodeIDs(t *testing.T) {
+	m := writeFiles(t, map[string]string{
+		"go.mod":     "module example.com/m\n",
+		"a/b/b.go":   "package b\n\ntype T struct{}\n",
+		"a_b/a_b.go": "package a_b\n\ntype T struct{}\n",
+	})
+	tt := []struct {
+		name     string
+		options  []parser.Option
+		expected []string
+	}{
+		{
+			name: "Classes",
+			expected: []string{
+				`class example__com__m__a__b__T["example.com.m.a.b.T"] {`,
+				`class example__com__m__a_ub__T["example.com.m.a_b.T"] {`,
+			},
+		},
+		{
+			name:    "ImportGraph",
+			options: []parser.Option{parser.WithImportGraph(true)},
+			expected: []string{
+				`example__com__m__a__b["example.com.m.a.b"]`,
+				`example__com__m__a_ub["example.com.m.a_b"]`,
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := parser.NewClassDiagramFromDirectories([]string{filepath.Join(m, "a", "b"), filepath.Join(m, "a_b")}, tc.options...)
+			if err != nil {
+				t.Fatalf("Expected no errors, got %s", err.Error())
+			}
+			result := NewRender().Render(p)
+			for _, expected := range tc.expected {
+				if !strings.Contains(result, expected) {
+					t.Errorf("Expected the diagram to contain %q, got\n%s", expected, result)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderMermaidNamespaces(t *testing.T) {
+	source := []byte(`package shapes
+
+// Square has four sides
+type Square struct {
+	Side float64
+}
+`)
+	tt := []struct {
+		name     string
+		options  []parser.Option
+		expected string
+	}{
+		{
+			name:     "Namespaces",
+			options:  []parser.Option{parser.WithMermaidNamespaces(true), parser.WithDocComments(true)},
+			expected: `classDiagram
+namespace __shapes {
+    class __shapes__Square[".shapes.Square"] {
+        <<class>>
+        +Side float64
+
+    }
+}
+    note for __shapes__Square "Square has four sides"
+
+
+`,
+		},
+		{
+			name:     "FlattenPackages",
+			options:  []parser.Option{parser.WithMermaidNamespaces(true), parser.WithDocComments(true), parser.WithFlattenPackages(true)},
+			expected: `classDiagram
+    class __shapes__Square["Square"] {
+        <<class>>
+        +Side float64
+
+    }
+    note for __shapes__Square "Square has four sides"
+
+
+`,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{Options: tc.options})
+			if err != nil {
+				t.Fatalf("Expected no errors, got %s", err.Error())
+			}
+			if err = p.ParseSource("shapes.go", source); err != nil {
+				t.Fatalf("Expected no errors, got %s", err.Error())
+			}
+			if result := NewRender().Render(p); result != tc.expected {
+				t.Errorf("Expected\n%s\ngot\n%s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestRenderStereotypes(t *testing.T) {
+	source := []byte(`package shapes
+
+type Square struct {
+	Side float64
+}
+
+type Kind string
+
+const Big Kind = "big"
+`)
+	stereotypes := map[string]string{"class": "<<entity>>", "type": "<<value>>"}
+	tt := []struct {
+		name     string
+		options  []parser.Option
+		expected []string
+	}{
+		{
+			name: "Default",
+			expected: []string{
+				"class __shapes__Square[\".shapes.Square\"] {\n        <<class>>\n",
+				"class __shapes__Kind[\".shapes.Kind\"] {\n        <<type>>\n",
+			},
+		},
+		{
+			name:    "Stereotypes",
+			options: []parser.Option{parser.WithStereotypes(stereotypes)},
+			expected: []string{
+				"class __shapes__Square[\".shapes.Square\"] {\n        <<entity>>\n",
+				"class __shapes__Kind[\".shapes.Kind\"] {\n        <<value>>\n",
+			},
+		},
+		{
+			// The enumeration annotation is kept, since the constants are only rendered along with it
+			name:    "Constants",
+			options: []parser.Option{parser.WithStereotypes(stereotypes), parser.WithConstants(true)},
+			expected: []string{
+				"class __shapes__Square[\".shapes.Square\"] {\n        <<entity>>\n",
+				"class __shapes__Kind[\".shapes.Kind\"] {\n        <<enumeration>>\n        Big\n",
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{Options: tc.options})
+			if err != nil {
+				t.Fatalf("Expected no errors, got %s", err.Error())
+			}
+			if err = p.ParseSource("shapes.go", source); err != nil {
+				t.Fatalf("Expected no errors, got %s", err.Error())
+			}
+			result := NewRender().Render(p)
+			for _, expected := range tc.expected {
+				if !strings.Contains(result, expected) {
+					t.Errorf("Expected the diagram to contain %q, got\n%s", expected, result)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package render
+
+import (
+	"strings"
+
+	"github.com/jfeliu007/goplantuml/parser"
+)
+
+// FlatNames returns the labels of the rendered structures when the packages are flattened, keyed by their fully
+// qualified name, given the included structures keyed by package as AllIncludedStructures returns them. Structures are
+// labeled with their name alone, unless a structure of another package has the same name, in which case both are
+// labeled with their fully qualified name
+func FlatNames(included map[string]map[string]*parser.Struct) map[string]string {
+	packages := map[string]map[string]struct{}{}
+	fullNames := map[string]string{}
+	for pack, structures := range included {
+		for name := range structures {
+			short := strings.TrimPrefix(name, pack+".")
+			fullName := pack + "." + short
+			fullNames[fullName] = short
+			if packages[short] == nil {
+				packages[short] = map[string]struct{}{}
+			}
+			packages[short][pack] = struct{}{}
+		}
+	}
+	result := make(map[string]string, len(fullNames))
+	for fullName, short := range fullNames {
+		result[fullName] = short
+		if len(packages[short]) > 1 {
+			result[fullName] = fullName
+		}
+	}
+	return result
+}
+
+// DisplayPackage returns the name the given package is displayed with, once the longest of the given package
+// prefixes it starts with is replaced with its alias. A prefix aliased with an empty string is removed along with the
+// dot that follows it, but a package that would be left without a name keeps its own
+func DisplayPackage(aliases map[string]string, pack string) string {
+	prefix, alias, ok := packageAlias(aliases, pack)
+	if !ok {
+		return pack
+	}
+	rest := pack[len(prefix):]
+	if alias == "" {
+		if rest == "" {
+			return pack
+		}
+		return rest[1:]
+	}
+	return alias + rest
+}
+
+// DisplayName returns the fully qualified name the type with the given fully qualified name is displayed with, that is
+// with its package displayed as DisplayPackage does, so the types are named the same way as their namespaces
+func DisplayName(aliases map[string]string, fullName string) string {
+	prefix, alias, ok := packageAlias(aliases, fullName)
+	if !ok {
+		return fullName
+	}
+	rest := fullName[len(prefix):]
+	if alias == "" {
+		if rest == "" || !strings.Contains(rest[1:], ".") {
+			// The type is in the aliased package itself, which keeps its name
+			return fullName
+		}
+		return rest[1:]
+	}
+	return alias + rest
+}
+
+// packageAlias returns the longest of the given package prefixes the given name starts with, followed by a dot unless
+// it is the whole name, along with its alias
+func packageAlias(aliases map[string]string, name string) (string, string, bool) {
+	longest := ""
+	found := false
+	for prefix := range aliases {
+		if prefix == "" || len(prefix) <= len(longest) {
+			continue
+		}
+		if name == prefix || strings.HasPrefix(name, prefix+".") {
+			longest = prefix
+			found = true
+		}
+	}
+	return longest, aliases[longest], found
+}
+
+// AliasCycles returns the cycles of aliases and defined types found by p.AliasCycles keyed by every type they hold.
+// The renderers draw a single connection for each of them, from its first type to the second one, along with a note
+// reading the cycle, instead of a connection for every type
+func AliasCycles(p *parser.ClassParser) map[string][]string {
+	result := map[string][]string{}
+	for _, cycle := range p.AliasCycles() {
+		for _, name := range cycle {
+			result[name] = cycle
+		}
+	}
+	return result
+}
+
+// AliasCycleNote returns the text of the note of the given cycle of aliases, which lists its types in the order they
+// refer to each other, back to the first one
+func AliasCycleNote(aliases map[string]string, cycle []string) string {
+	names := make([]string, 0, len(cycle)+1)
+	for _, name := range cycle {
+		names = append(names, DisplayName(aliases, name))
+	}
+	names = append(names, names[0])
+	return "alias cycle: " + strings.Join(names, " -> ")
+}
@@ -0,0 +1,28 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// NodeID returns the identifier of the node of the given name. Letters and digits are kept while every other character
+// is escaped after an underscore, so two different names never get the same identifier: dots are written as __,
+// underscores as _u and the rest as _x followed by their hexadecimal code and an underscore. The name itself is
+// rendered as the label of the node
+func NodeID(name string) string {
+	id := &strings.Builder{}
+	for _, c := range name {
+		switch {
+		case c < unicode.MaxASCII && (unicode.IsLetter(c) || unicode.IsDigit(c)):
+			id.WriteRune(c)
+		case c == '.':
+			id.WriteString("__")
+		case c == '_':
+			id.WriteString("_u")
+		default:
+			fmt.Fprintf(id, "_x%x_", c)
+		}
+	}
+	return id.String()
+}
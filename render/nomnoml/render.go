@@ -0,0 +1,254 @@
+package nomnoml
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/jfeliu007/goplantuml/parser"
+	"github.com/jfeliu007/goplantuml/render"
+)
+
+// The association arrows of the relationships
+const (
+	composition    = "->"
+	generalization = "-|>"
+	realization    = "-:>"
+	aggregation    = "o->"
+	alias          = "-->"
+)
+
+// escaper escapes the characters that nomnoml reads as the end of a class, a compartment or a line, so the members
+// holding them are rendered literally
+var escaper = strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`, "|", `\|`, ";", `\;`, "\n", " ")
+
+type renderer struct {
+}
+
+var _ render.Renderer = (*renderer)(nil)
+
+func init() {
+	render.Register("nomnoml", func() render.Renderer { return NewRender() })
+}
+
+// NewRender returns a renderer that writes nomnoml, as read by nomnoml.com. Every type is a [Name| fields| methods]
+// block named after its fully qualified name, and every connection is an association between two blocks. The types of
+// other packages that are connected to the rendered ones are drawn by nomnoml as blocks with no members.
+func NewRender() *renderer {
+	return &renderer{}
+}
+
+func (r *renderer) Render(p *parser.ClassParser) string {
+	str := &strings.Builder{}
+	// Writing into a strings.Builder never fails
+	_ = r.RenderTo(str, p)
+	return str.String()
+}
+
+func (r *renderer) RenderTo(w io.Writer, p *parser.ClassParser) error {
+	str := parser.NewLineWriterWithIndent(w, p.RenderingOptions.Indent)
+	if p.RenderingOptions.Title != "" {
+		str.WriteLineWithDepth(0, fmt.Sprintf(`#title: %s`, escaper.Replace(p.RenderingOptions.Title)))
+	}
+	var packages []string
+	for pack := range p.Structure {
+		packages = append(packages, pack)
+	}
+	sort.Strings(packages)
+	included := p.AllIncludedStructures()
+	var associations []string
+	for _, pack := range packages {
+		structures := included[pack]
+		var names []string
+		for name := range structures {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			structure := structures[name]
+			id := r.fullName(pack, name)
+			str.WriteLineWithDepth(0, r.class(p, id, structure))
+			associations = append(associations, r.associations(p, id, structure)...)
+		}
+	}
+	if p.RenderingOptions.Aliases {
+		associations = append(associations, r.aliasAssociations(p)...)
+	}
+	for _, association := range associations {
+		str.WriteLineWithDepth(0, association)
+	}
+	return str.Err()
+}
+
+// class returns the block of the given structure, with its fields and methods in compartments of their own. The
+// compartments that are not rendered with the current options are left out
+func (r *renderer) class(p *parser.ClassParser, id string, structure *parser.Struct) string {
+	compartments := []string{r.label(id, structure)}
+	if p.RenderingOptions.Fields {
+		compartments = append(compartments, strings.Join(r.fields(p, structure), ";"))
+	}
+	if p.RenderingOptions.Methods {
+		compartments = append(compartments, strings.Join(r.methods(p, structure), ";"))
+	}
+	return fmt.Sprintf("[%s]", strings.Join(compartments, "|"))
+}
+
+// label returns the name of the block of the given structure, prefixed with the classifier interfaces are drawn with
+func (r *renderer) label(id string, structure *parser.Struct) string {
+	if structure.Type == "interface" {
+		return "<abstract> " + escaper.Replace(id)
+	}
+	return escaper.Replace(id)
+}
+
+// fields returns the fields of the structure that are rendered with the current options
+func (r *renderer) fields(p *parser.ClassParser, structure *parser.Struct) []string {
+	result := []string{}
+	for _, element := range structure.TypeSet {
+		result = append(result, escaper.Replace(element))
+	}
+	var fields []string
+	for _, field := range structure.SortedFields(p.RenderingOptions.MemberOrder) {
+		if field.Embedded && p.RenderingOptions.EmbeddedAsComposition {
+			continue
+		}
+		accessModifier := "+"
+		if !field.IsExported() {
+			if !p.RenderingOptions.PrivateMembers {
+				continue
+			}
+			accessModifier = "-"
+		}
+		fields = append(fields, accessModifier+escaper.Replace(strings.TrimSpace(field.String())))
+	}
+	return append(result, r.limit(p, fields)...)
+}
+
+// methods returns the methods of the structure that are rendered with the current options
+func (r *renderer) methods(p *parser.ClassParser, structure *parser.Struct) []string {
+	result := []string{}
+	if structure.Type == "interface" && p.RenderingOptions.HideInterfaceMethods {
+		return result
+	}
+	if structure.Signature != nil {
+		result = append(result, escaper.Replace(structure.Signature.String()))
+	}
+	var methods []string
+	for _, method := range structure.SortedFunctions(p.RenderingOptions.MemberOrder) {
+		accessModifier := "+"
+		if !method.IsExported() {
+			if !p.RenderingOptions.PrivateMembers {
+				continue
+			}
+			accessModifier = "-"
+		}
+		methods = append(methods, accessModifier+escaper.Replace(method.String()))
+	}
+	return append(result, r.limit(p, methods)...)
+}
+
+// limit returns the first MaxMembers of the given members, followed by a line counting the ones left out
+func (r *renderer) limit(p *parser.ClassParser, members []string) []string {
+	max := p.RenderingOptions.MaxMembers
+	if max <= 0 || len(members) <= max {
+		return members
+	}
+	return append(members[:max], render.MoreMembers(len(members)-max))
+}
+
+// associations returns the compositions, realizations and aggregations of the given structure that are rendered with
+// the current options, sorted by relationship and target
+func (r *renderer) associations(p *parser.ClassParser, id string, structure *parser.Struct) []string {
+	ro := p.RenderingOptions
+	var result []string
+	add := func(targets map[string]struct{}, arrow string) {
+		var sorted []string
+		for target := range targets {
+			if !ro.BuiltinAggregations && p.GetPackageName(target, structure) == parser.BuiltinPackageName {
+				continue
+			}
+			target, _ = p.ResolveType(target, structure)
+			if !ro.IsIncluded(target) || ro.IsSelfReference(id, target) {
+				continue
+			}
+			sorted = append(sorted, target)
+		}
+		sort.Strings(sorted)
+		for _, target := range sorted {
+			result = append(result, r.association(id, arrow, target))
+		}
+	}
+	if ro.Compositions {
+		add(structure.Composition, composition)
+	}
+	if ro.Implementations {
+		arrow := realization
+		if structure.ExtendsRelationship() == parser.Generalization {
+			arrow = generalization
+		}
+		add(structure.Extends, arrow)
+	}
+	if ro.Aggregations {
+		aggregations := make(map[string]struct{}, len(structure.Aggregations))
+		for target := range structure.Aggregations {
+			aggregations[target] = struct{}{}
+		}
+		if ro.AggregatePrivateMembers {
+			for target := range structure.PrivateAggregations {
+				aggregations[target] = struct{}{}
+			}
+		}
+		if ro.BuiltinAggregations {
+			for _, target := range structure.AggregatedBuiltinTypes(ro.AggregatePrivateMembers) {
+				aggregations[target] = struct{}{}
+			}
+		}
+		// With SmartRelationships the types held by value are owned by the structure, so they are composed instead
+		values := map[string]struct{}{}
+		if ro.SmartRelationships {
+			for target := range aggregations {
+				if structure.HoldsByValue(target) {
+					values[target] = struct{}{}
+					delete(aggregations, target)
+				}
+			}
+		}
+		add(aggregations, aggregation)
+		add(values, composition)
+	}
+	return result
+}
+
+// aliasAssociations returns an association from every alias to the type it is an alias of, and from every defined
+// type to the type it is defined from
+func (r *renderer) aliasAssociations(p *parser.ClassParser) []string {
+	orderedAliases := parser.AliasSlice{}
+	for _, a := range p.AllAliases {
+		orderedAliases = append(orderedAliases, *a)
+	}
+	sort.Sort(orderedAliases)
+	var result []string
+	for _, a := range orderedAliases {
+		if !p.RenderingOptions.IsIncluded(a.Name) || !p.RenderingOptions.IsIncluded(a.AliasOf) {
+			continue
+		}
+		// The types of the current package are named pack..Name in the aliases
+		result = append(result, r.association(a.AliasOf, alias, strings.Replace(a.Name, "..", ".", 1)))
+	}
+	return result
+}
+
+// association returns the given arrow between the blocks with the given names
+func (r *renderer) association(source string, arrow string, target string) string {
+	return fmt.Sprintf("[%s]%s[%s]", escaper.Replace(source), arrow, escaper.Replace(target))
+}
+
+// fullName returns the fully qualified name of the structure with the given name in the given package. Defined types
+// are already stored with their package prefix
+func (r *renderer) fullName(pack string, name string) string {
+	if strings.HasPrefix(name, pack+".") {
+		return name
+	}
+	return pack + "." + name
+}
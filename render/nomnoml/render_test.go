@@ -0,0 +1,116 @@
+package nomnoml
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jfeliu007/goplantuml/parser"
+)
+
+const source = `package shapes
+
+type Shape interface {
+	Area() float64
+}
+
+type Point struct {
+	X int
+	Y int
+}
+
+type Square struct {
+	Point
+	corner *Point
+	Sides  map[string]int
+	Parent *Square
+	Label  func(int) []string
+}
+
+func (s *Square) Area() float64 {
+	return 0
+}
+
+func (s *Square) scale(f float64) {}
+
+type Size = Square
+`
+
+func TestRender(t *testing.T) {
+	tt := []struct {
+		name       string
+		options    []parser.Option
+		expected   []string
+		unexpected []string
+	}{
+		{
+			name:    "Default",
+			options: []parser.Option{parser.WithAggregations(true), parser.WithAliases(true), parser.WithTitle("Shapes")},
+			expected: []string{
+				"#title: Shapes\n",
+				"[<abstract> .shapes.Shape||+Area() float64]\n",
+				"[.shapes.Point|+X int;+Y int|]\n",
+				`[.shapes.Square|+Sides map\[string\]int;+Parent *.Square;+Label func(int) \[\]string|+Area() float64]` + "\n",
+				"[.shapes.Square]->[.shapes.Point]\n",
+				"[.shapes.Square]-:>[.shapes.Shape]\n",
+				"[.shapes.Square]o->[.shapes.Square]\n",
+				"[.shapes.Size]-->[.shapes.Square]\n",
+			},
+			unexpected: []string{"corner", "scale", "[.shapes.Square]o->[.shapes.Point]"},
+		},
+		{
+			name:     "PrivateMembers",
+			options:  []parser.Option{parser.WithPrivateMembers(true), parser.WithAggregatePrivateMembers(true), parser.WithAggregations(true)},
+			expected: []string{"-corner *.Point;", "-scale(f float64)]", "[.shapes.Square]o->[.shapes.Point]\n"},
+		},
+		{
+			name: "HiddenConnections",
+			options: []parser.Option{
+				parser.WithCompositions(false),
+				parser.WithImplementations(false),
+				parser.WithAliases(false),
+				parser.WithFields(false),
+				parser.WithMethods(false),
+			},
+			expected:   []string{"[<abstract> .shapes.Shape]\n", "[.shapes.Square]\n"},
+			unexpected: []string{"->", "-:>", "-->", "+Area"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{Options: tc.options})
+			if err != nil {
+				t.Fatalf("Expected no errors, got %s", err.Error())
+			}
+			if err = p.ParseSource("shapes.go", []byte(source)); err != nil {
+				t.Fatalf("Expected no errors, got %s", err.Error())
+			}
+			result := NewRender().Render(p)
+			for _, expected := range tc.expected {
+				if !strings.Contains(result, expected) {
+					t.Errorf("Expected the diagram to contain %q, got\n%s", expected, result)
+				}
+			}
+			for _, unexpected := range tc.unexpected {
+				if strings.Contains(result, unexpected) {
+					t.Errorf("Expected the diagram not to contain %q, got\n%s", unexpected, result)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderMaxMembers(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{
+		Options: []parser.Option{parser.WithMemberOrder(parser.MemberOrderSource), parser.WithMaxMembers(1)},
+	})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	if err = p.ParseSource("shapes.go", []byte(source)); err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	result := NewRender().Render(p)
+	if expected := "[.shapes.Square|+Sides map\\[string\\]int;... (+2 more)|+Area() float64]\n"; !strings.Contains(result, expected) {
+		t.Errorf("Expected the diagram to contain %q, got\n%s", expected, result)
+	}
+}
@@ -2,9 +2,12 @@ package plantuml
 
 import (
 	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"regexp"
 	"sort"
 	"strings"
-	"unicode"
 
 	"github.com/AvraamMavridis/randomcolor"
 	"github.com/jfeliu007/goplantuml/parser"
@@ -15,25 +18,187 @@ const implements = `"implements"`
 const extends = `"extends"`
 const aggregates = `"uses"`
 const aliasOf = `"alias of"`
+const derivesFrom = `"derives from"`
+const constrainedBy = `"constrained by"`
 const nodeSep = "skinparam nodesep 500"
 const ranskSep = "skinparam ranksep 1500"
 
+const docCommentWidth = 80
+
+// signatureIndent is the indentation of the continuation lines of the method signatures wrapped with
+// MaxSignatureWidth
+const signatureIndent = "    "
+
+var docCommentEscaper = strings.NewReplacer("~", "~~", "**", "~**", "//", "~//", `""`, `~""`, "--", "~--", "__", "~__", "<", "~<")
+
+// memberEscaper escapes the names and types rendered in the classes, which are read as creole like the doc comments, so
+// **T or a__b are not rendered in bold or underlined. [[ would start a link too, as in map[[2]int]string
+var memberEscaper = strings.NewReplacer("~", "~~", "**", "~**", "//", "~//", `""`, `~""`, "--", "~--", "__", "~__", "<", "~<", "[[", "[~[")
+
+// connectors holds the arrows the connections of every kind are drawn with, unless RenderingOptions.Connectors replaces
+// them
+var connectors = map[string]string{
+	"composition": "*--",
+	"extends":     "<|--",
+	"realization": "<|..",
+	"aggregation": "o--",
+	"alias":       "#..",
+	"derives":     "-->",
+	"constraint":  "..>",
+}
+
+// arrowLine matches the line of an arrow, which the color of the connection is written into
+var arrowLine = regexp.MustCompile(`--|\.\.`)
+
+var typeKeywords = regexp.MustCompile(`\b(map|chan|struct|interface|func)\b`)
+
+// packageFunctionsClass is the name of the class holding the functions of a package declared without a receiver
+const packageFunctionsClass = "functions"
+
+// packageVarsClass is the name of the class holding the variables of a package, see RenderingOptions.PackageVars
+const packageVarsClass = "vars"
+
 const aliasComplexNameComment = "'This class was created so that we can correctly have an alias pointing to this name. Since it contains dots that can break namespaces"
 
 type renderer struct {
+	// colorFunc returns the color of the connections instead of the default ones when it is set, see WithColorFunc
+	colorFunc func(src, dst, relKind string) string
+}
+
+// renderState holds what a single render needs besides the parser, so the renderer itself is never changed by a
+// render and can be shared by concurrent ones
+type renderState struct {
+	// included holds the included structures keyed by package
+	included map[string]map[string]*parser.Struct
+	// labels holds the labels of the classes keyed by their fully qualified name when FlattenPackages is set, and is
+	// nil otherwise
+	labels map[string]string
 }
 
+// newRenderState returns the state of a render of the given parser
+func newRenderState(p *parser.ClassParser) *renderState {
+	state := &renderState{included: p.AllIncludedStructures()}
+	if p.RenderingOptions.FlattenPackages {
+		state.labels = render.FlatNames(state.included)
+	}
+	return state
+}
+
+// Option sets up the renderer returned by NewRender
+type Option func(*renderer)
+
 var _ render.Renderer = (*renderer)(nil)
 
-func NewRender() *renderer {
-	return &renderer{}
+func init() {
+	render.Register("plantuml", func() render.Renderer { return NewRender() })
+}
+
+// WithColorFunc sets the function that returns the color of every connection the renderer draws, such as #FF0000,
+// given the fully qualified names of the types on the left and the right of its arrow and its kind ("composition",
+// "extends", "realization", "aggregation", "alias", "derives" or "constraint"). It replaces the random and the seeded
+// colors of the renderer. The colors are ignored when NoColor is set
+func WithColorFunc(f func(src, dst, relKind string) string) Option {
+	return func(r *renderer) {
+		r.colorFunc = f
+	}
+}
+
+func NewRender(options ...Option) *renderer {
+	r := &renderer{}
+	for _, option := range options {
+		option(r)
+	}
+	return r
 }
 
 func (r *renderer) Render(p *parser.ClassParser) string {
-	str := &parser.LineStringBuilder{}
+	str := &strings.Builder{}
+	// Writing into a strings.Builder never fails
+	_ = r.RenderTo(str, p)
+	return str.String()
+}
+
+func (r *renderer) RenderTo(w io.Writer, p *parser.ClassParser) error {
+	str := parser.NewLineWriterWithIndent(w, p.RenderingOptions.Indent)
+	r.renderHeader(p, str)
+
+	packages := r.sortedPackages(p)
+	if p.RenderingOptions.ImportGraph {
+		r.renderImportGraph(p, packages, str)
+		r.renderEnd(p, str)
+		return str.Err()
+	}
+	state := newRenderState(p)
+	if p.RenderingOptions.NestedNamespaces && !p.RenderingOptions.FlattenPackages {
+		r.renderNestedStructures(p, packages, str, state)
+	} else {
+		for _, pack := range packages {
+			r.renderStructures(p, pack, str, state)
+		}
+	}
+	if p.RenderingOptions.Aliases {
+		r.renderAliases(p, str)
+		r.renderAliasCycles(p, nil, str)
+	}
+	if p.RenderingOptions.ConstraintEdges {
+		r.renderConstraints(p, str, state)
+	}
+	r.renderFooter(p, str)
+	return str.Err()
+}
+
+// RenderPerPackage renders a complete document for every package with structures to render, keyed by the package
+// name. Every document holds the structures of its package along with the connections from and to them, including
+// the ones with other packages, so each of them can be rendered on its own
+func (r *renderer) RenderPerPackage(p *parser.ClassParser) map[string]string {
+	result := map[string]string{}
+	state := newRenderState(p)
+	for _, pack := range r.sortedPackages(p) {
+		structures := state.included[pack]
+		if len(structures) == 0 && len(p.RenderedPackageFunctions(pack)) == 0 && len(p.RenderedPackageVars(pack)) == 0 {
+			continue
+		}
+		builder := &strings.Builder{}
+		str := parser.NewLineWriterWithIndent(builder, p.RenderingOptions.Indent)
+		r.renderHeader(p, str)
+		r.renderStructures(p, pack, str, state)
+		names := r.quotedNames(p, pack, structures)
+		r.renderIncomingConnections(p, pack, names, str, state)
+		if p.RenderingOptions.Aliases {
+			aliases := parser.NewLineStringBuilder(p.RenderingOptions.Indent)
+			r.renderAliases(p, parser.NewLineWriterWithIndent(aliases, p.RenderingOptions.Indent))
+			if filtered := r.filterConnections(aliases, names); filtered.Len() > 0 {
+				str.WriteLineWithDepth(0, filtered.String())
+			}
+			r.renderAliasCycles(p, names, str)
+		}
+		if p.RenderingOptions.ConstraintEdges {
+			constraints := parser.NewLineStringBuilder(p.RenderingOptions.Indent)
+			r.renderConstraints(p, parser.NewLineWriterWithIndent(constraints, p.RenderingOptions.Indent), state)
+			if filtered := r.filterConnections(constraints, names); filtered.Len() > 0 {
+				str.WriteLineWithDepth(0, filtered.String())
+			}
+		}
+		r.renderFooter(p, str)
+		result[pack] = builder.String()
+	}
+	return result
+}
+
+func (r *renderer) renderHeader(p *parser.ClassParser, str *parser.LineWriter) {
+	if p.RenderingOptions.MarkdownFence {
+		str.WriteLineWithDepth(0, "```plantuml")
+	}
 	str.WriteLineWithDepth(0, "@startuml")
+	if theme := strings.TrimSpace(p.RenderingOptions.Theme); theme != "" {
+		str.WriteLineWithDepth(0, fmt.Sprintf(`!theme %s`, theme))
+	}
 	str.WriteLineWithDepth(0, nodeSep)
 	str.WriteLineWithDepth(0, ranskSep)
+	if p.RenderingOptions.FlattenPackages && !p.RenderingOptions.ImportGraph {
+		// The fully qualified names of the classes would create the packages again otherwise
+		str.WriteLineWithDepth(0, "set namespaceSeparator none")
+	}
 	if p.RenderingOptions.Title != "" {
 		str.WriteLineWithDepth(0, fmt.Sprintf(`title %s`, p.RenderingOptions.Title))
 	}
@@ -42,86 +207,401 @@ func (r *renderer) Render(p *parser.ClassParser) string {
 		str.WriteLineWithDepth(0, note)
 		str.WriteLineWithDepth(0, "end legend")
 	}
+}
 
+func (r *renderer) renderFooter(p *parser.ClassParser, str *parser.LineWriter) {
+	if !p.RenderingOptions.Fields {
+		str.WriteLineWithDepth(0, "hide fields")
+	}
+	if !p.RenderingOptions.Methods {
+		str.WriteLineWithDepth(0, "hide methods")
+	}
+	if p.RenderingOptions.HideEmptyMembers {
+		str.WriteLineWithDepth(0, "hide empty members")
+	}
+	r.renderEnd(p, str)
+}
+
+// renderEnd ends the diagram, closing the markdown fenced code block renderHeader opened if any
+func (r *renderer) renderEnd(p *parser.ClassParser, str *parser.LineWriter) {
+	str.WriteLineWithDepth(0, "@enduml")
+	if p.RenderingOptions.MarkdownFence {
+		str.WriteLineWithDepth(0, "```")
+	}
+}
+
+func (r *renderer) sortedPackages(p *parser.ClassParser) []string {
 	var packages []string
 	for pack := range p.Structure {
 		packages = append(packages, pack)
 	}
 	sort.Strings(packages)
-	for _, pack := range packages {
-		structures := p.Structure[pack]
-		r.renderStructures(p, pack, structures, str)
+	return packages
+}
+
+// renderIncomingConnections renders the connections from the included structures of the other packages to the
+// structures with the given quoted names
+func (r *renderer) renderIncomingConnections(
+	p *parser.ClassParser,
+	pack string,
+	names map[string]struct{},
+	str *parser.LineWriter,
+	state *renderState,
+) {
+	composition := parser.NewLineStringBuilder(p.RenderingOptions.Indent)
+	extends := parser.NewLineStringBuilder(p.RenderingOptions.Indent)
+	aggregations := parser.NewLineStringBuilder(p.RenderingOptions.Indent)
+	for _, other := range r.sortedPackages(p) {
+		if other == pack {
+			continue
+		}
+		structures := state.included[other]
+		var otherNames []string
+		for name := range structures {
+			otherNames = append(otherNames, name)
+		}
+		sort.Strings(otherNames)
+		for _, name := range otherNames {
+			structure := structures[name]
+			r.renderCompositions(p, structure, name, composition)
+			r.renderExtends(p, structure, name, extends)
+			r.renderAggregations(p, structure, name, aggregations)
+		}
+	}
+	r.renderConnections(p, str, r.filterConnections(composition, names), r.filterConnections(extends, names), r.filterConnections(aggregations, names))
+}
 
+// quotedNames returns the names the given structures are quoted with in the connections, both as they are referenced
+// by other structures and as their own connections name them
+func (r *renderer) quotedNames(p *parser.ClassParser, pack string, structures map[string]*parser.Struct) map[string]struct{} {
+	names := map[string]struct{}{}
+	for name, structure := range structures {
+		fullName := name
+		if !strings.HasPrefix(name, pack+".") {
+			fullName = fmt.Sprintf("%s.%s", pack, name)
+		}
+		names[fmt.Sprintf(`"%s"`, r.displayName(p, fullName))] = struct{}{}
+		names[fmt.Sprintf(`"%s"`, r.displayName(p, fmt.Sprintf("%s.%s", structure.PackageName, name)))] = struct{}{}
 	}
-	if p.RenderingOptions.Aliases {
-		r.renderAliases(p, str)
+	return names
+}
+
+// quotedName matches the names quoted in a connection
+var quotedName = regexp.MustCompile(`"[^"]*"`)
+
+// connectionEnds returns the quoted names of the types on both ends of the given connection. The source is quoted at
+// the start of the line and the target is the last name quoted before the " : " label, if any
+func connectionEnds(line string) (string, string, bool) {
+	if i := strings.Index(line, " : "); i >= 0 {
+		line = line[:i]
 	}
-	if !p.RenderingOptions.Fields {
-		str.WriteLineWithDepth(0, "hide fields")
+	quoted := quotedName.FindAllString(line, -1)
+	if len(quoted) < 2 || !strings.HasPrefix(strings.TrimSpace(line), quoted[0]) {
+		return "", "", false
 	}
-	if !p.RenderingOptions.Methods {
-		str.WriteLineWithDepth(0, "hide methods")
+	return quoted[0], quoted[len(quoted)-1], true
+}
+
+// filterConnections returns the connections of the given builder, one per line, whose source or target is one of the
+// given quoted names
+func (r *renderer) filterConnections(connections *parser.LineStringBuilder, names map[string]struct{}) *parser.LineStringBuilder {
+	result := parser.NewLineStringBuilder(connections.Indent)
+	for _, line := range strings.Split(connections.String(), "\n") {
+		source, target, ok := connectionEnds(line)
+		if !ok {
+			continue
+		}
+		_, fromName := names[source]
+		_, toName := names[target]
+		if fromName || toName {
+			result.WriteLineWithDepth(0, line)
+		}
 	}
-	str.WriteLineWithDepth(0, "@enduml")
-	return str.String()
+	return result
 }
 
-func (r *renderer) renderStructures(p *parser.ClassParser, pack string, structures map[string]*parser.Struct, str *parser.LineStringBuilder) {
-	if len(structures) > 0 {
-		composition := &parser.LineStringBuilder{}
-		extends := &parser.LineStringBuilder{}
-		aggregations := &parser.LineStringBuilder{}
-		str.WriteLineWithDepth(0, fmt.Sprintf(`namespace %s {`, pack))
+// renderImportGraph renders every package as a node connected to the other parsed packages it imports. Import cycles
+// are drawn like any other import so they can be spotted. The nodes are named with render.NodeID, so packages such as
+// a.b and a_b are not drawn as the same one
+func (r *renderer) renderImportGraph(p *parser.ClassParser, packages []string, str *parser.LineWriter) {
+	for _, pack := range packages {
+		str.WriteLineWithDepth(0, fmt.Sprintf(`package "%s" as %s {`, r.displayPackage(p, pack), render.NodeID(pack)))
+		str.WriteLineWithDepth(0, `}`)
+	}
+	for _, pack := range packages {
+		for _, imported := range p.ImportedPackages(pack) {
+			str.WriteLineWithDepth(0, fmt.Sprintf(`%s ..> %s : imports`, render.NodeID(pack), render.NodeID(imported)))
+		}
+	}
+}
 
-		names := []string{}
-		for name := range structures {
-			names = append(names, name)
+func (r *renderer) renderStructures(p *parser.ClassParser, pack string, str *parser.LineWriter, state *renderState) {
+	structures := state.included[pack]
+	if len(structures) > 0 || len(p.RenderedPackageFunctions(pack)) > 0 || len(p.RenderedPackageVars(pack)) > 0 {
+		composition := parser.NewLineStringBuilder(p.RenderingOptions.Indent)
+		extends := parser.NewLineStringBuilder(p.RenderingOptions.Indent)
+		aggregations := parser.NewLineStringBuilder(p.RenderingOptions.Indent)
+		// The classes of flattened packages are declared with their fully qualified name instead
+		if state.labels == nil {
+			str.WriteLineWithDepth(0, fmt.Sprintf(`namespace %s {`, r.displayPackage(p, pack)))
 		}
+		r.renderNamespace(p, pack, structures, str, composition, extends, aggregations, state)
+		if state.labels == nil {
+			str.WriteLineWithDepth(0, fmt.Sprintf(`}`))
+		}
+		r.renderConnections(p, str, composition, extends, aggregations)
+	}
+}
 
-		sort.Strings(names)
+// renderNestedStructures renders every package in a namespace nested into the namespaces of its parent packages. The
+// connections are rendered once all the namespaces are closed, so their fully qualified names resolve from the root.
+func (r *renderer) renderNestedStructures(p *parser.ClassParser, packages []string, str *parser.LineWriter, state *renderState) {
+	composition := parser.NewLineStringBuilder(p.RenderingOptions.Indent)
+	extends := parser.NewLineStringBuilder(p.RenderingOptions.Indent)
+	aggregations := parser.NewLineStringBuilder(p.RenderingOptions.Indent)
+	var open []string
+	for _, pack := range packages {
+		structures := state.included[pack]
+		if len(structures) == 0 && len(p.RenderedPackageFunctions(pack)) == 0 && len(p.RenderedPackageVars(pack)) == 0 {
+			continue
+		}
+		segments := namespaceSegments(r.displayPackage(p, pack))
+		common := 0
+		for common < len(open) && common < len(segments) && open[common] == segments[common] {
+			common++
+		}
+		for len(open) > common {
+			str.Indent(-1)
+			str.WriteLineWithDepth(0, "}")
+			open = open[:len(open)-1]
+		}
+		for _, segment := range segments[common:] {
+			str.WriteLineWithDepth(0, fmt.Sprintf(`namespace %s {`, segment))
+			str.Indent(1)
+			open = append(open, segment)
+		}
+		// The structures are rendered one level deeper than the namespace they are in
+		str.Indent(-1)
+		r.renderNamespace(p, pack, structures, str, composition, extends, aggregations, state)
+		str.Indent(1)
+	}
+	for range open {
+		str.Indent(-1)
+		str.WriteLineWithDepth(0, "}")
+	}
+	r.renderConnections(p, str, composition, extends, aggregations)
+}
 
-		for _, name := range names {
-			structure := structures[name]
-			r.renderStructure(p, structure, pack, name, str, composition, extends, aggregations)
+// namespaceSegments splits a package name into the names of the nested namespaces it is rendered in. A leading dot is
+// kept in the first segment so the namespaces still add up to the package name
+func namespaceSegments(pack string) []string {
+	segments := strings.Split(pack, ".")
+	if len(segments) > 1 && segments[0] == "" {
+		segments = append([]string{"." + segments[1]}, segments[2:]...)
+	}
+	return segments
+}
+
+func (r *renderer) renderNamespace(
+	p *parser.ClassParser,
+	pack string,
+	structures map[string]*parser.Struct,
+	str *parser.LineWriter,
+	composition *parser.LineStringBuilder,
+	extends *parser.LineStringBuilder,
+	aggregations *parser.LineStringBuilder,
+	state *renderState,
+) {
+	names := []string{}
+	for name := range structures {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, group := range r.groups(p, names, structures) {
+		if len(group) > 1 {
+			str.WriteLineWithDepth(1, "together {")
 		}
-		var orderedRenamedStructs []string
-		for tempName := range p.AllRenamedStructs[pack] {
-			orderedRenamedStructs = append(orderedRenamedStructs, tempName)
+		for _, name := range group {
+			r.renderStructure(p, structures[name], pack, name, str, composition, extends, aggregations, state)
 		}
-		sort.Strings(orderedRenamedStructs)
-		for _, tempName := range orderedRenamedStructs {
-			name := p.AllRenamedStructs[pack][tempName]
-			str.WriteLineWithDepth(1, fmt.Sprintf(`class "%s" as %s {`, name, tempName))
-			str.WriteLineWithDepth(2, aliasComplexNameComment)
+		if len(group) > 1 {
 			str.WriteLineWithDepth(1, "}")
 		}
-		str.WriteLineWithDepth(0, fmt.Sprintf(`}`))
-		if p.RenderingOptions.Compositions {
-			str.WriteLineWithDepth(0, composition.String())
+	}
+	if functions := p.RenderedPackageFunctions(pack); len(functions) > 0 {
+		r.renderPackageFunctions(p, pack, functions, str, state)
+	}
+	if variables := p.RenderedPackageVars(pack); len(variables) > 0 {
+		r.renderPackageVars(p, pack, variables, str, aggregations, state)
+	}
+	var orderedRenamedStructs []string
+	for tempName := range p.AllRenamedStructs[pack] {
+		orderedRenamedStructs = append(orderedRenamedStructs, tempName)
+	}
+	sort.Strings(orderedRenamedStructs)
+	for _, tempName := range orderedRenamedStructs {
+		name := p.AllRenamedStructs[pack][tempName]
+		if state.labels != nil {
+			tempName = r.displayName(p, fmt.Sprintf("%s.%s", pack, tempName))
 		}
-		if p.RenderingOptions.Implementations {
-			str.WriteLineWithDepth(0, extends.String())
+		str.WriteLineWithDepth(1, fmt.Sprintf(`class "%s" as %s {`, name, tempName))
+		str.WriteLineWithDepth(2, aliasComplexNameComment)
+		str.WriteLineWithDepth(1, "}")
+	}
+	// The classes of flattened packages are not in a namespace the legend could be drawn in
+	if p.RenderingOptions.PackageLegends && state.labels == nil {
+		r.renderPackageLegend(p, pack, structures, str)
+	}
+}
+
+// renderPackageLegend renders a note with the number of structs and interfaces of the given package that are rendered,
+// which are the given structures
+func (r *renderer) renderPackageLegend(p *parser.ClassParser, pack string, structures map[string]*parser.Struct, str *parser.LineWriter) {
+	structs, interfaces := 0, 0
+	for _, structure := range structures {
+		switch structure.Type {
+		case "class":
+			structs++
+		case "interface":
+			interfaces++
 		}
-		if p.RenderingOptions.Aggregations {
-			str.WriteLineWithDepth(0, aggregations.String())
+	}
+	str.WriteLineWithDepth(1, fmt.Sprintf(`note as %s_legend`, render.NodeID(pack)))
+	str.WriteLineWithDepth(2, fmt.Sprintf(`<b>%s</b>`, r.displayPackage(p, pack)))
+	str.WriteLineWithDepth(2, fmt.Sprintf(`Structs: %d`, structs))
+	str.WriteLineWithDepth(2, fmt.Sprintf(`Interfaces: %d`, interfaces))
+	str.WriteLineWithDepth(1, "end note")
+}
+
+// groups splits the given sorted names into the groups of structures rendered together, in the order of their first
+// name. Every structure is a group of its own unless GroupBy is set
+func (r *renderer) groups(p *parser.ClassParser, names []string, structures map[string]*parser.Struct) [][]string {
+	var result [][]string
+	files := map[string]int{}
+	for _, name := range names {
+		file := structures[name].DefinedIn
+		if p.RenderingOptions.GroupBy == parser.GroupByFile && file != "" {
+			if i, ok := files[file]; ok {
+				result[i] = append(result[i], name)
+				continue
+			}
+			files[file] = len(result)
 		}
+		result = append(result, []string{name})
 	}
+	return result
 }
 
-func (r *renderer) renderAliases(p *parser.ClassParser, str *parser.LineStringBuilder) {
+// renderPackageFunctions renders the given functions as the methods of a class with the functions stereotype
+func (r *renderer) renderPackageFunctions(p *parser.ClassParser, pack string, functions []*parser.Function, str *parser.LineWriter, state *renderState) {
+	privateMethods := parser.NewLineStringBuilder(p.RenderingOptions.Indent)
+	publicMethods := parser.NewLineStringBuilder(p.RenderingOptions.Indent)
+	r.renderStructMethods(p, &parser.Struct{PackageName: pack, Functions: functions}, privateMethods, publicMethods)
+	functionsClass := packageFunctionsClass
+	if state.labels != nil {
+		functionsClass = fmt.Sprintf(`"%s %s" as %s`, r.displayPackage(p, pack), packageFunctionsClass, r.displayName(p, fmt.Sprintf("%s.%s", pack, packageFunctionsClass)))
+	}
+	str.WriteLineWithDepth(1, fmt.Sprintf(`class %s << (F,#FFD700) functions >> {`, functionsClass))
+	if privateMethods.Len() > 0 {
+		str.WriteLineWithDepth(0, privateMethods.String())
+	}
+	if publicMethods.Len() > 0 {
+		str.WriteLineWithDepth(0, publicMethods.String())
+	}
+	str.WriteLineWithDepth(1, `}`)
+}
+
+// renderPackageVars renders the given variables as the fields of a class with the var stereotype, along with an
+// aggregation from that class to every parsed struct they hold, labeled with the variables holding it
+func (r *renderer) renderPackageVars(p *parser.ClassParser, pack string, variables []*parser.Variable, str *parser.LineWriter, aggregations *parser.LineStringBuilder, state *renderState) {
+	privateFields := parser.NewLineStringBuilder(p.RenderingOptions.Indent)
+	publicFields := parser.NewLineStringBuilder(p.RenderingOptions.Indent)
+	fields := make([]*parser.Field, 0, len(variables))
+	holders := map[string][]string{}
+	for _, variable := range variables {
+		fields = append(fields, &variable.Field)
+		if !variable.IsExported() && !p.RenderingOptions.PrivateMembers {
+			continue
+		}
+		for _, t := range variable.Types {
+			if _, ok := p.AllStructs[t]; ok && p.RenderingOptions.IsIncluded(t) {
+				holders[t] = append(holders[t], variable.Name)
+			}
+		}
+	}
+	r.renderStructFields(p, &parser.Struct{PackageName: pack, Fields: fields}, privateFields, publicFields)
+	fullName := fmt.Sprintf("%s.%s", pack, packageVarsClass)
+	varsClass := packageVarsClass
+	if state.labels != nil {
+		varsClass = fmt.Sprintf(`"%s %s" as %s`, r.displayPackage(p, pack), packageVarsClass, r.displayName(p, fullName))
+	}
+	str.WriteLineWithDepth(1, fmt.Sprintf(`class %s << (V,#B0C4DE) var >> {`, varsClass))
+	if privateFields.Len() > 0 {
+		str.WriteLineWithDepth(0, privateFields.String())
+	}
+	if publicFields.Len() > 0 {
+		str.WriteLineWithDepth(0, publicFields.String())
+	}
+	str.WriteLineWithDepth(1, `}`)
+
+	aggregationString := ""
+	if p.RenderingOptions.ConnectionLabels {
+		aggregationString = aggregates
+	}
 	var randColor = randomcolor.GetRandomColorInHex()
+	var targets []string
+	for t := range holders {
+		targets = append(targets, t)
+	}
+	sort.Strings(targets)
+	for _, t := range targets {
+		color := r.edgeColor(p, randColor, "aggregation", fullName, t)
+		aggregations.WriteLineWithDepth(0, fmt.Sprintf(`"%s"%s %s "%s"%s`, r.displayName(p, fullName), aggregationString, r.arrow(p, "aggregation", color), r.displayName(p, t), r.fieldNameLabel(p, holders[t])))
+	}
+}
+
+func (r *renderer) renderConnections(p *parser.ClassParser, str *parser.LineWriter, composition *parser.LineStringBuilder, extends *parser.LineStringBuilder, aggregations *parser.LineStringBuilder) {
+	if p.RenderingOptions.Compositions {
+		str.WriteLineWithDepth(0, composition.String())
+	}
+	if p.RenderingOptions.Implementations {
+		str.WriteLineWithDepth(0, extends.String())
+	}
+	if p.RenderingOptions.Aggregations {
+		str.WriteLineWithDepth(0, aggregations.String())
+	}
+}
+
+// renderAliases renders an edge from every alias to the type it is an alias of, and from every defined type to the type
+// it is defined from. Their colors are always derived from both ends of the edge, even without a ColorSeed, so the
+// aliases do not change color between renders
+func (r *renderer) renderAliases(p *parser.ClassParser, str *parser.LineWriter) {
 	var aliasString string
+	var derivesFromString string
 	if p.RenderingOptions.ConnectionLabels {
 		aliasString = aliasOf
+		derivesFromString = derivesFrom
 	}
 	orderedAliases := parser.AliasSlice{}
 	for _, alias := range p.AllAliases {
 		orderedAliases = append(orderedAliases, *alias)
 	}
 	sort.Sort(orderedAliases)
+	cycles := render.AliasCycles(p)
 	for _, alias := range orderedAliases {
+		if !p.RenderingOptions.IsIncluded(alias.Name) || !p.RenderingOptions.IsIncluded(alias.AliasOf) {
+			continue
+		}
 		aliasName := alias.Name
-		if strings.Count(alias.Name, ".") > 1 {
+		if cycle, ok := cycles[alias.AliasOf]; ok {
+			// A cycle is drawn with the connection of its first type alone, see renderAliasCycles
+			if alias.AliasOf != cycle[0] {
+				continue
+			}
+			aliasName = cycle[1%len(cycle)]
+		} else if strings.Count(alias.Name, ".") > 1 {
 			split := strings.SplitN(alias.Name, ".", 2)
 			if aliasRename, ok := p.AllRenamedStructs[split[0]]; ok {
 				renamed := parser.GenerateRenamedStructName(split[1])
@@ -130,7 +610,79 @@ func (r *renderer) renderAliases(p *parser.ClassParser, str *parser.LineStringBu
 				}
 			}
 		}
-		str.WriteLineWithDepth(0, fmt.Sprintf(`"%s" #.[%s]. %s"%s"`, aliasName, randColor, aliasString, alias.AliasOf))
+		// Instantiations of generic types are labeled with their type arguments
+		typeArguments := ""
+		if len(alias.TypeArguments) > 0 {
+			typeArguments = fmt.Sprintf(" : [%s]", memberEscaper.Replace(strings.Join(alias.TypeArguments, ", ")))
+		}
+		if alias.DefinedType {
+			color := r.edgeColor(p, seededColor(p.RenderingOptions.ColorSeed, alias.AliasOf, aliasName), "derives", alias.AliasOf, aliasName)
+			str.WriteLineWithDepth(0, fmt.Sprintf(`"%s" %s %s"%s"%s`, r.displayName(p, alias.AliasOf), r.arrow(p, "derives", color), derivesFromString, r.displayName(p, aliasName), typeArguments))
+		} else {
+			color := r.edgeColor(p, seededColor(p.RenderingOptions.ColorSeed, aliasName, alias.AliasOf), "alias", aliasName, alias.AliasOf)
+			str.WriteLineWithDepth(0, fmt.Sprintf(`"%s" %s %s"%s"%s`, r.displayName(p, aliasName), r.arrow(p, "alias", color), aliasString, r.displayName(p, alias.AliasOf), typeArguments))
+		}
+	}
+}
+
+// renderAliasCycles renders a note on the first type of every cycle of aliases, which lists the types of the cycle.
+// When quotedNames is not nil, only the cycles starting with one of the types quoted in it are rendered
+func (r *renderer) renderAliasCycles(p *parser.ClassParser, quotedNames map[string]struct{}, str *parser.LineWriter) {
+	cycles := render.AliasCycles(p)
+	var names []string
+	for name, cycle := range cycles {
+		if name != cycle[0] || !p.RenderingOptions.IsIncluded(name) {
+			continue
+		}
+		if _, ok := quotedNames[fmt.Sprintf(`"%s"`, r.displayName(p, name))]; quotedNames != nil && !ok {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		note := render.NodeID(name) + "_cycle"
+		str.WriteLineWithDepth(0, fmt.Sprintf(`note "%s" as %s`, render.AliasCycleNote(p.RenderingOptions.PackageAliases, cycles[name]), note))
+		str.WriteLineWithDepth(0, fmt.Sprintf(`%s .. "%s"`, note, r.displayName(p, name)))
+	}
+}
+
+// renderConstraints renders a dashed edge from every generic type, and from the class holding the functions of its
+// package, to the parsed interfaces their type parameters are constrained by
+func (r *renderer) renderConstraints(p *parser.ClassParser, str *parser.LineWriter, state *renderState) {
+	label := ""
+	if p.RenderingOptions.ConnectionLabels {
+		label = constrainedBy
+	}
+	for _, pack := range r.sortedPackages(p) {
+		structures := state.included[pack]
+		var names []string
+		for name := range structures {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			// Defined types are stored with their package prefix
+			fullName := fmt.Sprintf("%s.%s", pack, strings.TrimPrefix(name, pack+"."))
+			r.renderConstraintEdges(p, fullName, structures[name].TypeParameters, label, str)
+		}
+		typeParameters := make([]*parser.Field, 0)
+		for _, function := range p.RenderedPackageFunctions(pack) {
+			typeParameters = append(typeParameters, function.TypeParameters...)
+		}
+		r.renderConstraintEdges(p, fmt.Sprintf("%s.%s", pack, packageFunctionsClass), typeParameters, label, str)
+	}
+}
+
+// renderConstraintEdges renders a dashed edge from the class with the given fully qualified name to every parsed
+// interface the given type parameters are constrained by
+func (r *renderer) renderConstraintEdges(p *parser.ClassParser, source string, typeParameters []*parser.Field, label string, str *parser.LineWriter) {
+	for _, constraint := range p.ConstraintInterfaces(typeParameters) {
+		if !p.RenderingOptions.IsIncluded(constraint) {
+			continue
+		}
+		color := r.edgeColor(p, seededColor(p.RenderingOptions.ColorSeed, source, constraint), "constraint", source, constraint)
+		str.WriteLineWithDepth(0, fmt.Sprintf(`"%s" %s %s"%s"`, r.displayName(p, source), r.arrow(p, "constraint", color), label, r.displayName(p, constraint)))
 	}
 }
 
@@ -139,28 +691,60 @@ func (r *renderer) renderStructure(
 	structure *parser.Struct,
 	pack string,
 	name string,
-	str *parser.LineStringBuilder,
+	str *parser.LineWriter,
 	composition *parser.LineStringBuilder,
 	extends *parser.LineStringBuilder,
 	aggregations *parser.LineStringBuilder,
+	state *renderState,
 ) {
 
-	privateFields := &parser.LineStringBuilder{}
-	publicFields := &parser.LineStringBuilder{}
-	privateMethods := &parser.LineStringBuilder{}
-	publicMethods := &parser.LineStringBuilder{}
+	privateFields := parser.NewLineStringBuilder(p.RenderingOptions.Indent)
+	publicFields := parser.NewLineStringBuilder(p.RenderingOptions.Indent)
+	privateMethods := parser.NewLineStringBuilder(p.RenderingOptions.Indent)
+	publicMethods := parser.NewLineStringBuilder(p.RenderingOptions.Indent)
 	sType := ""
 	renderStructureType := structure.Type
 	switch structure.Type {
 	case "class":
 		sType = "<< (S,Aquamarine) >>"
-	case "alias":
+	case "func":
+		sType = "<< (F,#FFD700) func >>"
+		renderStructureType = "class"
+	case "alias", "type":
 		sType = "<< (T, #FF7700) >> "
+		if structure.Type == "type" {
+			sType = "<< (T, #FF7700) type >> "
+		}
 		renderStructureType = "class"
+		if p.RenderingOptions.Constants && len(structure.Constants) > 0 {
+			sType = ""
+			renderStructureType = "enum"
+		}
 
 	}
-	str.WriteLineWithDepth(1, fmt.Sprintf(`%s %s %s {`, renderStructureType, name, sType))
+	if stereotype, ok := p.RenderingOptions.Stereotypes[structure.Type]; ok && renderStructureType != "enum" {
+		sType = stereotype
+	}
+	id := name
+	label := name
+	if state.labels != nil {
+		fullName := fmt.Sprintf("%s.%s", pack, strings.TrimPrefix(name, pack+"."))
+		id = r.displayName(p, fullName)
+		label = r.displayName(p, state.labels[fullName])
+	}
+	renderName := id
+	if len(structure.TypeParameters) > 0 {
+		renderName = fmt.Sprintf(`"%s[%s]" as %s`, label, r.renderTypeParameters(structure), id)
+	} else if label != id {
+		renderName = fmt.Sprintf(`"%s" as %s`, label, id)
+	}
+	str.WriteLineWithDepth(1, fmt.Sprintf(`%s %s %s {`, renderStructureType, renderName, sType))
+	if renderStructureType == "enum" {
+		r.renderConstants(p, structure, str)
+	}
+	r.renderTypeSet(p, structure, publicFields)
 	r.renderStructFields(p, structure, privateFields, publicFields)
+	r.renderSignature(p, structure, publicMethods)
 	r.renderStructMethods(p, structure, privateMethods, publicMethods)
 	r.renderCompositions(p, structure, name, composition)
 	r.renderExtends(p, structure, name, extends)
@@ -178,30 +762,87 @@ func (r *renderer) renderStructure(
 		str.WriteLineWithDepth(0, publicMethods.String())
 	}
 	str.WriteLineWithDepth(1, fmt.Sprintf(`}`))
+	if p.RenderingOptions.DocComments && strings.TrimSpace(structure.Doc) != "" {
+		r.renderDocComment(structure, id, str)
+	}
+	if structure.Note != "" {
+		r.renderNote(structure, id, str)
+	}
+}
+
+// renderDocComment renders the doc comment of the structure as a note on top of it. Creole markup and lines that
+// would end the note are escaped so the comment is rendered as it was written
+func (r *renderer) renderDocComment(structure *parser.Struct, name string, str *parser.LineWriter) {
+	str.WriteLineWithDepth(1, fmt.Sprintf(`note top of %s`, name))
+	for _, line := range render.WrapText(structure.Doc, docCommentWidth) {
+		line = docCommentEscaper.Replace(line)
+		if strings.EqualFold(strings.TrimSpace(line), "end note") {
+			line = "~" + line
+		}
+		str.WriteLineWithDepth(2, line)
+	}
+	str.WriteLineWithDepth(1, "end note")
+}
+
+// renderNote renders the text of the //plantuml:note directive of the structure as a note on its right, escaped the
+// same way the doc comments are
+func (r *renderer) renderNote(structure *parser.Struct, name string, str *parser.LineWriter) {
+	str.WriteLineWithDepth(1, fmt.Sprintf(`note right of %s : %s`, name, docCommentEscaper.Replace(structure.Note)))
+}
+
+func (r *renderer) renderConstants(p *parser.ClassParser, structure *parser.Struct, str *parser.LineWriter) {
+	for _, constant := range structure.Constants {
+		str.WriteLineWithDepth(2, memberEscaper.Replace(render.ConstantString(p.RenderingOptions, constant)))
+	}
+}
+
+func (r *renderer) renderTypeParameters(structure *parser.Struct) string {
+	typeParameters := make([]string, 0)
+	for _, tp := range structure.TypeParameters {
+		typeParameters = append(typeParameters, memberEscaper.Replace(fmt.Sprintf("%s %s", tp.Name, tp.Type)))
+	}
+	return strings.Join(typeParameters, ", ")
 }
 
 func (r *renderer) renderAggregations(p *parser.ClassParser, structure *parser.Struct, name string, aggregations *parser.LineStringBuilder) {
 
-	aggregationMap := structure.Aggregations
+	// The aggregations are copied so the private ones are not added to the parsed structure
+	aggregationMap := make(map[string]struct{}, len(structure.Aggregations))
+	for agg := range structure.Aggregations {
+		aggregationMap[agg] = struct{}{}
+	}
 	if p.RenderingOptions.AggregatePrivateMembers {
 		r.updatePrivateAggregations(structure, aggregationMap)
 	}
+	if p.RenderingOptions.BuiltinAggregations {
+		for _, agg := range structure.AggregatedBuiltinTypes(p.RenderingOptions.AggregatePrivateMembers) {
+			aggregationMap[agg] = struct{}{}
+		}
+	}
 	r.renderAggregationMap(p, aggregationMap, structure, aggregations, name)
 }
 
 func (r *renderer) renderCompositions(p *parser.ClassParser, structure *parser.Struct, name string, composition *parser.LineStringBuilder) {
+	if structure.Type == "class" && !p.RenderingOptions.EmbeddedAsComposition {
+		// The embedded fields are rendered as fields instead
+		return
+	}
 	var randColor = randomcolor.GetRandomColorInHex()
 	var orderedCompositions []string
 
 	for c := range structure.Composition {
-		if !strings.Contains(c, ".") {
-			c = fmt.Sprintf("%s.%s", p.GetPackageName(c, structure), c)
+		fieldsLabel := r.fieldNameLabel(p, structure.CompositionFields[c])
+		// PlantUML draws the types that were not parsed as empty classes of their own
+		c, _ = p.ResolveType(c, structure)
+		if !p.RenderingOptions.IsIncluded(c) || p.RenderingOptions.IsSelfReference(fmt.Sprintf("%s.%s", structure.PackageName, name), c) {
+			continue
 		}
 		composedString := ""
 		if p.RenderingOptions.ConnectionLabels {
 			composedString = extends
 		}
-		c = fmt.Sprintf(`"%s" *-[%s]- %s"%s.%s"`, c, randColor, composedString, structure.PackageName, name)
+		color := r.edgeColor(p, randColor, "composition", c, fmt.Sprintf("%s.%s", structure.PackageName, name))
+		c = fmt.Sprintf(`"%s" %s %s"%s"%s`, r.displayName(p, c), r.arrow(p, "composition", color), composedString, r.displayName(p, fmt.Sprintf("%s.%s", structure.PackageName, name)), fieldsLabel)
 		orderedCompositions = append(orderedCompositions, c)
 	}
 	sort.Strings(orderedCompositions)
@@ -227,84 +868,293 @@ func (r *renderer) renderAggregationMap(p *parser.ClassParser, aggregationMap ma
 	sort.Strings(orderedAggregations)
 
 	for _, a := range orderedAggregations {
+		original := a
+		multiplicityString := ""
+		if multiplicity, ok := structure.Multiplicities[a]; ok && p.RenderingOptions.Multiplicity {
+			multiplicityString = fmt.Sprintf(` "%s"`, multiplicity)
+		}
 		if !strings.Contains(a, ".") {
 			a = fmt.Sprintf("%s.%s", p.GetPackageName(a, structure), a)
 		}
+		if !p.RenderingOptions.IsIncluded(a) || p.RenderingOptions.IsSelfReference(fmt.Sprintf("%s.%s", structure.PackageName, name), a) {
+			continue
+		}
 		aggregationString := ""
 		if p.RenderingOptions.ConnectionLabels {
 			aggregationString = aggregates
 		}
-		if p.GetPackageName(a, structure) != parser.BuiltinPackageName {
-			aggregations.WriteLineWithDepth(0, fmt.Sprintf(`"%s.%s"%s o-[%s]- "%s"`, structure.PackageName, name, aggregationString, randColor, a))
+		kind := "aggregation"
+		if p.RenderingOptions.SmartRelationships && structure.HoldsByValue(original) {
+			kind = "composition"
+		}
+		if p.RenderingOptions.BuiltinAggregations || p.GetPackageName(original, structure) != parser.BuiltinPackageName {
+			color := r.edgeColor(p, randColor, kind, fmt.Sprintf("%s.%s", structure.PackageName, name), a)
+			fieldsLabel := r.fieldNameLabel(p, structure.AggregatingFields(original, p.RenderingOptions.AggregatePrivateMembers))
+			aggregations.WriteLineWithDepth(0, fmt.Sprintf(`"%s"%s %s%s "%s"%s`, r.displayName(p, fmt.Sprintf("%s.%s", structure.PackageName, name)), aggregationString, r.arrow(p, kind, color), multiplicityString, r.displayName(p, a), fieldsLabel))
 		}
 	}
 }
 
-func (r *renderer) renderExtends(p *parser.ClassParser, structure *parser.Struct, name string, extends *parser.LineStringBuilder) {
+// displayPackage returns the name the given package is displayed with, see PackageAliases
+func (r *renderer) displayPackage(p *parser.ClassParser, pack string) string {
+	return render.DisplayPackage(p.RenderingOptions.PackageAliases, pack)
+}
+
+// displayName returns the fully qualified name the given type is displayed with, see PackageAliases
+func (r *renderer) displayName(p *parser.ClassParser, fullName string) string {
+	return render.DisplayName(p.RenderingOptions.PackageAliases, fullName)
+}
+
+// fieldNameLabel returns the label of a connection created by the given fields, or an empty string when the
+// connections are not labeled with the names of their fields
+func (r *renderer) fieldNameLabel(p *parser.ClassParser, fields []string) string {
+	if !p.RenderingOptions.FieldNameLabels || len(fields) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" : %s", strings.Join(fields, ", "))
+}
+
+func (r *renderer) renderExtends(p *parser.ClassParser, structure *parser.Struct, name string, extensions *parser.LineStringBuilder) {
 	var randColor = randomcolor.GetRandomColorInHex()
 	var orderedExtends []string
 	for c := range structure.Extends {
 		if !strings.Contains(c, ".") {
 			c = fmt.Sprintf("%s.%s", structure.PackageName, c)
 		}
-		implementString := ""
-		if p.RenderingOptions.ConnectionLabels {
-			implementString = implements
+		if !p.RenderingOptions.IsIncluded(c) {
+			continue
 		}
-		c = fmt.Sprintf(`"%s" <|-[%s]- %s"%s.%s"`, c, randColor, implementString, structure.PackageName, name)
+		// Interfaces are generalized by the ones they embed and realized by the types that implement them
+		kind, implementString := "realization", implements
+		if structure.ExtendsRelationship() == parser.Generalization {
+			kind, implementString = "extends", extends
+		}
+		if !p.RenderingOptions.ConnectionLabels {
+			implementString = ""
+		}
+		color := r.edgeColor(p, randColor, kind, c, fmt.Sprintf("%s.%s", structure.PackageName, name))
+		c = fmt.Sprintf(`"%s" %s %s"%s"`, r.displayName(p, c), r.arrow(p, kind, color), implementString, r.displayName(p, fmt.Sprintf("%s.%s", structure.PackageName, name)))
 		orderedExtends = append(orderedExtends, c)
 	}
 	sort.Strings(orderedExtends)
 	for _, c := range orderedExtends {
-		extends.WriteLineWithDepth(0, c)
+		extensions.WriteLineWithDepth(0, c)
 	}
 }
 
-func (r *renderer) renderStructMethods(p *parser.ClassParser, structure *parser.Struct, privateMethods *parser.LineStringBuilder, publicMethods *parser.LineStringBuilder) {
+// renderTypeSet renders the type set elements of a constraint interface before its fields
+func (r *renderer) renderTypeSet(p *parser.ClassParser, structure *parser.Struct, fields *parser.LineStringBuilder) {
+	if !p.RenderingOptions.Fields {
+		return
+	}
+	for _, element := range structure.TypeSet {
+		fields.WriteLineWithDepth(2, r.formatType(p, element))
+	}
+}
+
+// renderSignature renders the signature of a function type before its methods
+func (r *renderer) renderSignature(p *parser.ClassParser, structure *parser.Struct, methods *parser.LineStringBuilder) {
+	if structure.Signature == nil || !p.RenderingOptions.Methods {
+		return
+	}
+	methods.WriteLineWithDepth(2, r.formatType(p, structure.Signature.String()))
+}
 
-	for _, method := range structure.Functions {
+func (r *renderer) renderStructMethods(p *parser.ClassParser, structure *parser.Struct, privateMethods *parser.LineStringBuilder, publicMethods *parser.LineStringBuilder) {
+	if structure.Type == "interface" && p.RenderingOptions.HideInterfaceMethods {
+		return
+	}
+	groupByVisibility := p.RenderingOptions.MemberOrder.GroupsByVisibility()
+	rendered, hidden := 0, 0
+	for _, method := range structure.SortedFunctions(p.RenderingOptions.MemberOrder) {
 		accessModifier := "+"
-		if unicode.IsLower(rune(method.Name[0])) {
+		if !method.IsExported() {
 			if !p.RenderingOptions.PrivateMembers {
 				continue
 			}
 
 			accessModifier = "-"
 		}
+		if max := p.RenderingOptions.MaxMembers; max > 0 && rendered >= max {
+			hidden++
+			continue
+		}
+		rendered++
 		parameterList := make([]string, 0)
-		for _, p := range method.Parameters {
-			parameterList = append(parameterList, fmt.Sprintf("%s %s", p.Name, p.Type))
+		for _, parameter := range method.Parameters {
+			parameterList = append(parameterList, p.RenderingOptions.MemberString(parameter))
 		}
 		returnValues := ""
 		if len(method.ReturnValues) > 0 {
-			if len(method.ReturnValues) == 1 {
+			if len(method.ReturnValues) == 1 && !method.HasNamedReturnValues() {
 				returnValues = method.ReturnValues[0]
 			} else {
-				returnValues = fmt.Sprintf("(%s)", strings.Join(method.ReturnValues, ", "))
+				returnValues = fmt.Sprintf("(%s)", strings.Join(method.NamedReturnValues(), ", "))
 			}
 		}
-		if accessModifier == "-" {
-			privateMethods.WriteLineWithDepth(2, fmt.Sprintf(`%s %s(%s) %s`, accessModifier, method.Name, strings.Join(parameterList, ", "), returnValues))
+		methodName := method.Name
+		if method.PointerReceiver && p.RenderingOptions.ShowReceiverKind {
+			methodName = "*" + methodName
+		}
+		prefix := fmt.Sprintf("%s %s(", accessModifier, memberEscaper.Replace(methodName))
+		suffix := fmt.Sprintf(") %s", returnValues)
+		parameters := r.joinParameters(parameterList, len(prefix), len(suffix), p.RenderingOptions.MaxSignatureWidth)
+		line := prefix + r.formatType(p, parameters) + r.formatType(p, suffix)
+		if accessModifier == "-" && groupByVisibility {
+			privateMethods.WriteLineWithDepth(2, line)
 		} else {
-			publicMethods.WriteLineWithDepth(2, fmt.Sprintf(`%s %s(%s) %s`, accessModifier, method.Name, strings.Join(parameterList, ", "), returnValues))
+			publicMethods.WriteLineWithDepth(2, line)
 		}
 	}
+	if hidden > 0 {
+		// The methods left out are counted in the last line, written after the public ones
+		publicMethods.WriteLineWithDepth(2, "{method} "+render.MoreMembers(hidden))
+	}
+}
+
+// joinParameters joins the parameters of a method. When width is greater than 0 and the signature, which adds
+// prefixLength and suffixLength characters to the parameters, is longer than it, the parameters are wrapped onto
+// indented continuation lines of at most width characters. A parameter longer than width gets a line of its own
+func (r *renderer) joinParameters(parameters []string, prefixLength int, suffixLength int, width int) string {
+	joined := strings.Join(parameters, ", ")
+	if width <= 0 || prefixLength+len(joined)+suffixLength <= width {
+		return joined
+	}
+	lines := []string{}
+	current := ""
+	for i, parameter := range parameters {
+		if i < len(parameters)-1 {
+			parameter += ","
+		}
+		if current != "" && len(signatureIndent)+len(current)+1+len(parameter) > width {
+			lines = append(lines, current)
+			current = ""
+		}
+		if current != "" {
+			current += " "
+		}
+		current += parameter
+	}
+	lines = append(lines, current)
+	return `\n` + signatureIndent + strings.Join(lines, `\n`+signatureIndent) + `\n`
+}
+
+// formatType returns the given type escaped with memberEscaper and with its keywords highlighted, unless PlainText is
+// set
+func (r *renderer) formatType(p *parser.ClassParser, t string) string {
+	t = memberEscaper.Replace(t)
+	if p.RenderingOptions.PlainText {
+		return t
+	}
+	return typeKeywords.ReplaceAllString(t, "<font color=blue>$1</font>")
 }
 
 func (r *renderer) renderStructFields(p *parser.ClassParser, structure *parser.Struct, privateFields, publicFields *parser.LineStringBuilder) {
-	for _, field := range structure.Fields {
+	groupByVisibility := p.RenderingOptions.MemberOrder.GroupsByVisibility()
+	rendered, hidden := 0, 0
+	for _, field := range structure.SortedFields(p.RenderingOptions.MemberOrder) {
+		if field.Embedded && p.RenderingOptions.EmbeddedAsComposition {
+			continue
+		}
 		accessModifier := "+"
-		if unicode.IsLower(rune(field.Name[0])) {
+		if !field.IsExported() {
 			if !p.RenderingOptions.PrivateMembers {
 				continue
 			}
 
 			accessModifier = "-"
 		}
-		if accessModifier == "-" {
-			privateFields.WriteLineWithDepth(2, fmt.Sprintf(`%s %s %s`, accessModifier, field.Name, field.Type))
+		if max := p.RenderingOptions.MaxMembers; max > 0 && rendered >= max {
+			hidden++
+			continue
+		}
+		rendered++
+		fieldType := p.RenderingOptions.MemberType(field)
+		modifier := ""
+		if strings.Contains(fieldType, "(") {
+			// The parentheses of function types would make PlantUML read the field as a method
+			modifier = "{field} "
+		}
+		line := fmt.Sprintf(`%s%s %s %s%s`, modifier, accessModifier, memberEscaper.Replace(field.Name), r.formatType(p, fieldType), r.fieldComment(p, field))
+		if accessModifier == "-" && groupByVisibility {
+			privateFields.WriteLineWithDepth(2, line)
 		} else {
-			publicFields.WriteLineWithDepth(2, fmt.Sprintf(`%s %s %s`, accessModifier, field.Name, field.Type))
+			publicFields.WriteLineWithDepth(2, line)
 		}
 	}
+	if hidden > 0 {
+		// The parentheses of the line would make PlantUML read it as a method
+		publicFields.WriteLineWithDepth(2, "{field} "+render.MoreMembers(hidden))
+	}
+}
+
+// fieldComment returns the comment rendered after the given field, with its creole markup escaped. It is empty unless
+// FieldComments is set
+func (r *renderer) fieldComment(p *parser.ClassParser, field *parser.Field) string {
+	if !p.RenderingOptions.FieldComments || field.Comment == "" {
+		return ""
+	}
+	return " : " + docCommentEscaper.Replace(field.Comment)
+}
+
+// arrow returns the arrow the connections of the given kind are drawn with. Its line is split by the given color, as in
+// *-[#FF0000]-, unless NoColor is set. Custom arrows that already hold a style, or whose line is not made of two dashes
+// or dots, are drawn with the default color
+func (r *renderer) arrow(p *parser.ClassParser, kind string, color string) string {
+	arrow := connectors[kind]
+	if connector, ok := p.RenderingOptions.Connectors[kind]; ok {
+		arrow = connector
+	}
+	if p.RenderingOptions.NoColor || strings.Contains(arrow, "[") {
+		return arrow
+	}
+	line := arrowLine.FindStringIndex(arrow)
+	if line == nil {
+		return arrow
+	}
+	return fmt.Sprintf("%s[%s]%s", arrow[:line[0]+1], color, arrow[line[0]+1:])
+}
+
+// edgeColor returns the color of the connection of the given kind between source and target, which is the one of the
+// function set with WithColorFunc if any. Otherwise, unless a ColorSeed is set, the given random color is used. With a
+// seed, the color is derived from the seed and both ends of the connection so that the same connection keeps its color
+// between renders no matter the order in which it is rendered.
+func (r *renderer) edgeColor(p *parser.ClassParser, randColor string, kind string, source string, target string) string {
+	if r.colorFunc != nil {
+		return r.colorFunc(source, target, kind)
+	}
+	if p.RenderingOptions.ColorSeed == 0 {
+		return randColor
+	}
+	return seededColor(p.RenderingOptions.ColorSeed, source, target)
+}
+
+// seededColor returns the color derived from the given seed and both ends of the connection between source and target
+func seededColor(seed int, source string, target string) string {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%d %s %s", seed, source, target)
+	return hsvToHex(float64(h.Sum32()%360), 0.7, 0.85)
+}
+
+// hsvToHex converts the given hue (0-360), saturation and value (0-1) into a #RRGGBB color
+func hsvToHex(h, s, v float64) string {
+	c := v * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := v - c
+	var red, green, blue float64
+	switch {
+	case h < 60:
+		red, green, blue = c, x, 0
+	case h < 120:
+		red, green, blue = x, c, 0
+	case h < 180:
+		red, green, blue = 0, c, x
+	case h < 240:
+		red, green, blue = 0, x, c
+	case h < 300:
+		red, green, blue = x, 0, c
+	default:
+		red, green, blue = c, 0, x
+	}
+	return fmt.Sprintf("#%02X%02X%02X", int((red+m)*255), int((green+m)*255), int((blue+m)*255))
 }
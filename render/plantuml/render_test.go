@@ -0,0 +1,1700 @@
+package plantuml
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/jfeliu007/goplantuml/parser"
+)
+
+// colors matches the random colors the connections are drawn with
+var colors = regexp.MustCompile(`\[#[0-9A-Fa-f]{6}\]`)
+
+// withoutColors returns the given diagram with the connections drawn without their random colors
+func withoutColors(diagram string) string {
+	return colors.ReplaceAllString(diagram, "")
+}
+
+// writeFiles writes the given files, keyed by their path relative to a new temporary directory, and returns that
+// directory
+func writeFiles(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, content := range files {
+		name = filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatalf("Expected no errors, got %s", err.Error())
+		}
+		if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatalf("Expected no errors, got %s", err.Error())
+		}
+	}
+	return root
+}
+
+// newTestParser returns a parser that has not parsed any file, rendering with the given options
+func newTestParser(t *testing.T, options map[parser.RenderingOption]interface{}) *parser.ClassParser {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{
+		RenderingOptions: options,
+	})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	return p
+}
+
+func getTestStruct() *parser.Struct {
+	return &parser.Struct{
+		Type:        "class",
+		PackageName: "main",
+		Composition: map[string]struct{}{
+			"foopack.AnotherClass": {},
+		},
+		Extends: map[string]struct{}{
+			"NewClass": {},
+		},
+		Aggregations: map[string]struct{}{},
+		Fields: []*parser.Field{
+			{
+				Name: "privateField",
+				Type: "int",
+			},
+			{
+				Name: "PublicField",
+				Type: "error",
+			},
+		},
+		Functions: []*parser.Function{
+			{
+				Name: "foo",
+				Parameters: []*parser.Field{
+					{
+						Type: "int",
+					},
+					{
+						Type: "string",
+					},
+				},
+				ReturnValues: []string{"error", "int"},
+			},
+			{
+				Name: "Boo",
+				Parameters: []*parser.Field{
+					{
+						Type: "string",
+					},
+					{
+						Type: "int",
+					},
+				},
+				ReturnValues: []string{"int"},
+			},
+		},
+	}
+}
+
+func TestRenderStructFields(t *testing.T) {
+	p := newTestParser(t, map[parser.RenderingOption]interface{}{parser.RenderPrivateMembers: true})
+	st := &parser.Struct{
+		Fields: []*parser.Field{
+			{
+				Name: "privateField",
+				Type: "int",
+			},
+			{
+				Name: "PublicField",
+				Type: "string",
+			},
+		},
+	}
+	privateFields := &parser.LineStringBuilder{}
+	publicFields := &parser.LineStringBuilder{}
+	NewRender().renderStructFields(p, st, privateFields, publicFields)
+	if expected := "        - privateField int\n"; privateFields.String() != expected {
+		t.Errorf("Expected the private fields to be %q, got %q", expected, privateFields.String())
+	}
+	if expected := "        + PublicField string\n"; publicFields.String() != expected {
+		t.Errorf("Expected the public fields to be %q, got %q", expected, publicFields.String())
+	}
+}
+
+func TestRenderStructMethods(t *testing.T) {
+	p := newTestParser(t, map[parser.RenderingOption]interface{}{parser.RenderPrivateMembers: true})
+	st := &parser.Struct{
+		Functions: []*parser.Function{
+			{
+				Name: "foo",
+				Parameters: []*parser.Field{
+					{
+						Type: "int",
+					},
+					{
+						Type: "string",
+					},
+				},
+				ReturnValues: []string{"error", "int"},
+			},
+			{
+				Name: "Bar",
+				Parameters: []*parser.Field{
+					{
+						Type: "int",
+					},
+					{
+						Type: "string",
+					},
+				},
+				ReturnValues: []string{"int"},
+			},
+		},
+	}
+	privateMethods := &parser.LineStringBuilder{}
+	publicMethods := &parser.LineStringBuilder{}
+	NewRender().renderStructMethods(p, st, privateMethods, publicMethods)
+	if expected := "        - foo(int, string) (error, int)\n"; privateMethods.String() != expected {
+		t.Errorf("Expected the private methods to be %q, got %q", expected, privateMethods.String())
+	}
+	if expected := "        + Bar(int, string) int\n"; publicMethods.String() != expected {
+		t.Errorf("Expected the public methods to be %q, got %q", expected, publicMethods.String())
+	}
+}
+
+func TestRenderStructure(t *testing.T) {
+	p := newTestParser(t, map[parser.RenderingOption]interface{}{parser.RenderPrivateMembers: true})
+	str := &strings.Builder{}
+	lineWriter := parser.NewLineWriter(str)
+	composition := &parser.LineStringBuilder{}
+	extends := &parser.LineStringBuilder{}
+	aggregations := &parser.LineStringBuilder{}
+	NewRender().renderStructure(p, getTestStruct(), "main", "TestClass", lineWriter, composition, extends, aggregations, newRenderState(p))
+	tt := []struct {
+		name     string
+		result   string
+		expected string
+	}{
+		{name: "Class", result: str.String(), expected: "    class TestClass << (S,Aquamarine) >> {\n        - privateField int\n\n        + PublicField error\n\n        - foo(int, string) (error, int)\n\n        + Boo(string, int) int\n\n    }\n"},
+		{name: "Compositions", result: withoutColors(composition.String()), expected: `"foopack.AnotherClass" *-- "main.TestClass"` + "\n"},
+		{name: "Extends", result: withoutColors(extends.String()), expected: `"main.NewClass" <|.. "main.TestClass"` + "\n"},
+		{name: "Aggregations", result: aggregations.String(), expected: ""},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.result != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, tc.result)
+			}
+		})
+	}
+}
+
+func TestRenderCompositions(t *testing.T) {
+	tt := []struct {
+		name        string
+		composition string
+		expected    string
+	}{
+		{name: "OtherPackage", composition: "foopack.AnotherClass", expected: `"foopack.AnotherClass" *-- "main.TestClass"` + "\n"},
+		{name: "SamePackage", composition: "AnotherClass", expected: `"main.AnotherClass" *-- "main.TestClass"` + "\n"},
+		{name: "Builtin", composition: "int", expected: `"` + parser.BuiltinPackageName + `.int" *-- "main.TestClass"` + "\n"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p := newTestParser(t, nil)
+			st := &parser.Struct{
+				Type:        "class",
+				PackageName: "main",
+				Composition: map[string]struct{}{tc.composition: {}},
+			}
+			composition := &parser.LineStringBuilder{}
+			NewRender().renderCompositions(p, st, "TestClass", composition)
+			if result := withoutColors(composition.String()); result != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestRenderExtends(t *testing.T) {
+	tt := []struct {
+		name     string
+		extends  string
+		expected string
+	}{
+		{name: "OtherPackage", extends: "foopack.AnotherClass", expected: `"foopack.AnotherClass" <|.. "main.TestClass"` + "\n"},
+		{name: "SamePackage", extends: "AnotherClass", expected: `"main.AnotherClass" <|.. "main.TestClass"` + "\n"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p := newTestParser(t, nil)
+			st := &parser.Struct{
+				Type:        "class",
+				PackageName: "main",
+				Extends:     map[string]struct{}{tc.extends: {}},
+			}
+			extends := &parser.LineStringBuilder{}
+			NewRender().renderExtends(p, st, "TestClass", extends)
+			if result := withoutColors(extends.String()); result != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestRenderAggregations(t *testing.T) {
+	tt := []struct {
+		name     string
+		options  map[parser.RenderingOption]interface{}
+		expected string
+	}{
+		{
+			name:     "Public",
+			options:  map[parser.RenderingOption]interface{}{parser.RenderAggregations: true},
+			expected: `"main.TestClass" o-- "main.File"` + "\n",
+		},
+		{
+			name:     "Private",
+			options:  map[parser.RenderingOption]interface{}{parser.RenderAggregations: true, parser.AggregatePrivateMembers: true},
+			expected: "\"main.TestClass\" o-- \"main.File\"\n\"main.TestClass\" o-- \"main.File2\"\n",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p := newTestParser(t, tc.options)
+			st := &parser.Struct{
+				Type:                "class",
+				PackageName:         "main",
+				Aggregations:        map[string]struct{}{"File": {}},
+				PrivateAggregations: map[string]struct{}{"File2": {}},
+			}
+			aggregations := &parser.LineStringBuilder{}
+			NewRender().renderAggregations(p, st, "TestClass", aggregations)
+			if result := withoutColors(aggregations.String()); result != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestRenderingOptions(t *testing.T) {
+	tt := []struct {
+		name     string
+		options  map[parser.RenderingOption]interface{}
+		expected string
+	}{
+		{
+			name:    "ShowMembers",
+			options: map[parser.RenderingOption]interface{}{parser.RenderPrivateMembers: true},
+			expected: `@startuml
+skinparam nodesep 500
+skinparam ranksep 1500
+namespace github.com.jfeliu007.goplantuml.testingsupport.renderingoptions {
+    class Test << (S,Aquamarine) >> {
+        - integer int
+
+        - function() 
+
+    }
+}
+
+
+@enduml
+`,
+		},
+		{
+			name:    "HideFields",
+			options: map[parser.RenderingOption]interface{}{parser.RenderFields: false, parser.RenderPrivateMembers: true},
+			expected: `@startuml
+skinparam nodesep 500
+skinparam ranksep 1500
+namespace github.com.jfeliu007.goplantuml.testingsupport.renderingoptions {
+    class Test << (S,Aquamarine) >> {
+        - integer int
+
+        - function() 
+
+    }
+}
+
+
+hide fields
+@enduml
+`,
+		},
+		{
+			name:    "HideMethods",
+			options: map[parser.RenderingOption]interface{}{parser.RenderMethods: false, parser.RenderPrivateMembers: true},
+			expected: `@startuml
+skinparam nodesep 500
+skinparam ranksep 1500
+namespace github.com.jfeliu007.goplantuml.testingsupport.renderingoptions {
+    class Test << (S,Aquamarine) >> {
+        - integer int
+
+        - function() 
+
+    }
+}
+
+
+hide methods
+@enduml
+`,
+		},
+		{
+			name: "HidePrivateMembers",
+			expected: `@startuml
+skinparam nodesep 500
+skinparam ranksep 1500
+namespace github.com.jfeliu007.goplantuml.testingsupport.renderingoptions {
+    class Test << (S,Aquamarine) >> {
+    }
+}
+
+
+@enduml
+`,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := parser.NewClassDiagram([]string{"../../testingsupport/renderingoptions"}, []string{}, false)
+			if err != nil {
+				t.Fatalf("Expected no errors, got %s", err.Error())
+			}
+			if err = p.SetRenderingOptions(tc.options); err != nil {
+				t.Fatalf("Expected no errors, got %s", err.Error())
+			}
+			if result := NewRender().Render(p); result != tc.expected {
+				t.Errorf("Expected\n%s\ngot\n%s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestRenderConnectionLabels(t *testing.T) {
+	p, err := parser.NewClassDiagram([]string{"../../testingsupport/connectionlabels"}, []string{}, false)
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = p.SetRenderingOptions(map[parser.RenderingOption]interface{}{
+		parser.RenderConnectionLabels: true,
+		parser.RenderAggregations:     true,
+		parser.RenderPrivateMembers:   true,
+	})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	if result, expected := withoutColors(NewRender().Render(p)), `@startuml
+skinparam nodesep 500
+skinparam ranksep 1500
+namespace github.com.jfeliu007.goplantuml.testingsupport.connectionlabels {
+    interface AbstractInterface  {
+        - interfaceFunction() bool
+
+    }
+    class ImplementsAbstractInterface << (S,Aquamarine) >> {
+        + PublicUse .AbstractInterface
+
+        - interfaceFunction() bool
+
+    }
+    class github.com.jfeliu007.goplantuml.testingsupport.connectionlabels.AliasOfInt << (T, #FF7700) type >>  {
+    }
+}
+"github.com.jfeliu007.goplantuml.testingsupport.connectionlabels.AliasOfInt" *-- "extends""github.com.jfeliu007.goplantuml.testingsupport.connectionlabels.ImplementsAbstractInterface"
+
+"github.com.jfeliu007.goplantuml.testingsupport.connectionlabels.AbstractInterface" <|.. "implements""github.com.jfeliu007.goplantuml.testingsupport.connectionlabels.ImplementsAbstractInterface"
+
+"github.com.jfeliu007.goplantuml.testingsupport.connectionlabels.ImplementsAbstractInterface""uses" o-- "github.com.jfeliu007.goplantuml.testingsupport.connectionlabels.AbstractInterface"
+
+"github.com.jfeliu007.goplantuml.testingsupport.connectionlabels.AliasOfInt" --> "derives from""builtin.int"
+@enduml
+`; result != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, result)
+	}
+}
+
+func TestRenderParenthesizedTypeDeclarations(t *testing.T) {
+	p, err := parser.NewClassDiagram([]string{"../../testingsupport/parenthesizedtypedeclarations"}, []string{}, false)
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	if result, expected := NewRender().Render(p), `@startuml
+skinparam nodesep 500
+skinparam ranksep 1500
+namespace github.com.jfeliu007.goplantuml.testingsupport.parenthesizedtypedeclarations {
+    interface Bar  {
+        + Bar() 
+
+    }
+    interface Foo  {
+        + Foo() 
+
+    }
+}
+
+
+@enduml
+`; result != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, result)
+	}
+}
+
+func TestRenderGoldenFiles(t *testing.T) {
+	tt := []struct {
+		name        string
+		directories []string
+		options     map[parser.RenderingOption]interface{}
+		golden      string
+	}{
+		{
+			name:        "TestingSupport",
+			directories: []string{"../../testingsupport"},
+			options: map[parser.RenderingOption]interface{}{
+				parser.RenderTitle:          "Test Title",
+				parser.RenderNotes:          "Notes Example 1\nNotes Example 1 continues\nNotes Example 2",
+				parser.RenderPrivateMembers: true,
+			},
+			golden: "../../testingsupport/testingsupport.puml",
+		},
+		{
+			name:        "MultipleFolders",
+			directories: []string{"../../testingsupport/subfolder3", "../../testingsupport/subfolder2"},
+			golden:      "../../testingsupport/subfolder1-2.puml",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := parser.NewClassDiagram(tc.directories, []string{}, false)
+			if err != nil {
+				t.Fatalf("Expected no errors, got %s", err.Error())
+			}
+			if err = p.SetRenderingOptions(tc.options); err != nil {
+				t.Fatalf("Expected no errors, got %s", err.Error())
+			}
+			expected, err := ioutil.ReadFile(tc.golden)
+			if err != nil {
+				t.Fatalf("Expected no errors reading %s, got %s", tc.golden, err.Error())
+			}
+			if result := withoutColors(NewRender().Render(p)); result != string(expected) {
+				t.Errorf("Expected\n%s\ngot\n%s", expected, result)
+			}
+		})
+	}
+}
+
+func TestRenderImportGraph(t *testing.T) {
+	m := writeFiles(t, map[string]string{
+		"go.mod":     "module example.com/m\n",
+		"app/app.go": "package app\n\nimport (\n\t\"example.com/m/a/b\"\n\t\"example.com/m/a_b\"\n)\n\ntype App struct {\n\tB  b.B\n\tAB a_b.B\n}\n",
+		"a/b/b.go":   "package b\n\ntype B struct{}\n",
+		"a_b/a_b.go": "package a_b\n\ntype B struct{}\n",
+	})
+	directories := []string{filepath.Join(m, "app"), filepath.Join(m, "a", "b"), filepath.Join(m, "a_b")}
+	p, err := parser.NewClassDiagramFromDirectories(directories, parser.WithImportGraph(true))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	expected := `@startuml
+skinparam nodesep 500
+skinparam ranksep 1500
+package "example.com.m.a.b" as example__com__m__a__b {
+}
+package "example.com.m.a_b" as example__com__m__a_ub {
+}
+package "example.com.m.app" as example__com__m__app {
+}
+example__com__m__app ..> example__com__m__a__b : imports
+example__com__m__app ..> example__com__m__a_ub : imports
+@enduml
+`
+	if result := NewRender().Render(p); result != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, result)
+	}
+}
+
+func TestRenderPrivateAggregationsTwice(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{
+		Options: []parser.Option{parser.WithAggregations(true), parser.WithAggregatePrivateMembers(true), parser.WithNoColor(true)},
+	})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = p.ParseSource("shop.go", []byte(`package shop
+
+type Order struct {
+	Total int
+}
+
+type Item struct {
+	Price int
+}
+
+type Cart struct {
+	Orders []*Order
+	items  []Item
+}
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	structure := p.Structure[".shop"]["Cart"]
+	aggregations := map[string]struct{}{}
+	for agg := range structure.Aggregations {
+		aggregations[agg] = struct{}{}
+	}
+	first := NewRender().Render(p)
+	second := NewRender().Render(p)
+	if !reflect.DeepEqual(structure.Aggregations, aggregations) {
+		t.Errorf("Expected the aggregations of the parsed structure to stay %v, got %v", aggregations, structure.Aggregations)
+	}
+	if second != first {
+		t.Errorf("Expected rendering twice to give the same diagram, got\n%s\nthen\n%s", first, second)
+	}
+	if expected := `".shop.Cart" o-- ".shop.Item"`; !strings.Contains(first, expected) {
+		t.Errorf("Expected the diagram to contain %q, got\n%s", expected, first)
+	}
+}
+
+func TestRenderEscapesMembers(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{
+		Options: []parser.Option{parser.WithPlainText(true), parser.WithNoColor(true), parser.WithPrivateMembers(true)},
+	})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = p.ParseSource("escaped.go", []byte(`package escaped
+
+type Box[T interface{ ~int | ~string }] struct {
+	Value   T
+	Lookup  map[[2]int]string
+	Handler func(int) (string, error)
+	Twice   **int
+	my__id  int
+	Log     func(...any)
+	Wrap    func(func(...string), ...func(...int))
+}
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	result := NewRender().Render(p)
+	tt := []struct {
+		name     string
+		expected string
+	}{
+		{name: "Generic", expected: `class "Box[T interface{~~int | ~~string}]" as Box`},
+		{name: "Map", expected: "+ Lookup map[~[]int]string"},
+		{name: "Function", expected: "{field} + Handler func(int) (string, error)"},
+		{name: "Pointer", expected: "+ Twice ~**int"},
+		{name: "Name", expected: "- my~__id int"},
+		{name: "Variadic", expected: "{field} + Log func(...any)"},
+		{name: "NestedVariadic", expected: "{field} + Wrap func(func(...string) , ...func(...int) )"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if !strings.Contains(result, tc.expected) {
+				t.Errorf("Expected the diagram to contain %q, got\n%s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestRenderBuiltinAggregations(t *testing.T) {
+	source := []byte(`package people
+
+type Names []string
+
+type Person struct {
+	Name string
+	Tags Names
+}
+`)
+	for _, include := range []bool{false, true} {
+		p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{
+			Options: []parser.Option{parser.WithNoColor(true), parser.WithAggregations(true), parser.WithBuiltinAggregations(include)},
+		})
+		if err != nil {
+			t.Fatalf("Expected no errors, got %s", err.Error())
+		}
+		if err := p.ParseSource("people.go", source); err != nil {
+			t.Fatalf("Expected no errors, got %s", err.Error())
+		}
+		result := NewRender().Render(p)
+		if !strings.Contains(result, `".people.Person" o-- ".people.Names"`) {
+			t.Errorf("Expected the aggregation of Names to be rendered, got\n%s", result)
+		}
+		if strings.Contains(result, `".people.Person" o-- "builtin.string"`) != include {
+			t.Errorf("Expected the aggregation of string to be rendered only with BuiltinAggregations %t, got\n%s", include, result)
+		}
+	}
+}
+
+func TestRenderInterfaceCollectionAggregations(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{
+		Options: []parser.Option{parser.WithNoColor(true), parser.WithAggregations(true), parser.WithMultiplicity(true)},
+	})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = p.ParseSource("web.go", []byte(`package web
+
+type Handler interface {
+	Serve()
+}
+
+type Router struct {
+	Handlers []Handler
+	ByName   map[string]Handler
+}
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	result := NewRender().Render(p)
+	if expected := `".web.Router" o-- "0..*" ".web.Handler"`; !strings.Contains(result, expected) {
+		t.Errorf("Expected the diagram to contain %q, got\n%s", expected, result)
+	}
+}
+
+func TestRenderFlattenPackages(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{
+		Options: []parser.Option{parser.WithNoColor(true), parser.WithAggregations(true), parser.WithFlattenPackages(true)},
+	})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	sources := map[string]string{
+		"a/a.go": "package a\n\ntype Person struct {\n\tOther *Shared\n}\n\ntype Shared struct{}\n",
+		"b/b.go": "package b\n\ntype Shared struct{}\n",
+	}
+	for _, name := range []string{"a/a.go", "b/b.go"} {
+		if err := p.ParseSource(name, []byte(sources[name])); err != nil {
+			t.Fatalf("Expected no errors, got %s", err.Error())
+		}
+	}
+	result := NewRender().Render(p)
+	if strings.Contains(result, "namespace ") {
+		t.Errorf("Expected no namespaces, got\n%s", result)
+	}
+	tt := []struct {
+		name     string
+		expected string
+	}{
+		{name: "Separator", expected: "set namespaceSeparator none"},
+		{name: "Unique", expected: `class "Person" as .a.Person`},
+		{name: "Ambiguous", expected: "class .b.Shared "},
+		{name: "Aggregation", expected: `".a.Person" o-- ".a.Shared"`},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if !strings.Contains(result, tc.expected) {
+				t.Errorf("Expected the diagram to contain %q, got\n%s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestRenderGroupByFile(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{
+		Options: []parser.Option{parser.WithGroupBy(parser.GroupByFile)},
+	})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	sources := map[string]string{
+		"shop/order.go":    "package shop\n\ntype Order struct{}\n\ntype OrderLine struct{}\n",
+		"shop/customer.go": "package shop\n\ntype Customer struct{}\n",
+	}
+	for _, name := range []string{"shop/order.go", "shop/customer.go"} {
+		if err := p.ParseSource(name, []byte(sources[name])); err != nil {
+			t.Fatalf("Expected no errors, got %s", err.Error())
+		}
+	}
+	result := NewRender().Render(p)
+	if strings.Count(result, "together {") != 1 {
+		t.Fatalf("Expected a single together block, got\n%s", result)
+	}
+	// The classes have no members, so every class is followed by its closing brace
+	var lines []string
+	for _, line := range strings.Split(result, "\n") {
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	for i, line := range lines {
+		if line != "together {" {
+			continue
+		}
+		if i+5 >= len(lines) || !strings.HasPrefix(lines[i+1], "class Order ") || !strings.HasPrefix(lines[i+3], "class OrderLine ") || lines[i+5] != "}" {
+			t.Errorf("Expected Order and OrderLine to be rendered together, got\n%s", result)
+		}
+	}
+}
+
+func TestRenderConnectors(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = p.ParseSource("shapes.go", []byte(`package shapes
+
+type Shape interface {
+	Area() float64
+}
+
+type Square struct {
+	Side float64
+}
+
+func (s Square) Area() float64 {
+	return s.Side * s.Side
+}
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	tt := []struct {
+		name     string
+		options  []parser.Option
+		expected string
+	}{
+		{
+			name:     "Default",
+			options:  []parser.Option{parser.WithNoColor(true)},
+			expected: `".shapes.Shape" <|.. ".shapes.Square"`,
+		},
+		{
+			name:     "Custom",
+			options:  []parser.Option{parser.WithNoColor(true), parser.WithConnectors(map[string]string{"realization": "<|--"})},
+			expected: `".shapes.Shape" <|-- ".shapes.Square"`,
+		},
+		{
+			name:     "Colored",
+			options:  []parser.Option{parser.WithNoColor(false), parser.WithColorSeed(1), parser.WithConnectors(map[string]string{"realization": "<|--"})},
+			expected: `".shapes.Shape" <|-[#`,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := p.ApplyOptions(tc.options...); err != nil {
+				t.Fatalf("Expected no errors, got %s", err.Error())
+			}
+			result := NewRender().Render(p)
+			if !strings.Contains(result, tc.expected) {
+				t.Errorf("Expected the diagram to contain %q, got\n%s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestRenderExtendsRelationships(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = p.ParseSource("shapes.go", []byte(`package shapes
+
+type Shape interface {
+	Area() float64
+}
+
+type Polygon interface {
+	Shape
+	Sides() int
+}
+
+type Square struct {
+	Side float64
+}
+
+func (s Square) Area() float64 {
+	return s.Side * s.Side
+}
+
+func (s Square) Sides() int {
+	return 4
+}
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	if err := p.ApplyOptions(parser.WithNoColor(true), parser.WithConnectionLabels(true)); err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	result := NewRender().Render(p)
+	for _, expected := range []string{
+		`".shapes.Shape" <|-- "extends"".shapes.Polygon"`,
+		`".shapes.Polygon" <|.. "implements"".shapes.Square"`,
+		`".shapes.Shape" <|.. "implements"".shapes.Square"`,
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected the diagram to contain %q, got\n%s", expected, result)
+		}
+	}
+}
+
+func TestRenderMarkdownFence(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = p.ParseSource("shapes.go", []byte(`package shapes
+
+type Square struct {
+	Side float64
+}
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	tt := []struct {
+		name    string
+		options []parser.Option
+	}{
+		{
+			name:    "Classes",
+			options: []parser.Option{parser.WithMarkdownFence(true)},
+		},
+		{
+			name:    "ImportGraph",
+			options: []parser.Option{parser.WithMarkdownFence(true), parser.WithImportGraph(true)},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := p.ApplyOptions(tc.options...); err != nil {
+				t.Fatalf("Expected no errors, got %s", err.Error())
+			}
+			result := NewRender().Render(p)
+			if !strings.HasPrefix(result, "```plantuml\n@startuml\n") || !strings.HasSuffix(result, "@enduml\n```\n") {
+				t.Errorf("Expected the diagram to be fenced, got\n%s", result)
+			}
+		})
+	}
+}
+
+func TestRenderPackageAliases(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	for path, source := range map[string]string{
+		"orders/orders.go": `package orders
+
+import "github.com/myorg/myrepo/internal/services/shapes"
+
+type Order struct {
+	Shape *shapes.Shape
+}
+`,
+		"shapes/shapes.go": `package shapes
+
+type Shape interface {
+	Area() float64
+}
+
+type Square struct {
+	Side float64
+}
+
+func (s Square) Area() float64 {
+	return s.Side * s.Side
+}
+`,
+	} {
+		if err := p.ParseSource(path, []byte(source)); err != nil {
+			t.Fatalf("Expected no errors, got %s", err.Error())
+		}
+	}
+	err = p.ApplyOptions(
+		parser.WithNoColor(true),
+		parser.WithAggregations(true),
+		parser.WithPackageAliases(map[string]string{"github.com.myorg.myrepo": "", ".shapes": "geometry"}),
+	)
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	result := NewRender().Render(p)
+	for _, expected := range []string{
+		"namespace geometry {",
+		`"geometry.Shape" <|.. "geometry.Square"`,
+		`".orders.Order" o-- "internal.services.shapes.Shape"`,
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected the diagram to contain %q, got\n%s", expected, result)
+		}
+	}
+	if err := p.ApplyOptions(parser.WithPackageAliases(map[string]string{"github.com.": "gh"})); err == nil {
+		t.Error("Expected an error for a prefix ending with a dot")
+	}
+}
+
+func TestRenderUnnamedParameters(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = p.ParseSource("doer.go", []byte(`package doer
+
+import "context"
+
+type Doer interface {
+	Do(context.Context, []byte) error
+	DoNamed(ctx context.Context, data []byte) error
+}
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	result := NewRender().Render(p)
+	for _, expected := range []string{
+		"+ Do(context.Context, []byte) error\n",
+		"+ DoNamed(ctx context.Context, data []byte) error\n",
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected the diagram to contain %q, got\n%s", expected, result)
+		}
+	}
+}
+
+func TestRenderPackageLegends(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{
+		Options: []parser.Option{parser.WithPackageLegends(true)},
+	})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = p.ParseSource("shapes.go", []byte(`package shapes
+
+type Shape interface {
+	Area() float64
+}
+
+type Square struct {
+	Side float64
+}
+
+type Circle struct {
+	Radius float64
+}
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	result := NewRender().Render(p)
+	expected := "    note as __shapes_legend\n        <b>.shapes</b>\n        Structs: 2\n        Interfaces: 1\n    end note\n}\n"
+	if !strings.Contains(result, expected) {
+		t.Errorf("Expected the diagram to contain %q, got\n%s", expected, result)
+	}
+	// The types that are not rendered are not counted
+	if err = p.ApplyOptions(parser.WithExcludePattern(`Circle`)); err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	result = NewRender().Render(p)
+	expected = "    note as __shapes_legend\n        <b>.shapes</b>\n        Structs: 1\n        Interfaces: 1\n    end note\n}\n"
+	if !strings.Contains(result, expected) {
+		t.Errorf("Expected the diagram to contain %q, got\n%s", expected, result)
+	}
+}
+
+func TestRenderGenericAliases(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{
+		Options: []parser.Option{parser.WithNoColor(true), parser.WithConnectionLabels(true)},
+	})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = p.ParseSource("lists.go", []byte(`package lists
+
+type List[T any] struct {
+	Items []T
+}
+
+type IntList = List[int]
+
+type Ints List[int]
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	result := NewRender().Render(p)
+	for _, expected := range []string{
+		`".lists.List" #.. "alias of"".lists.IntList" : [int]`,
+		`".lists.Ints" --> "derives from"".lists.List" : [int]`,
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected the diagram to contain %q, got\n%s", expected, result)
+		}
+	}
+}
+
+func TestRenderConstValues(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{
+		Options: []parser.Option{parser.WithConstants(true), parser.WithConstValues(true)},
+	})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = p.ParseSource("limits.go", []byte(`package limits
+
+type Limit int
+
+const MaxRetries Limit = 5
+
+type Flag uint
+
+const (
+	Read Flag = 1 << iota
+	Write
+	Execute
+)
+
+type State string
+
+const Unknown = State(unknown())
+
+func unknown() string { return "" }
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	result := NewRender().Render(p)
+	for _, expected := range []string{
+		"        MaxRetries = 5\n",
+		"        Read = 1\n        Write = 2\n        Execute = 4\n",
+		"        Unknown\n",
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected the diagram to contain %q, got\n%s", expected, result)
+		}
+	}
+}
+
+func TestRenderHideSelfReferences(t *testing.T) {
+	source := []byte(`package tree
+
+type Node struct {
+	Parent   *Node
+	Children []*Node
+	Leaf     *Leaf
+}
+
+type Leaf struct {
+	Value int
+}
+`)
+	tt := []struct {
+		name     string
+		hide     bool
+		expected bool
+	}{
+		{name: "Shown by default", hide: false, expected: true},
+		{name: "Hidden", hide: true, expected: false},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{
+				Options: []parser.Option{parser.WithNoColor(true), parser.WithAggregations(true), parser.WithHideSelfReferences(tc.hide)},
+			})
+			if err != nil {
+				t.Fatalf("Expected no errors, got %s", err.Error())
+			}
+			if err = p.ParseSource("tree.go", source); err != nil {
+				t.Fatalf("Expected no errors, got %s", err.Error())
+			}
+			result := NewRender().Render(p)
+			if !strings.Contains(result, `".tree.Node" o-- ".tree.Leaf"`) {
+				t.Errorf("Expected the aggregation of Leaf to be rendered, got\n%s", result)
+			}
+			if strings.Contains(result, `".tree.Node" o-- ".tree.Node"`) != tc.expected {
+				t.Errorf("Expected the aggregation of Node with itself to be rendered: %t, got\n%s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestRenderAliasColors(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = p.ParseSource("ids.go", []byte(`package ids
+
+type Identifier struct{}
+
+type ID = Identifier
+
+type Key Identifier
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	result := NewRender().Render(p)
+	for _, expected := range []string{
+		fmt.Sprintf(`".idsIdentifier" #.[%s]. ".ids.ID"`, seededColor(0, ".idsIdentifier", ".ids.ID")),
+		fmt.Sprintf(`".ids.Key" -[%s]-> ".idsIdentifier"`, seededColor(0, ".ids.Key", ".idsIdentifier")),
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected the diagram to contain %q, got\n%s", expected, result)
+		}
+	}
+	if again := NewRender().Render(p); again != result {
+		t.Errorf("Expected the diagram not to change between renders, got\n%s\nand\n%s", result, again)
+	}
+}
+
+func TestRenderNoteDirective(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = p.ParseSource("registry.go", []byte(`package registry
+
+//plantuml:note "Safe for <concurrent> use"
+type Registry struct{}
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	result := NewRender().Render(p)
+	expected := "    note right of Registry : Safe for ~<concurrent> use\n"
+	if !strings.Contains(result, expected) {
+		t.Errorf("Expected the diagram to contain %q, got\n%s", expected, result)
+	}
+}
+
+func TestRenderConstraintEdges(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{
+		Options: []parser.Option{parser.WithNoColor(true), parser.WithPackageFunctions(true), parser.WithConstraintEdges(true)},
+	})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = p.ParseSource("shapes.go", []byte(`package shapes
+
+import "fmt"
+
+type Shape interface {
+	Area() float64
+}
+
+type Ordered[T any] interface {
+	Less(T) bool
+}
+
+type Box[T Shape, S fmt.Stringer] struct {
+	Items []T
+}
+
+type Sorted[T Ordered[T]] []T
+
+type Number[T interface{ ~int | ~float64 }] struct {
+	Value T
+}
+
+func Largest[T Shape](shapes ...T) T {
+	return shapes[0]
+}
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	result := NewRender().Render(p)
+	for _, expected := range []string{
+		`".shapes.Box" ..> ".shapes.Shape"`,
+		`".shapes.Sorted" ..> ".shapes.Ordered"`,
+		`".shapes.functions" ..> ".shapes.Shape"`,
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected the diagram to contain %q, got\n%s", expected, result)
+		}
+	}
+	// Neither inline constraints nor the interfaces that were not parsed are connected
+	for _, unexpected := range []string{`".shapes.Number" ..>`, `"fmt.Stringer"`} {
+		if strings.Contains(result, unexpected) {
+			t.Errorf("Expected the diagram not to contain %q, got\n%s", unexpected, result)
+		}
+	}
+}
+
+func TestRenderColorFunc(t *testing.T) {
+	var kinds []string
+	colorFunc := func(src, dst, relKind string) string {
+		kinds = append(kinds, fmt.Sprintf("%s %s %s", src, relKind, dst))
+		return "#ABCDEF"
+	}
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{
+		Options: []parser.Option{parser.WithAggregations(true), parser.WithAliases(true)},
+	})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = p.ParseSource("zoo.go", []byte(`package zoo
+
+type Animal struct {
+	Name string
+}
+
+type Pet = Animal
+
+type Zoo struct {
+	Animals []*Animal
+}
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	result := NewRender(WithColorFunc(colorFunc)).Render(p)
+	for _, expected := range []string{`".zoo.Zoo" o-[#ABCDEF]- ".zoo.Animal"`, `".zooAnimal" #.[#ABCDEF]. ".zoo.Pet"`} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected the diagram to contain %q, got\n%s", expected, result)
+		}
+	}
+	expected := []string{".zoo.Zoo aggregation .zoo.Animal", ".zooAnimal alias .zoo.Pet"}
+	if !reflect.DeepEqual(kinds, expected) {
+		t.Errorf("Expected the color function to be called for %q, got %q", expected, kinds)
+	}
+	// The function is only used by the renderer it was given to
+	if result = NewRender().Render(p); strings.Contains(result, "#ABCDEF") {
+		t.Errorf("Expected the other renderers not to use the color function, got\n%s", result)
+	}
+}
+
+func TestRenderPackageVars(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{
+		Options: []parser.Option{parser.WithNoColor(true), parser.WithAggregations(true), parser.WithFieldNameLabels(true), parser.WithPackageVars(true)},
+	})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = p.ParseSource("client.go", []byte(`package client
+
+type Client struct {
+	Retries int
+}
+
+var (
+	DefaultClient = &Client{}
+	Backup        Client
+	Timeout       = 30
+	fallback      = &Client{}
+)
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	result := NewRender().Render(p)
+	for _, expected := range []string{
+		"class vars << (V,#B0C4DE) var >> {",
+		"+ DefaultClient *.Client",
+		"+ Backup .Client",
+		`".client.vars" o-- ".client.Client" : DefaultClient, Backup`,
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected the diagram to contain %q, got\n%s", expected, result)
+		}
+	}
+	// Private variables are hidden like the private fields, and the ones that do not hold a struct are not rendered
+	for _, unexpected := range []string{"fallback", "Timeout"} {
+		if strings.Contains(result, unexpected) {
+			t.Errorf("Expected the diagram not to contain %q, got\n%s", unexpected, result)
+		}
+	}
+}
+
+func TestRenderMaxMembers(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{
+		Options: []parser.Option{parser.WithMemberOrder(parser.MemberOrderSource), parser.WithMaxMembers(2)},
+	})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = p.ParseSource("big.go", []byte(`package big
+
+type Big struct {
+	A int
+	B int
+	C int
+	D int
+	E int
+}
+
+func (b *Big) One()   {}
+func (b *Big) Two()   {}
+func (b *Big) Three() {}
+
+type Small struct {
+	A int
+	B int
+}
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	result := NewRender().Render(p)
+	for _, expected := range []string{
+		"+ A int\n        + B int\n        {field} ... (+3 more)\n",
+		"+ One() \n        + Two() \n        {method} ... (+1 more)\n",
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected the diagram to contain %q, got\n%s", expected, result)
+		}
+	}
+	for _, unexpected := range []string{"+ C int", "+ Three()", "(+0 more)"} {
+		if strings.Contains(result, unexpected) {
+			t.Errorf("Expected the diagram not to contain %q, got\n%s", unexpected, result)
+		}
+	}
+}
+
+func TestRenderAliasCycles(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{
+		Options: []parser.Option{parser.WithNoColor(true), parser.WithAliases(true)},
+	})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = p.ParseSource("loop.go", []byte(`package loop
+
+type A = B
+
+type B = A
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	result := NewRender().Render(p)
+	for _, expected := range []string{
+		`".loop.B" #.. ".loop.A"`,
+		`note "alias cycle: .loop.A -> .loop.B -> .loop.A" as __loop__A_cycle`,
+		`__loop__A_cycle .. ".loop.A"`,
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected the diagram to contain %q, got\n%s", expected, result)
+		}
+	}
+	if count := strings.Count(result, "#.."); count != 1 {
+		t.Errorf("Expected a single connection for the cycle, got %d in\n%s", count, result)
+	}
+	for _, unexpected := range []string{`".loopA"`, `".loopB"`} {
+		if strings.Contains(result, unexpected) {
+			t.Errorf("Expected the diagram not to contain %q, got\n%s", unexpected, result)
+		}
+	}
+}
+
+func TestRenderQualifiedMemberTypes(t *testing.T) {
+	source := []byte(`package shop
+
+type Order struct {
+	Total int
+}
+
+type Cart struct {
+	Orders []*Order
+}
+
+func (c *Cart) Add(o Order) {}
+`)
+	tt := []struct {
+		qualified bool
+		expected  []string
+	}{
+		{qualified: false, expected: []string{"+ Orders []*.Order", "+ Add(o .Order) "}},
+		{qualified: true, expected: []string{"+ Orders []*.shop.Order", "+ Add(o .shop.Order) "}},
+	}
+	for _, tc := range tt {
+		p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{
+			Options: []parser.Option{parser.WithQualifiedMemberTypes(tc.qualified)},
+		})
+		if err != nil {
+			t.Fatalf("Expected no errors, got %s", err.Error())
+		}
+		if err = p.ParseSource("shop.go", source); err != nil {
+			t.Fatalf("Expected no errors, got %s", err.Error())
+		}
+		result := NewRender().Render(p)
+		for _, expected := range tc.expected {
+			if !strings.Contains(result, expected) {
+				t.Errorf("Expected the diagram to contain %q with QualifiedMemberTypes %t, got\n%s", expected, tc.qualified, result)
+			}
+		}
+	}
+}
+
+func TestRenderColorSeed(t *testing.T) {
+	source := []byte(`package zoo
+
+type Animal interface {
+	Name() string
+}
+
+type Keeper struct{}
+
+type Cage struct {
+	Keeper
+	Animals []Animal
+}
+
+type Dog struct{}
+
+func (d Dog) Name() string {
+	return "dog"
+}
+`)
+	renderWithSeed := func(seed int) string {
+		p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{
+			Options: []parser.Option{parser.WithAggregations(true), parser.WithColorSeed(seed)},
+		})
+		if err != nil {
+			t.Fatalf("Expected no errors, got %s", err.Error())
+		}
+		if err = p.ParseSource("zoo.go", source); err != nil {
+			t.Fatalf("Expected no errors, got %s", err.Error())
+		}
+		return NewRender().Render(p)
+	}
+	first := renderWithSeed(1)
+	if second := renderWithSeed(1); second != first {
+		t.Errorf("Expected the same seed to give the same diagram, got\n%s\nthen\n%s", first, second)
+	}
+	if len(colors.FindAllString(first, -1)) != 3 {
+		t.Errorf("Expected the three connections to be colored, got\n%s", first)
+	}
+	other := renderWithSeed(2)
+	if withoutColors(other) != withoutColors(first) {
+		t.Errorf("Expected another seed to only change the colors, got\n%s\nthen\n%s", first, other)
+	}
+	if reflect.DeepEqual(colors.FindAllString(other, -1), colors.FindAllString(first, -1)) {
+		t.Errorf("Expected another seed to change the colors, got\n%s\nthen\n%s", first, other)
+	}
+}
+
+func TestRenderNestedNamespaces(t *testing.T) {
+	m := writeFiles(t, map[string]string{
+		"go.mod":     "module example.com/m\n",
+		"a/b/b.go":   "package b\n\ntype B struct{}\n",
+		"a/b/c/c.go": "package c\n\ntype C struct{}\n",
+	})
+	directories := []string{filepath.Join(m, "a", "b"), filepath.Join(m, "a", "b", "c")}
+	p, err := parser.NewClassDiagramFromDirectories(directories, parser.WithNestedNamespaces(true))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	result := NewRender().Render(p)
+	expected := `@startuml
+skinparam nodesep 500
+skinparam ranksep 1500
+namespace example {
+    namespace com {
+        namespace m {
+            namespace a {
+                namespace b {
+                    class B << (S,Aquamarine) >> {
+                    }
+                    namespace c {
+                        class C << (S,Aquamarine) >> {
+                        }
+                    }
+                }
+            }
+        }
+    }
+}
+
+
+@enduml
+`
+	if result != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, result)
+	}
+}
+
+func TestRenderStereotypes(t *testing.T) {
+	source := []byte(`package shapes
+
+type Square struct {
+	Side float64
+}
+
+type Kind string
+
+const Big Kind = "big"
+`)
+	stereotypes := map[string]string{"class": "<<entity>>", "type": "<<value>>"}
+	tt := []struct {
+		name     string
+		options  []parser.Option
+		expected []string
+	}{
+		{
+			name:     "Default",
+			expected: []string{"class Square << (S,Aquamarine) >> {", "class .shapes.Kind << (T, #FF7700) type >>  {"},
+		},
+		{
+			name:     "Stereotypes",
+			options:  []parser.Option{parser.WithStereotypes(stereotypes)},
+			expected: []string{"class Square <<entity>> {", "class .shapes.Kind <<value>> {"},
+		},
+		{
+			// The enumerations of the types with constants keep their keyword instead
+			name:     "Constants",
+			options:  []parser.Option{parser.WithStereotypes(stereotypes), parser.WithConstants(true)},
+			expected: []string{"class Square <<entity>> {", "enum .shapes.Kind  {"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{Options: tc.options})
+			if err != nil {
+				t.Fatalf("Expected no errors, got %s", err.Error())
+			}
+			if err = p.ParseSource("shapes.go", source); err != nil {
+				t.Fatalf("Expected no errors, got %s", err.Error())
+			}
+			result := NewRender().Render(p)
+			for _, expected := range tc.expected {
+				if !strings.Contains(result, expected) {
+					t.Errorf("Expected the diagram to contain %q, got\n%s", expected, result)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderPerPackageSharedPrefix(t *testing.T) {
+	m := writeFiles(t, map[string]string{
+		"go.mod":           "module example.com/m\n",
+		"foo/foo.go":       "package foo\n\ntype Item struct{}\n\ntype Ref = Item\n",
+		"foobar/foobar.go": "package foobar\n\nimport \"example.com/m/foo\"\n\ntype Item struct{}\n\ntype Ref = Item\n\ntype Box struct {\n\tItem\n\tItems []foo.Item\n}\n",
+	})
+	directories := []string{filepath.Join(m, "foo"), filepath.Join(m, "foobar")}
+	p, err := parser.NewClassDiagramFromDirectories(directories, parser.WithNoColor(true), parser.WithAggregations(true), parser.WithAliases(true), parser.WithConstraintEdges(true))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	documents := NewRender().RenderPerPackage(p)
+	foo := documents["example.com.m.foo"]
+	if expected := `"example.com.m.foobar.Box" o-- "example.com.m.foo.Item"`; !strings.Contains(foo, expected) {
+		t.Errorf("Expected the diagram to contain %q, got\n%s", expected, foo)
+	}
+	// The connections between the types of foobar do not touch foo even if its name is a prefix of foobar
+	for _, unexpected := range []string{`*-- "example.com.m.foobar.Box"`, `#.. "example.com.m.foobar.Ref"`} {
+		if strings.Contains(foo, unexpected) {
+			t.Errorf("Expected the diagram not to contain %q, got\n%s", unexpected, foo)
+		}
+	}
+	// No constraint connects the types of foo, so no empty section is written for them
+	if strings.Contains(foo, "\n\n\n@enduml") {
+		t.Errorf("Expected no empty sections before @enduml, got\n%s", foo)
+	}
+}
+
+func TestRenderHideEmptyMembers(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = p.ParseSource("shapes.go", []byte(`package shapes
+
+type Empty struct{}
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	tt := []struct {
+		name     string
+		hide     bool
+		expected bool
+	}{
+		{
+			name:     "Hidden",
+			hide:     true,
+			expected: true,
+		},
+		{
+			name:     "Not hidden",
+			hide:     false,
+			expected: false,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := p.ApplyOptions(parser.WithHideEmptyMembers(tc.hide)); err != nil {
+				t.Fatalf("Expected no errors, got %s", err.Error())
+			}
+			result := NewRender().Render(p)
+			if contains := strings.Contains(result, "\nhide empty members\n"); contains != tc.expected {
+				t.Errorf("Expected hide empty members to be rendered %t, got\n%s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestRenderMaxSignatureWidth(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = p.ParseSource("shapes.go", []byte(`package shapes
+
+type Box struct{}
+
+func (b Box) Resize(width float64, height float64, depth float64) error {
+	return nil
+}
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	tt := []struct {
+		name     string
+		width    int
+		expected string
+	}{
+		{
+			name:     "Wrapped",
+			width:    40,
+			expected: `+ Resize(\n    width float64, height float64,\n    depth float64\n) error`,
+		},
+		{
+			name:     "Not wrapped",
+			width:    0,
+			expected: `+ Resize(width float64, height float64, depth float64) error`,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := p.ApplyOptions(parser.WithPlainText(true), parser.WithMaxSignatureWidth(tc.width)); err != nil {
+				t.Fatalf("Expected no errors, got %s", err.Error())
+			}
+			result := NewRender().Render(p)
+			if !strings.Contains(result, "\n        "+tc.expected+"\n") {
+				t.Errorf("Expected the diagram to contain %q, got\n%s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestRenderTheme(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{
+		Options: []parser.Option{parser.WithTheme("cerulean")},
+	})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	err = p.ParseSource("shapes.go", []byte(`package shapes
+
+type Square struct{}
+`))
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	result := NewRender().Render(p)
+	if expected := "@startuml\n!theme cerulean\nskinparam nodesep 500\n"; !strings.HasPrefix(result, expected) {
+		t.Errorf("Expected the diagram to start with %q, got\n%s", expected, result)
+	}
+}
@@ -0,0 +1,88 @@
+package plantuml
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jfeliu007/goplantuml/parser"
+)
+
+// encoding is the base64 variant of PlantUML, which uses its own alphabet so the encoded diagrams can be written in URLs
+var encoding = base64.NewEncoding("0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz-_").WithPadding(base64.NoPadding)
+
+// maxURLLength is the length of the longest URL sent to the PlantUML servers. Their URLs are limited, to 8192 bytes
+// by default for the ones running on Jetty, so longer diagrams are posted instead
+const maxURLLength = 4096
+
+// maxSVGSize is the size of the largest SVG read from the PlantUML servers
+var maxSVGSize int64 = 32 << 20
+
+// defaultClient is the client RenderSVG uses when it is not given one, which does not wait forever for the server
+var defaultClient = &http.Client{Timeout: 30 * time.Second}
+
+// RenderSVG renders the diagram of the given parser and returns the SVG the PlantUML server at serverURL, such as
+// https://www.plantuml.com/plantuml, draws from it, so the Java jar of PlantUML does not need to be installed. The
+// diagram is sent in the URL, as /svg/ followed by its encoded text, unless the URL would be longer than the servers
+// accept, in which case its text is posted to /svg. The request is made with the given client, or with one that times
+// out after 30 seconds when it is nil, and is canceled with ctx. It fails if the server cannot be reached, does not
+// answer with 200 OK or answers with more than 32 MiB
+func RenderSVG(ctx context.Context, client *http.Client, p *parser.ClassParser, serverURL string) ([]byte, error) {
+	if client == nil {
+		client = defaultClient
+	}
+	text := NewRender().Render(p)
+	encoded, err := Encode(text)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := fmt.Sprintf("%s/svg", strings.TrimSuffix(serverURL, "/"))
+	var request *http.Request
+	if len(endpoint)+1+len(encoded) > maxURLLength {
+		request, err = http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(text))
+	} else {
+		request, err = http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/"+encoded, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("PlantUML server answered %s", response.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxSVGSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > maxSVGSize {
+		return nil, fmt.Errorf("PlantUML server answered more than %d bytes", maxSVGSize)
+	}
+	return body, nil
+}
+
+// Encode returns the given PlantUML text compressed with deflate and encoded with the base64 variant of PlantUML, as the
+// PlantUML servers expect it in their URLs
+func Encode(text string) (string, error) {
+	compressed := &bytes.Buffer{}
+	writer, err := flate.NewWriter(compressed, flate.BestCompression)
+	if err != nil {
+		return "", err
+	}
+	if _, err := writer.Write([]byte(text)); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(compressed.Bytes()), nil
+}
@@ -0,0 +1,116 @@
+package plantuml
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jfeliu007/goplantuml/parser"
+)
+
+// newSVGServer returns a server answering <svg></svg> to the diagrams encoded in the URLs of /plantuml/svg/ and posted
+// to /plantuml/svg. The text of the last diagram it received is stored in received
+func newSVGServer(received *string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/plantuml/svg" {
+			text, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			*received = string(text)
+			w.Write([]byte("<svg></svg>"))
+			return
+		}
+		if !strings.HasPrefix(r.URL.Path, "/plantuml/svg/") {
+			http.NotFound(w, r)
+			return
+		}
+		compressed, err := encoding.DecodeString(strings.TrimPrefix(r.URL.Path, "/plantuml/svg/"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		text, err := io.ReadAll(flate.NewReader(bytes.NewReader(compressed)))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		*received = string(text)
+		w.Write([]byte("<svg></svg>"))
+	}))
+}
+
+func TestRenderSVG(t *testing.T) {
+	long := &strings.Builder{}
+	long.WriteString("package shapes\n")
+	for i := 0; i < 1000; i++ {
+		// The names are scattered so the diagram does not compress into a short URL
+		fmt.Fprintf(long, "\ntype T%x struct{}\n", uint32(i)*2654435761)
+	}
+	tt := []struct {
+		name   string
+		source string
+	}{
+		{name: "Encoded", source: "package shapes\n\ntype Square struct{}\n"},
+		{name: "Posted", source: long.String()},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{})
+			if err != nil {
+				t.Fatalf("Expected no errors, got %s", err.Error())
+			}
+			if err = p.ParseSource("shapes.go", []byte(tc.source)); err != nil {
+				t.Fatalf("Expected no errors, got %s", err.Error())
+			}
+			var received string
+			server := newSVGServer(&received)
+			defer server.Close()
+
+			svg, err := RenderSVG(context.Background(), nil, p, server.URL+"/plantuml/")
+			if err != nil {
+				t.Fatalf("Expected no errors, got %s", err.Error())
+			}
+			if string(svg) != "<svg></svg>" {
+				t.Errorf("Expected the SVG of the server, got %s", svg)
+			}
+			if expected := NewRender().Render(p); received != expected {
+				t.Errorf("Expected the server to receive\n%s\ngot\n%s", expected, received)
+			}
+		})
+	}
+}
+
+func TestRenderSVGErrors(t *testing.T) {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	if err = p.ParseSource("shapes.go", []byte("package shapes\n\ntype Square struct{}\n")); err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	var received string
+	server := newSVGServer(&received)
+	defer server.Close()
+
+	if _, err = RenderSVG(context.Background(), server.Client(), p, server.URL+"/missing"); err == nil {
+		t.Errorf("Expected an error when the server does not answer 200 OK")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = RenderSVG(ctx, server.Client(), p, server.URL+"/plantuml"); err == nil {
+		t.Errorf("Expected an error when the context is canceled")
+	}
+	defer func(size int64) { maxSVGSize = size }(maxSVGSize)
+	maxSVGSize = int64(len("<svg></svg>") - 1)
+	if _, err = RenderSVG(context.Background(), server.Client(), p, server.URL+"/plantuml"); err == nil {
+		t.Errorf("Expected an error when the SVG is too large")
+	}
+}
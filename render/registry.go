@@ -0,0 +1,49 @@
+package render
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	registryMutex sync.RWMutex
+	registry      = map[string]func() Renderer{}
+)
+
+// Register makes a renderer available by the given name. It is meant to be called from the init function of the
+// package of the renderer, and panics if the name is empty, the factory is nil or the name is already registered
+func Register(name string, factory func() Renderer) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	if name == "" || factory == nil {
+		panic("render: Register needs a name and a factory")
+	}
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("render: Register called twice for renderer %s", name))
+	}
+	registry[name] = factory
+}
+
+// Get returns a new renderer of the given name. It fails if no renderer was registered with that name
+func Get(name string) (Renderer, error) {
+	registryMutex.RLock()
+	factory, ok := registry[name]
+	registryMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("Unknown renderer %s", name)
+	}
+	return factory(), nil
+}
+
+// Names returns the sorted names of the registered renderers
+func Names() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,89 @@
+package render
+
+import (
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/jfeliu007/goplantuml/parser"
+)
+
+type testRenderer struct {
+	name string
+}
+
+func (r *testRenderer) Render(p *parser.ClassParser) string {
+	return r.name
+}
+
+func (r *testRenderer) RenderTo(w io.Writer, p *parser.ClassParser) error {
+	_, err := io.WriteString(w, r.name)
+	return err
+}
+
+// withRegistry replaces the registered renderers with an empty registry until the end of the test
+func withRegistry(t *testing.T) {
+	registryMutex.Lock()
+	saved := registry
+	registry = map[string]func() Renderer{}
+	registryMutex.Unlock()
+	t.Cleanup(func() {
+		registryMutex.Lock()
+		registry = saved
+		registryMutex.Unlock()
+	})
+}
+
+func TestRegistry(t *testing.T) {
+	withRegistry(t)
+	Register("zeta", func() Renderer { return &testRenderer{name: "zeta"} })
+	Register("alpha", func() Renderer { return &testRenderer{name: "alpha"} })
+	r, err := Get("zeta")
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	if result := r.Render(nil); result != "zeta" {
+		t.Errorf("Expected the zeta renderer, got %s", result)
+	}
+	if _, err := Get("missing"); err == nil {
+		t.Errorf("Expected an error for a renderer that was not registered")
+	}
+	if names := Names(); !reflect.DeepEqual(names, []string{"alpha", "zeta"}) {
+		t.Errorf("Expected the sorted names [alpha zeta], got %v", names)
+	}
+}
+
+func TestRegisterPanics(t *testing.T) {
+	factory := func() Renderer { return &testRenderer{} }
+	tt := []struct {
+		name     string
+		register func()
+	}{
+		{
+			name:     "Empty name",
+			register: func() { Register("", factory) },
+		},
+		{
+			name:     "Nil factory",
+			register: func() { Register("nil", nil) },
+		},
+		{
+			name: "Duplicate name",
+			register: func() {
+				Register("twice", factory)
+				Register("twice", factory)
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			withRegistry(t)
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected Register to panic")
+				}
+			}()
+			tc.register()
+		})
+	}
+}
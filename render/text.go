@@ -0,0 +1,47 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jfeliu007/goplantuml/parser"
+)
+
+// WrapText splits the given text into lines of at most width characters, breaking them between words. Line breaks in
+// the text are kept and words longer than width get a line of their own. Leading and trailing blank lines are removed.
+func WrapText(text string, width int) []string {
+	result := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		words := strings.Fields(line)
+		if len(words) == 0 {
+			result = append(result, "")
+			continue
+		}
+		current := words[0]
+		for _, word := range words[1:] {
+			if len(current)+1+len(word) > width {
+				result = append(result, current)
+				current = word
+				continue
+			}
+			current += " " + word
+		}
+		result = append(result, current)
+	}
+	return result
+}
+
+// MoreMembers returns the line rendered in place of the given number of fields or methods left out of a class by
+// MaxMembers
+func MoreMembers(count int) string {
+	return fmt.Sprintf("... (+%d more)", count)
+}
+
+// ConstantString returns the given constant of an enumeration as it is rendered, which is its name followed by its value
+// when ConstValues is set and the value is known
+func ConstantString(ro *parser.RenderingOptions, constant *parser.Field) string {
+	if !ro.ConstValues || constant.Value == "" {
+		return constant.Name
+	}
+	return fmt.Sprintf("%s = %s", constant.Name, constant.Value)
+}
@@ -0,0 +1,183 @@
+package yaml
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jfeliu007/goplantuml/parser"
+	"github.com/jfeliu007/goplantuml/render"
+	"github.com/jfeliu007/goplantuml/render/json"
+)
+
+// plainScalar matches the strings that are written without quotes. Every other string is double quoted, so it is never
+// read back as a number, a boolean, null or as YAML syntax
+var plainScalar = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_./-]*$`)
+
+// reservedScalars are the plain strings that YAML parsers read as booleans or null
+var reservedScalars = map[string]struct{}{
+	"y": {}, "n": {}, "yes": {}, "no": {}, "on": {}, "off": {}, "true": {}, "false": {}, "null": {},
+}
+
+type renderer struct {
+}
+
+var _ render.Renderer = (*renderer)(nil)
+
+func init() {
+	render.Register("yaml", func() render.Renderer { return NewRender() })
+}
+
+// NewRender returns a renderer that writes the parsed model as YAML. The model is the one written by the json
+// renderer, with the same keys in the same order, so both documents always hold the same information
+func NewRender() *renderer {
+	return &renderer{}
+}
+
+func (r *renderer) Render(p *parser.ClassParser) string {
+	str := &strings.Builder{}
+	// Writing into a strings.Builder never fails
+	_ = r.RenderTo(str, p)
+	return str.String()
+}
+
+// RenderTo writes the whole parsed model as YAML. Rendering options are ignored, every parsed element is written
+func (r *renderer) RenderTo(w io.Writer, p *parser.ClassParser) error {
+	str := parser.NewLineWriter(w)
+	r.writeMapping(str, "", "", reflect.ValueOf(json.NewModel(p)).Elem())
+	return str.Err()
+}
+
+// entry is a key of a mapping and its value
+type entry struct {
+	key   string
+	value reflect.Value
+}
+
+// entries returns the entries of the given struct, keyed by the name in their json tag, or of the given map, sorted by
+// key. The struct fields tagged with omitempty are left out when they are empty
+func (r *renderer) entries(v reflect.Value) []entry {
+	var result []entry
+	if v.Kind() == reflect.Map {
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, key := range keys {
+			result = append(result, entry{key: key.String(), value: v.MapIndex(key)})
+		}
+		return result
+	}
+	for i := 0; i < v.NumField(); i++ {
+		tag := strings.Split(v.Type().Field(i).Tag.Get("json"), ",")
+		if tag[0] == "-" {
+			continue
+		}
+		if len(tag) > 1 && tag[1] == "omitempty" && v.Field(i).IsZero() {
+			continue
+		}
+		result = append(result, entry{key: tag[0], value: v.Field(i)})
+	}
+	return result
+}
+
+// writeMapping writes the entries of the given struct or map. The first one is written after first, such as "- " for
+// the mappings that are items of a sequence, and the rest are aligned with it after indent
+func (r *renderer) writeMapping(str *parser.LineWriter, indent string, first string, v reflect.Value) {
+	entries := r.entries(v)
+	if len(entries) == 0 {
+		str.WriteLineWithDepth(0, fmt.Sprintf("%s%s{}", indent, first))
+		return
+	}
+	column := indent + strings.Repeat(" ", len(first))
+	for i, e := range entries {
+		prefix := column
+		if i == 0 {
+			prefix = indent + first
+		}
+		r.writeEntry(str, column, prefix, e)
+	}
+}
+
+// writeEntry writes the key of the entry after prefix. Scalars and empty collections are written on the same line,
+// while the items of sequences and the entries of mappings are written on the following lines, indented from column
+func (r *renderer) writeEntry(str *parser.LineWriter, column string, prefix string, e entry) {
+	v := e.value
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			str.WriteLineWithDepth(0, fmt.Sprintf("%s%s: null", prefix, r.key(e.key)))
+			return
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.Len() == 0 {
+			str.WriteLineWithDepth(0, fmt.Sprintf("%s%s: []", prefix, r.key(e.key)))
+			return
+		}
+		str.WriteLineWithDepth(0, fmt.Sprintf("%s%s:", prefix, r.key(e.key)))
+		for i := 0; i < v.Len(); i++ {
+			r.writeItem(str, column+"  ", v.Index(i))
+		}
+	case reflect.Struct, reflect.Map:
+		if len(r.entries(v)) == 0 {
+			str.WriteLineWithDepth(0, fmt.Sprintf("%s%s: {}", prefix, r.key(e.key)))
+			return
+		}
+		str.WriteLineWithDepth(0, fmt.Sprintf("%s%s:", prefix, r.key(e.key)))
+		r.writeMapping(str, column+"  ", "", v)
+	default:
+		str.WriteLineWithDepth(0, fmt.Sprintf("%s%s: %s", prefix, r.key(e.key), r.scalar(v)))
+	}
+}
+
+// writeItem writes an item of a sequence at the given indentation
+func (r *renderer) writeItem(str *parser.LineWriter, indent string, v reflect.Value) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			str.WriteLineWithDepth(0, fmt.Sprintf("%s- null", indent))
+			return
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct, reflect.Map:
+		r.writeMapping(str, indent, "- ", v)
+	case reflect.Slice:
+		if v.Len() == 0 {
+			str.WriteLineWithDepth(0, fmt.Sprintf("%s- []", indent))
+			return
+		}
+		str.WriteLineWithDepth(0, fmt.Sprintf("%s-", indent))
+		for i := 0; i < v.Len(); i++ {
+			r.writeItem(str, indent+"  ", v.Index(i))
+		}
+	default:
+		str.WriteLineWithDepth(0, fmt.Sprintf("%s- %s", indent, r.scalar(v)))
+	}
+}
+
+// key returns the given key of a mapping, quoted when it is not a plain scalar
+func (r *renderer) key(key string) string {
+	return r.scalar(reflect.ValueOf(key))
+}
+
+// scalar returns the given string, bool or number as a YAML scalar
+func (r *renderer) scalar(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		s := v.String()
+		if _, ok := reservedScalars[strings.ToLower(s)]; !ok && plainScalar.MatchString(s) {
+			return s
+		}
+		return strconv.Quote(s)
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	}
+	return strconv.Quote(fmt.Sprint(v.Interface()))
+}
@@ -0,0 +1,203 @@
+package yaml
+
+import (
+	encjson "encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jfeliu007/goplantuml/parser"
+	"github.com/jfeliu007/goplantuml/render/json"
+)
+
+const source = `package shapes
+
+type Yes struct {
+	Points []*Point
+	Kind   Kind
+}
+
+func (y *Yes) Add(p Point) (n int, err error) {
+	return 0, nil
+}
+
+type Point struct {
+	X int
+}
+
+type Kind string
+
+const On Kind = "on: off"
+`
+
+// expected quotes the keys and the values that would be read as something else than a plain string: "On" and "Yes"
+// would be booleans, "n" too, and the ones starting with a dot, a bracket or holding a colon would be YAML syntax
+const expected = `version: 1
+packages:
+  - name: ".shapes"
+    structs:
+      - name: ".shapes.Kind"
+        type: type
+        typeParameters: []
+        fields: []
+        methods: []
+        constants:
+          - name: "On"
+            type: Kind
+            fullType: ""
+            embedded: false
+            value: "\"on: off\""
+        compositions: []
+        extends: []
+        aggregations: []
+        privateAggregations: []
+        multiplicities: {}
+      - name: Point
+        type: class
+        typeParameters: []
+        fields:
+          - name: X
+            type: int
+            fullType: int
+            embedded: false
+        methods: []
+        constants: []
+        compositions: []
+        extends: []
+        aggregations: []
+        privateAggregations: []
+        multiplicities: {}
+      - name: "Yes"
+        type: class
+        typeParameters: []
+        fields:
+          - name: Points
+            type: "[]*.Point"
+            fullType: "[]*.shapes.Point"
+            embedded: false
+          - name: Kind
+            type: ".Kind"
+            fullType: ".shapes.Kind"
+            embedded: false
+        methods:
+          - name: Add
+            parameters:
+              - name: p
+                type: ".Point"
+                fullType: ".shapes.Point"
+                embedded: false
+            returnValues:
+              - int
+              - error
+            returnValueNames:
+              - "n"
+              - err
+            pointerReceiver: true
+        constants: []
+        compositions: []
+        extends: []
+        aggregations:
+          - ".shapes.Kind"
+          - ".shapes.Point"
+        privateAggregations: []
+        multiplicities:
+          ".shapes.Point": "0..*"
+    functions: []
+aliases:
+  - name: builtin.string
+    packageName: ".shapes"
+    aliasOf: ".shapes.Kind"
+    definedType: true
+`
+
+func parseSource(t *testing.T) *parser.ClassParser {
+	p, err := parser.NewClassDiagramWithOptions(&parser.ClassDiagramOptions{})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	if err = p.ParseSource("shapes.go", []byte(source)); err != nil {
+		t.Fatalf("Expected no errors, got %s", err.Error())
+	}
+	return p
+}
+
+func TestRender(t *testing.T) {
+	if result := NewRender().Render(parseSource(t)); result != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, result)
+	}
+}
+
+func TestRenderSameModelAsJSON(t *testing.T) {
+	p := parseSource(t)
+	expected := jsonTokens(t, json.NewRender().Render(p))
+	result := yamlTokens(t, NewRender().Render(p))
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected the keys and values of the JSON document\n%q\ngot\n%q", expected, result)
+	}
+}
+
+// jsonTokens returns the keys and the scalars of the given JSON document in the order they are written
+func jsonTokens(t *testing.T, document string) []string {
+	var result []string
+	decoder := encjson.NewDecoder(strings.NewReader(document))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return result
+		}
+		if err != nil {
+			t.Fatalf("Expected no errors, got %s", err.Error())
+		}
+		if _, ok := token.(encjson.Delim); !ok {
+			result = append(result, fmt.Sprint(token))
+		}
+	}
+}
+
+// yamlTokens returns the keys and the scalars of the given YAML document, as written by the renderer, in the order they
+// are written. Quoted keys and scalars are unquoted and empty collections are left out, as they are in jsonTokens
+func yamlTokens(t *testing.T, document string) []string {
+	var result []string
+	for _, line := range strings.Split(strings.TrimSuffix(document, "\n"), "\n") {
+		line = strings.TrimLeft(line, " ")
+		for strings.HasPrefix(line, "- ") {
+			line = line[2:]
+		}
+		for line != "" {
+			scalar, rest := yamlScalar(t, line)
+			if scalar != "[]" && scalar != "{}" {
+				result = append(result, scalar)
+			}
+			line = strings.TrimPrefix(strings.TrimPrefix(rest, ":"), " ")
+		}
+	}
+	return result
+}
+
+// yamlScalar returns the scalar the given line starts with, unquoted, and the rest of the line
+func yamlScalar(t *testing.T, line string) (string, string) {
+	if !strings.HasPrefix(line, `"`) {
+		if i := strings.Index(line, ":"); i >= 0 {
+			return line[:i], line[i:]
+		}
+		return line, ""
+	}
+	for i := 1; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if line[i] == '"' {
+			scalar, err := strconv.Unquote(line[:i+1])
+			if err != nil {
+				t.Fatalf("Expected no errors unquoting %s, got %s", line[:i+1], err.Error())
+			}
+			return scalar, line[i+1:]
+		}
+	}
+	t.Fatalf("Expected the quoted scalar to be closed in %s", line)
+	return "", ""
+}
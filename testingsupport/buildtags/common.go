@@ -0,0 +1,5 @@
+package buildtags
+
+//Common is for testing purposes
+type Common struct {
+}
@@ -0,0 +1,7 @@
+//go:build linux
+
+package buildtags
+
+//OnlyLinux is for testing purposes
+type OnlyLinux struct {
+}
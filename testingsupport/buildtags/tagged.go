@@ -0,0 +1,7 @@
+//go:build custom
+
+package buildtags
+
+//OnlyCustom is for testing purposes
+type OnlyCustom struct {
+}
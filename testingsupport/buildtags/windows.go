@@ -0,0 +1,7 @@
+//go:build windows
+
+package buildtags
+
+//OnlyWindows is for testing purposes
+type OnlyWindows struct {
+}
@@ -0,0 +1,4 @@
+package withtests_test
+
+type External struct {
+}
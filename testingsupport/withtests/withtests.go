@@ -0,0 +1,5 @@
+package withtests
+
+type Service struct {
+	Name string
+}
@@ -0,0 +1,5 @@
+package withtests
+
+type mockService struct {
+	Service
+}